@@ -5,17 +5,29 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"reflect"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/oszuidwest/zwfm-encoder/internal/audio"
 	"github.com/oszuidwest/zwfm-encoder/internal/config"
+	"github.com/oszuidwest/zwfm-encoder/internal/delay"
+	"github.com/oszuidwest/zwfm-encoder/internal/encoder"
 	"github.com/oszuidwest/zwfm-encoder/internal/eventlog"
+	"github.com/oszuidwest/zwfm-encoder/internal/hls"
+	"github.com/oszuidwest/zwfm-encoder/internal/listen"
+	"github.com/oszuidwest/zwfm-encoder/internal/metadata"
 	"github.com/oszuidwest/zwfm-encoder/internal/notify"
 	"github.com/oszuidwest/zwfm-encoder/internal/recording"
+	"github.com/oszuidwest/zwfm-encoder/internal/rtp"
+	"github.com/oszuidwest/zwfm-encoder/internal/schedule"
 	"github.com/oszuidwest/zwfm-encoder/internal/types"
 )
 
@@ -40,10 +52,14 @@ func (s *Server) writeNoContent(w http.ResponseWriter) {
 }
 
 func (s *Server) writeConfigError(w http.ResponseWriter, err error) {
-	if errors.Is(err, config.ErrStreamNotFound) || errors.Is(err, config.ErrRecorderNotFound) {
+	if errors.Is(err, config.ErrStreamNotFound) || errors.Is(err, config.ErrRecorderNotFound) || errors.Is(err, config.ErrProgrammeNotFound) {
 		s.writeError(w, http.StatusNotFound, err.Error())
 		return
 	}
+	if errors.Is(err, config.ErrMainProgramme) {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	s.writeError(w, http.StatusInternalServerError, err.Error())
 }
 
@@ -68,13 +84,16 @@ func parseJSON[T any](s *Server, w http.ResponseWriter, r *http.Request) (T, boo
 
 // handleAPIConfig returns the full configuration for the frontend.
 func (s *Server) handleAPIConfig(w http.ResponseWriter, r *http.Request) {
-	cfg := s.config.Snapshot()
+	prog := s.programme(r)
+	cfg := prog.config.Snapshot()
 
 	resp := types.APIConfigResponse{
 		// Audio
-		AudioInput: cfg.AudioInput,
-		Devices:    audio.Devices(),
-		Platform:   runtime.GOOS,
+		AudioInput:    cfg.AudioInput,
+		AudioChannels: cfg.AudioChannels,
+		Devices:       audio.Devices(),
+		Platform:      runtime.GOOS,
+		Processing:    cfg.Processing,
 
 		// Silence detection
 		SilenceThreshold:  cfg.SilenceThreshold,
@@ -119,8 +138,85 @@ func (s *Server) handleAPIDevices(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleGetMixer returns the capture mixer controls of the selected input's card.
+func (s *Server) handleGetMixer(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	controls, err := audio.MixerControls(prog.config.AudioInput())
+	if err != nil {
+		s.writeMixerError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"controls": controls,
+	})
+}
+
+// handleSetMixer applies a mixer control value and persists it.
+func (s *Server) handleSetMixer(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	req, ok := parseJSON[audio.MixerSetting](s, w, r)
+	if !ok {
+		return
+	}
+	if req.Control == "" {
+		s.writeError(w, http.StatusBadRequest, "control: is required")
+		return
+	}
+
+	if err := audio.SetMixerControl(prog.config.AudioInput(), req.Control, req.Value); err != nil {
+		s.writeMixerError(w, err)
+		return
+	}
+	if err := prog.config.SetMixerSetting(req); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeNoContent(w)
+}
+
+// handleGetProcessing returns the audio processing settings.
+func (s *Server) handleGetProcessing(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	s.writeJSON(w, http.StatusOK, prog.config.Processing())
+}
+
+// handleSetProcessing replaces the audio processing settings and applies them to running capture.
+func (s *Server) handleSetProcessing(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	req, ok := parseJSON[audio.ProcessingConfig](s, w, r)
+	if !ok {
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := prog.config.SetProcessing(req); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	prog.encoder.UpdateProcessing()
+	s.broadcastConfigChanged()
+	s.writeJSON(w, http.StatusOK, prog.config.Processing())
+}
+
+func (s *Server) writeMixerError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, audio.ErrMixerUnsupported):
+		s.writeError(w, http.StatusNotImplemented, err.Error())
+	case errors.Is(err, audio.ErrMixerControlNotFound):
+		s.writeError(w, http.StatusNotFound, err.Error())
+	default:
+		s.writeError(w, http.StatusBadRequest, err.Error())
+	}
+}
+
 // handleAPISettings updates all settings atomically.
 func (s *Server) handleAPISettings(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	req, ok := parseJSON[config.SettingsUpdate](s, w, r)
 	if !ok {
 		return
@@ -134,32 +230,32 @@ func (s *Server) handleAPISettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cfg := s.config.Snapshot()
-	audioInputChanged := req.AudioInput != cfg.AudioInput
+	cfg := prog.config.Snapshot()
+	audioInputChanged := req.AudioInput != cfg.AudioInput || cmp.Or(req.AudioChannels, audio.Channels) != cfg.AudioChannels
 
 	// Preserve existing secret if not provided (empty = keep existing)
 	req.GraphClientSecret = cmp.Or(req.GraphClientSecret, cfg.GraphClientSecret)
 
 	// Apply ALL settings atomically (single lock, single file write)
-	if err := s.config.ApplySettings(&req); err != nil {
+	if err := prog.config.ApplySettings(&req); err != nil {
 		s.writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	// Side effects after successful save
-	s.encoder.UpdateSilenceConfig()
-	s.encoder.UpdateSilenceDumpConfig()
-	s.encoder.InvalidateGraphSecretExpiryCache()
+	prog.encoder.UpdateSilenceConfig()
+	prog.encoder.UpdateSilenceDumpConfig()
+	prog.encoder.InvalidateGraphSecretExpiryCache()
 
-	// Restart encoder if audio input changed
-	if audioInputChanged && s.ffmpegAvailable && s.encoder.State() == types.StateRunning {
+	// Restart encoder if audio input or channel count changed
+	if audioInputChanged && s.ffmpegAvailable && prog.encoder.State() == types.StateRunning {
 		go func() {
 			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 			defer cancel()
 
 			done := make(chan error, 1)
 			go func() {
-				done <- s.encoder.Restart()
+				done <- prog.encoder.Restart()
 			}()
 
 			select {
@@ -179,14 +275,16 @@ func (s *Server) handleAPISettings(w http.ResponseWriter, r *http.Request) {
 
 // handleListStreams returns all configured streams.
 func (s *Server) handleListStreams(w http.ResponseWriter, r *http.Request) {
-	cfg := s.config.Snapshot()
+	prog := s.programme(r)
+	cfg := prog.config.Snapshot()
 	s.writeJSON(w, http.StatusOK, cfg.Streams)
 }
 
 // handleGetStream returns a single stream by ID.
 func (s *Server) handleGetStream(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	id := r.PathValue("id")
-	stream := s.config.Stream(id)
+	stream := prog.config.Stream(id)
 	if stream == nil {
 		s.writeError(w, http.StatusNotFound, "Stream not found")
 		return
@@ -198,6 +296,8 @@ func (s *Server) handleGetStream(w http.ResponseWriter, r *http.Request) {
 type StreamRequest struct {
 	// Enabled reports whether the stream is active.
 	Enabled bool `json:"enabled"`
+	// Type selects the output type (empty = srt).
+	Type types.OutputType `json:"type"`
 	// Host is the SRT server hostname.
 	Host string `json:"host"`
 	// Port is the SRT server port.
@@ -208,12 +308,47 @@ type StreamRequest struct {
 	StreamID string `json:"stream_id"`
 	// Codec selects the audio codec.
 	Codec types.Codec `json:"codec"`
+	// Channels selects the input channel pair or mono channel (empty = 1-2).
+	Channels audio.Route `json:"channels"`
+	// TrimDB adjusts the output level in dB.
+	TrimDB float64 `json:"trim_db"`
+	// Loudness configures automatic loudness normalisation.
+	Loudness audio.LoudnessConfig `json:"loudness"`
+	// Delay configures the time-shift buffer.
+	Delay delay.Config `json:"delay"`
+	// HLS configures the built-in HLS packager.
+	HLS hls.Config `json:"hls"`
+	// RTP configures the RTP/AES67 sender.
+	RTP rtp.Config `json:"rtp"`
+	// Exec configures the command of an exec output.
+	Exec types.ExecConfig `json:"exec"`
+	// IcecastURL is the admin metadata URL of the Icecast mount that receives title updates.
+	IcecastURL string `json:"icecast_url"`
+	// Schedule limits the stream to weekly windows (empty = always on).
+	Schedule schedule.Config `json:"schedule"`
 	// MaxRetries is the maximum number of retries before giving up.
 	MaxRetries int `json:"max_retries"`
 }
 
+// validateChannels reports an error if route selects channels the audio input does not capture.
+func validateChannels(cfg *config.Config, route audio.Route) error {
+	if n := cfg.AudioChannels(); !route.Valid(n) {
+		return fmt.Errorf("channels: audio input captures %d channels", n)
+	}
+	return nil
+}
+
+// validateExec reports an error if an exec stream runs a command outside the allowlist.
+func validateExec(cfg *config.Config, stream *types.Stream) error {
+	if stream.Output() != types.OutputExec {
+		return nil
+	}
+	return stream.Exec.CheckAllowlist(cfg.ExecAllowlistPath())
+}
+
 // handleCreateStream creates a new stream.
 func (s *Server) handleCreateStream(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	req, ok := parseJSON[StreamRequest](s, w, r)
 	if !ok {
 		return
@@ -221,11 +356,21 @@ func (s *Server) handleCreateStream(w http.ResponseWriter, r *http.Request) {
 
 	stream := &types.Stream{
 		Enabled:    true,
+		Type:       req.Type,
 		Host:       req.Host,
 		Port:       req.Port,
 		Password:   req.Password,
 		StreamID:   req.StreamID,
 		Codec:      req.Codec, // Already validated by UnmarshalJSON
+		Channels:   req.Channels,
+		TrimDB:     req.TrimDB,
+		Loudness:   req.Loudness,
+		Delay:      req.Delay,
+		HLS:        req.HLS,
+		RTP:        req.RTP,
+		Exec:       req.Exec,
+		IcecastURL: req.IcecastURL,
+		Schedule:   req.Schedule,
 		MaxRetries: req.MaxRetries,
 	}
 
@@ -234,27 +379,49 @@ func (s *Server) handleCreateStream(w http.ResponseWriter, r *http.Request) {
 		s.writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := validateChannels(prog.config, stream.Channels); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateExec(prog.config, stream); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Persistence failures are server errors
-	if err := s.config.AddStream(stream); err != nil {
+	if err := prog.config.AddStream(stream); err != nil {
 		s.writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	if s.encoder.State() == types.StateRunning {
-		if err := s.encoder.StartStream(stream.ID); err != nil {
+	resp := streamResponse{Stream: stream}
+	if prog.encoder.State() == types.StateRunning {
+		switch err := prog.encoder.StartStream(stream.ID); {
+		case errors.Is(err, encoder.ErrOutsideSchedule):
+			resp.Notice = outsideScheduleNotice
+		case err != nil:
 			slog.Warn("failed to start new stream", "stream_id", stream.ID, "error", err)
 		}
 	}
 
 	s.broadcastConfigChanged()
-	s.writeJSON(w, http.StatusCreated, stream)
+	s.writeJSON(w, http.StatusCreated, resp)
+}
+
+// outsideScheduleNotice tells the client that a saved stream was not started.
+const outsideScheduleNotice = "Stream is outside its schedule and starts with its next window"
+
+// streamResponse is a saved stream, with a notice when it was not started.
+type streamResponse struct {
+	*types.Stream
+	Notice string `json:"notice,omitempty"`
 }
 
 // handleUpdateStream replaces a stream by ID.
 func (s *Server) handleUpdateStream(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	id := r.PathValue("id")
-	existing := s.config.Stream(id)
+	existing := prog.config.Stream(id)
 	if existing == nil {
 		s.writeError(w, http.StatusNotFound, "Stream not found")
 		return
@@ -270,11 +437,21 @@ func (s *Server) handleUpdateStream(w http.ResponseWriter, r *http.Request) {
 	updated := &types.Stream{
 		ID:         id,
 		Enabled:    req.Enabled,
+		Type:       req.Type,
 		Host:       req.Host,
 		Port:       req.Port,
 		Password:   cmp.Or(req.Password, existing.Password),
 		StreamID:   req.StreamID,
 		Codec:      req.Codec,
+		Channels:   req.Channels,
+		TrimDB:     req.TrimDB,
+		Loudness:   req.Loudness,
+		Delay:      req.Delay,
+		HLS:        req.HLS,
+		RTP:        req.RTP,
+		Exec:       req.Exec,
+		IcecastURL: req.IcecastURL,
+		Schedule:   req.Schedule,
 		MaxRetries: req.MaxRetries,
 		CreatedAt:  existing.CreatedAt,
 	}
@@ -284,22 +461,35 @@ func (s *Server) handleUpdateStream(w http.ResponseWriter, r *http.Request) {
 		s.writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := validateChannels(prog.config, updated.Channels); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateExec(prog.config, updated); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Persistence failures are server errors (not-found can happen on concurrent delete)
-	if err := s.config.UpdateStream(updated); err != nil {
+	if err := prog.config.UpdateStream(updated); err != nil {
 		s.writeConfigError(w, err)
 		return
 	}
 
-	// Restart stream if encoder is running
-	if s.encoder.State() == types.StateRunning {
-		if err := s.encoder.StopStream(id); err != nil {
+	resp := streamResponse{Stream: updated}
+	if prog.encoder.State() == types.StateRunning && updated.IsEnabled() && !updated.Schedule.Allows(time.Now()) {
+		resp.Notice = outsideScheduleNotice
+	}
+
+	// Restart stream if encoder is running; level changes apply without restart
+	if prog.encoder.State() == types.StateRunning && !onlyLevelChanged(*existing, *updated) {
+		if err := prog.encoder.StopStream(id); err != nil {
 			slog.Warn("failed to stop stream for restart", "stream_id", id, "error", err)
 		}
 		go func() {
 			time.Sleep(types.StreamRestartDelay)
-			if s.encoder.State() == types.StateRunning {
-				if err := s.encoder.StartStream(id); err != nil {
+			if prog.encoder.State() == types.StateRunning {
+				if err := prog.encoder.StartStream(id); err != nil && !errors.Is(err, encoder.ErrOutsideSchedule) {
 					slog.Warn("failed to restart stream", "stream_id", id, "error", err)
 				}
 			}
@@ -307,22 +497,31 @@ func (s *Server) handleUpdateStream(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.broadcastConfigChanged()
-	s.writeJSON(w, http.StatusOK, updated)
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+// onlyLevelChanged reports whether two stream configurations differ at most
+// in their trim and loudness settings.
+func onlyLevelChanged(a, b types.Stream) bool {
+	a.Channels, b.Channels = a.Channels.Normalize(), b.Channels.Normalize()
+	a.TrimDB, a.Loudness = b.TrimDB, b.Loudness
+	return reflect.DeepEqual(a, b)
 }
 
 // handleDeleteStream deletes a stream by ID.
 func (s *Server) handleDeleteStream(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	id := r.PathValue("id")
-	if s.config.Stream(id) == nil {
+	if prog.config.Stream(id) == nil {
 		s.writeError(w, http.StatusNotFound, "Stream not found")
 		return
 	}
 
-	if err := s.encoder.StopStream(id); err != nil {
+	if err := prog.encoder.StopStream(id); err != nil {
 		slog.Warn("failed to stop stream before delete", "stream_id", id, "error", err)
 	}
 
-	if err := s.config.RemoveStream(id); err != nil {
+	if err := prog.config.RemoveStream(id); err != nil {
 		s.writeConfigError(w, err)
 		return
 	}
@@ -331,16 +530,226 @@ func (s *Server) handleDeleteStream(w http.ResponseWriter, r *http.Request) {
 	s.writeNoContent(w)
 }
 
+// handleStreamDelayAction dumps or ramps the delay of a stream.
+func (s *Server) handleStreamDelayAction(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	id := r.PathValue("id")
+
+	var err error
+	var message string
+	switch r.PathValue("action") {
+	case "dump":
+		err, message = prog.encoder.DumpDelay(id), "Delay dumped"
+	case "ramp":
+		err, message = prog.encoder.RampDelay(id), "Delay ramping up"
+	default:
+		s.writeError(w, http.StatusBadRequest, "invalid action: must be dump or ramp")
+		return
+	}
+
+	switch {
+	case errors.Is(err, encoder.ErrStreamNotFound):
+		s.writeError(w, http.StatusNotFound, "Stream not found")
+	case err != nil:
+		s.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		s.writeMessage(w, message)
+	}
+}
+
+// handleListen serves a live monitoring encode of the programme or a stream
+// for as long as the client stays connected.
+func (s *Server) handleListen(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	format, err := listen.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	listener, err := prog.encoder.Listen(r.PathValue("id"), format)
+	switch {
+	case errors.Is(err, encoder.ErrStreamNotFound):
+		s.writeError(w, http.StatusNotFound, "Stream not found")
+		return
+	case errors.Is(err, encoder.ErrNotRunning):
+		s.writeError(w, http.StatusServiceUnavailable, "Encoder is not running")
+		return
+	case err != nil:
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer listener.Close()
+
+	// The response lasts as long as the listener; lift the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Warn("failed to clear write deadline for listener", "error", err)
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if header := listener.Header(); len(header) > 0 {
+		if _, err := w.Write(header); err != nil {
+			return
+		}
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data, ok := <-listener.Audio():
+			if !ok {
+				return
+			}
+			if _, err := w.Write(data); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleSetMetadata sets the item on air. It accepts JSON, URL-encoded or
+// plain "artist - title" bodies, or the same fields as query parameters.
+func (s *Server) handleSetMetadata(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid body: "+err.Error())
+		return
+	}
+
+	msg := string(body)
+	if strings.TrimSpace(msg) == "" {
+		msg = r.URL.RawQuery
+	}
+	np, err := metadata.Parse(msg)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	prog.encoder.SetNowPlaying(np)
+	s.writeJSON(w, http.StatusOK, np)
+}
+
+// handleGetMetadata returns the item on air, or 204 if none has been received.
+func (s *Server) handleGetMetadata(w http.ResponseWriter, r *http.Request) {
+	np := s.programme(r).encoder.NowPlaying()
+	if np.IsZero() {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, np)
+}
+
+// handleStreamSDP returns the session description of a running RTP stream.
+func (s *Server) handleStreamSDP(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	id := r.PathValue("id")
+	stream := prog.config.Stream(id)
+	if stream == nil || stream.Output() != types.OutputRTP {
+		s.writeError(w, http.StatusNotFound, "Stream not found")
+		return
+	}
+
+	sender := prog.encoder.RTP(id)
+	if sender == nil {
+		s.writeError(w, http.StatusNotFound, "Stream is not running")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/sdp")
+	if _, err := io.WriteString(w, sender.SDP()); err != nil {
+		slog.Debug("failed to write sdp", "stream_id", id, "error", err)
+	}
+}
+
+// handleHLS serves the playlist and segments of an HLS stream. Streams that
+// are not public require a login.
+func (s *Server) handleHLS(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var prog *programme
+	var stream *types.Stream
+	for _, p := range s.programmeList() {
+		if stream = p.config.Stream(id); stream != nil {
+			prog = p
+			break
+		}
+	}
+	if stream == nil || stream.Output() != types.OutputHLS {
+		s.writeError(w, http.StatusNotFound, "Stream not found")
+		return
+	}
+
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		packager := prog.encoder.HLS(id)
+		if packager == nil {
+			s.writeError(w, http.StatusNotFound, "Stream is not running")
+			return
+		}
+
+		if stream.HLS.Public {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+
+		file := r.PathValue("file")
+		if file == hls.PlaylistName {
+			playlist, ok := packager.Playlist()
+			if !ok {
+				s.writeError(w, http.StatusNotFound, "Playlist is not ready yet")
+				return
+			}
+			w.Header().Set("Content-Type", hls.PlaylistContentType)
+			w.Header().Set("Cache-Control", "no-cache")
+			if _, err := w.Write(playlist); err != nil {
+				slog.Debug("failed to write hls playlist", "stream_id", id, "error", err)
+			}
+			return
+		}
+
+		data, contentType, ok := packager.Segment(file)
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "Segment not found")
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "max-age=60")
+		if _, err := w.Write(data); err != nil {
+			slog.Debug("failed to write hls segment", "stream_id", id, "error", err)
+		}
+	}
+
+	if !stream.HLS.Public {
+		serve = s.sessions.AuthMiddleware()(serve)
+	}
+	serve(w, r)
+}
+
 // handleListRecorders returns all configured recorders.
 func (s *Server) handleListRecorders(w http.ResponseWriter, r *http.Request) {
-	cfg := s.config.Snapshot()
+	prog := s.programme(r)
+	cfg := prog.config.Snapshot()
 	s.writeJSON(w, http.StatusOK, cfg.Recorders)
 }
 
+// handleListRecorderSchedules returns the scheduled shows of all recorders
+// with their next occurrence.
+func (s *Server) handleListRecorderSchedules(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	s.writeJSON(w, http.StatusOK, prog.encoder.RecorderSchedules())
+}
+
 // handleGetRecorder returns a single recorder by ID.
 func (s *Server) handleGetRecorder(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	id := r.PathValue("id")
-	recorder := s.config.Recorder(id)
+	recorder := prog.config.Recorder(id)
 	if recorder == nil {
 		s.writeError(w, http.StatusNotFound, "Recorder not found")
 		return
@@ -356,8 +765,16 @@ type RecorderRequest struct {
 	Enabled bool `json:"enabled"`
 	// Codec selects the recording codec.
 	Codec types.Codec `json:"codec"`
+	// Channels selects the input channel pair or mono channel (empty = 1-2).
+	Channels audio.Route `json:"channels"`
+	// TrimDB adjusts the output level in dB.
+	TrimDB float64 `json:"trim_db"`
+	// Loudness configures automatic loudness normalisation.
+	Loudness audio.LoudnessConfig `json:"loudness"`
 	// RotationMode selects the file rotation mode.
 	RotationMode types.RotationMode `json:"rotation_mode"`
+	// RotationMinutes is the file length in interval mode.
+	RotationMinutes int `json:"rotation_minutes"`
 	// StorageMode selects local/S3 storage behavior.
 	StorageMode types.StorageMode `json:"storage_mode"`
 	// LocalPath is the local directory for recordings.
@@ -372,10 +789,15 @@ type RecorderRequest struct {
 	S3SecretAccessKey string `json:"s3_secret_access_key"`
 	// RetentionDays is the number of days to retain recordings.
 	RetentionDays int `json:"retention_days"`
+	// Schedules lists the shows an on-demand recorder records on its own.
+	Schedules []schedule.Show `json:"schedules"`
+	// Compliance configures the tamper-evident archive mode.
+	Compliance types.Compliance `json:"compliance"`
 }
 
 // handleCreateRecorder creates a new recorder.
 func (s *Server) handleCreateRecorder(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	req, ok := parseJSON[RecorderRequest](s, w, r)
 	if !ok {
 		return
@@ -387,12 +809,18 @@ func (s *Server) handleCreateRecorder(w http.ResponseWriter, r *http.Request) {
 		Codec:             req.Codec,        // Already validated by UnmarshalJSON
 		RotationMode:      req.RotationMode, // Already validated by UnmarshalJSON
 		StorageMode:       req.StorageMode,  // Already validated by UnmarshalJSON
+		RotationMinutes:   req.RotationMinutes,
+		Channels:          req.Channels,
+		TrimDB:            req.TrimDB,
+		Loudness:          req.Loudness,
 		LocalPath:         req.LocalPath,
 		S3Endpoint:        req.S3Endpoint,
 		S3Bucket:          req.S3Bucket,
 		S3AccessKeyID:     req.S3AccessKeyID,
 		S3SecretAccessKey: req.S3SecretAccessKey,
 		RetentionDays:     req.RetentionDays,
+		Schedules:         req.Schedules,
+		Compliance:        req.Compliance,
 	}
 
 	// Validate first - client error
@@ -400,9 +828,13 @@ func (s *Server) handleCreateRecorder(w http.ResponseWriter, r *http.Request) {
 		s.writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := validateChannels(prog.config, recorder.Channels); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Persistence/manager failures are server errors
-	if err := s.encoder.AddRecorder(recorder); err != nil {
+	if err := prog.encoder.AddRecorder(recorder); err != nil {
 		s.writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -413,8 +845,9 @@ func (s *Server) handleCreateRecorder(w http.ResponseWriter, r *http.Request) {
 
 // handleUpdateRecorder replaces a recorder by ID.
 func (s *Server) handleUpdateRecorder(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	id := r.PathValue("id")
-	existing := s.config.Recorder(id)
+	existing := prog.config.Recorder(id)
 	if existing == nil {
 		s.writeError(w, http.StatusNotFound, "Recorder not found")
 		return
@@ -432,7 +865,11 @@ func (s *Server) handleUpdateRecorder(w http.ResponseWriter, r *http.Request) {
 		Name:              req.Name,
 		Enabled:           req.Enabled,
 		Codec:             req.Codec,
+		Channels:          req.Channels,
+		TrimDB:            req.TrimDB,
+		Loudness:          req.Loudness,
 		RotationMode:      req.RotationMode,
+		RotationMinutes:   req.RotationMinutes,
 		StorageMode:       req.StorageMode,
 		LocalPath:         req.LocalPath,
 		S3Endpoint:        req.S3Endpoint,
@@ -440,6 +877,8 @@ func (s *Server) handleUpdateRecorder(w http.ResponseWriter, r *http.Request) {
 		S3AccessKeyID:     req.S3AccessKeyID,
 		S3SecretAccessKey: cmp.Or(req.S3SecretAccessKey, existing.S3SecretAccessKey),
 		RetentionDays:     req.RetentionDays,
+		Schedules:         req.Schedules,
+		Compliance:        req.Compliance,
 		CreatedAt:         existing.CreatedAt,
 	}
 
@@ -448,9 +887,13 @@ func (s *Server) handleUpdateRecorder(w http.ResponseWriter, r *http.Request) {
 		s.writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := validateChannels(prog.config, updated.Channels); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Persistence/manager failures are server errors (not-found can happen on concurrent delete)
-	if err := s.encoder.UpdateRecorder(updated); err != nil {
+	if err := prog.encoder.UpdateRecorder(updated); err != nil {
 		s.writeConfigError(w, err)
 		return
 	}
@@ -461,13 +904,14 @@ func (s *Server) handleUpdateRecorder(w http.ResponseWriter, r *http.Request) {
 
 // handleDeleteRecorder deletes a recorder by ID.
 func (s *Server) handleDeleteRecorder(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	id := r.PathValue("id")
-	if s.config.Recorder(id) == nil {
+	if prog.config.Recorder(id) == nil {
 		s.writeError(w, http.StatusNotFound, "Recorder not found")
 		return
 	}
 
-	if err := s.encoder.RemoveRecorder(id); err != nil {
+	if err := prog.encoder.RemoveRecorder(id); err != nil {
 		s.writeConfigError(w, err)
 		return
 	}
@@ -478,22 +922,23 @@ func (s *Server) handleDeleteRecorder(w http.ResponseWriter, r *http.Request) {
 
 // handleRecorderAction handles start/stop actions for a recorder.
 func (s *Server) handleRecorderAction(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	id := r.PathValue("id")
 	action := r.PathValue("action")
 
 	switch action {
 	case "start":
-		if s.encoder.State() != types.StateRunning {
+		if prog.encoder.State() != types.StateRunning {
 			s.writeError(w, http.StatusBadRequest, "Encoder must be running to start recorder")
 			return
 		}
-		if err := s.encoder.StartRecorder(id); err != nil {
+		if err := prog.encoder.StartRecorder(id); err != nil {
 			s.writeError(w, http.StatusBadRequest, err.Error())
 			return
 		}
 		s.writeMessage(w, "Recorder started")
 	case "stop":
-		if err := s.encoder.StopRecorder(id); err != nil {
+		if err := prog.encoder.StopRecorder(id); err != nil {
 			s.writeError(w, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -503,6 +948,268 @@ func (s *Server) handleRecorderAction(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleListRecordings lists the files of a recorder on disk and in S3.
+func (s *Server) handleListRecordings(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	id := r.PathValue("id")
+	if prog.config.Recorder(id) == nil {
+		s.writeError(w, http.StatusNotFound, "Recorder not found")
+		return
+	}
+
+	recordings, err := prog.encoder.Recordings(r.Context(), id)
+	if err != nil {
+		s.writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, recordings)
+}
+
+// handleGetRecording serves a file of a recorder with Range support, from
+// disk or proxied from S3. With redirect=true, files in S3 are redirected to
+// a presigned URL instead; download=true asks the browser to save the file.
+func (s *Server) handleGetRecording(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	id := r.PathValue("id")
+	name := r.PathValue("name")
+	if prog.config.Recorder(id) == nil {
+		s.writeError(w, http.StatusNotFound, "Recorder not found")
+		return
+	}
+
+	if r.URL.Query().Get("redirect") == "true" {
+		url, err := prog.encoder.PresignRecording(r.Context(), id, name)
+		if err == nil {
+			http.Redirect(w, r, url, http.StatusFound)
+			return
+		}
+		if !errors.Is(err, recording.ErrRecordingNotFound) {
+			s.writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		// Not in S3 (yet): serve the local copy
+	}
+
+	file, modTime, err := prog.encoder.OpenRecording(r.Context(), id, name)
+	switch {
+	case errors.Is(err, recording.ErrRecordingNotFound):
+		s.writeError(w, http.StatusNotFound, "Recording not found")
+		return
+	case err != nil:
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			slog.Debug("failed to close recording", "recorder_id", id, "name", name, "error", err)
+		}
+	}()
+
+	// Large files take longer than the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Warn("failed to clear write deadline for recording", "error", err)
+	}
+
+	w.Header().Set("Content-Type", recording.ContentType(name))
+	if r.URL.Query().Get("download") == "true" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	}
+	http.ServeContent(w, r, name, modTime, file)
+}
+
+// ExportRequest contains fields for exporting a time range of a recorder.
+type ExportRequest struct {
+	// Start is the beginning of the range.
+	Start time.Time `json:"start"`
+	// End is the end of the range.
+	End time.Time `json:"end"`
+	// Codec transcodes the result; empty keeps the recorder's codec.
+	Codec string `json:"codec,omitempty"`
+}
+
+// handleCreateExport queues a job that exports a time range of a recorder.
+func (s *Server) handleCreateExport(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	id := r.PathValue("id")
+	if prog.config.Recorder(id) == nil {
+		s.writeError(w, http.StatusNotFound, "Recorder not found")
+		return
+	}
+
+	req, ok := parseJSON[ExportRequest](s, w, r)
+	if !ok {
+		return
+	}
+
+	export, err := prog.encoder.StartExport(id, req.Start, req.End, types.Codec(req.Codec))
+	switch {
+	case errors.Is(err, recording.ErrInvalidExport):
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusAccepted, export)
+}
+
+// handleListExports lists the export jobs of a recorder.
+func (s *Server) handleListExports(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	id := r.PathValue("id")
+	if prog.config.Recorder(id) == nil {
+		s.writeError(w, http.StatusNotFound, "Recorder not found")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, prog.encoder.Exports(id))
+}
+
+// handleGetExport returns the state and progress of an export job.
+func (s *Server) handleGetExport(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	export, err := prog.encoder.Export(r.PathValue("id"), r.PathValue("job"))
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, "Export not found")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, export)
+}
+
+// handleDownloadExport serves the result of a finished export job with
+// Range support.
+func (s *Server) handleDownloadExport(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	id := r.PathValue("id")
+	file, export, err := prog.encoder.OpenExport(id, r.PathValue("job"))
+	switch {
+	case errors.Is(err, recording.ErrExportNotFound):
+		s.writeError(w, http.StatusNotFound, "Export not found")
+		return
+	case errors.Is(err, recording.ErrExportNotReady):
+		s.writeError(w, http.StatusConflict, "Export is "+string(export.State))
+		return
+	case err != nil:
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			slog.Debug("failed to close export", "recorder_id", id, "export_id", export.ID, "error", err)
+		}
+	}()
+
+	// Large files take longer than the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Warn("failed to clear write deadline for export", "error", err)
+	}
+
+	w.Header().Set("Content-Type", recording.ContentType(export.Filename))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": export.Filename}))
+	http.ServeContent(w, r, export.Filename, export.FinishedAt, file)
+}
+
+// handleDeleteExport cancels an export job and removes its result.
+func (s *Server) handleDeleteExport(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	if err := prog.encoder.DeleteExport(r.PathValue("id"), r.PathValue("job")); err != nil {
+		s.writeError(w, http.StatusNotFound, "Export not found")
+		return
+	}
+	s.writeNoContent(w)
+}
+
+// handleVerifyChain verifies the hash chain of the files of a recorder that
+// started between from and to (RFC 3339), by default the last 24 hours.
+func (s *Server) handleVerifyChain(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	id := r.PathValue("id")
+	if prog.config.Recorder(id) == nil {
+		s.writeError(w, http.StatusNotFound, "Recorder not found")
+		return
+	}
+
+	to, ok := s.queryTime(w, r, "to", time.Now())
+	if !ok {
+		return
+	}
+	from, ok := s.queryTime(w, r, "from", to.Add(-recording.DefaultChainVerifySpan))
+	if !ok {
+		return
+	}
+
+	// Every file is read, which takes longer than the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Warn("failed to clear write deadline for chain verification", "error", err)
+	}
+
+	report, err := prog.encoder.VerifyChain(r.Context(), id, from, to)
+	switch {
+	case errors.Is(err, recording.ErrInvalidRange):
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		s.writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, report)
+}
+
+// handleRecorderCoverage reports the coverage of the files of a recorder per
+// day, by default over the last week from local midnight.
+func (s *Server) handleRecorderCoverage(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
+	id := r.PathValue("id")
+	if prog.config.Recorder(id) == nil {
+		s.writeError(w, http.StatusNotFound, "Recorder not found")
+		return
+	}
+
+	to, ok := s.queryTime(w, r, "to", time.Now())
+	if !ok {
+		return
+	}
+	y, m, d := to.Date()
+	from, ok := s.queryTime(w, r, "from", time.Date(y, m, d-recording.DefaultCoverageDays+1, 0, 0, 0, 0, time.Local))
+	if !ok {
+		return
+	}
+
+	// Sidecars of files only in S3 are read one by one
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Warn("failed to clear write deadline for coverage report", "error", err)
+	}
+
+	report, err := prog.encoder.RecordingCoverage(r.Context(), id, from, to)
+	switch {
+	case errors.Is(err, recording.ErrInvalidRange):
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		s.writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, report)
+}
+
+// queryTime returns the RFC 3339 time in query parameter name, or def if it
+// is not set. It writes a 400 response for an invalid time.
+func (s *Server) queryTime(w http.ResponseWriter, r *http.Request, name string, def time.Time) (time.Time, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, name+": must be an RFC 3339 time")
+		return time.Time{}, false
+	}
+	return t, true
+}
+
 // S3TestRequest contains fields for testing S3 connectivity.
 type S3TestRequest struct {
 	// Endpoint is the S3-compatible endpoint URL.
@@ -578,12 +1285,13 @@ type NotificationTestRequest struct {
 
 // handleAPITestWebhook tests webhook notification connectivity.
 func (s *Server) handleAPITestWebhook(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	req, ok := parseJSON[NotificationTestRequest](s, w, r)
 	if !ok {
 		return
 	}
 
-	cfg := s.config.Snapshot()
+	cfg := prog.config.Snapshot()
 	webhookURL := cmp.Or(req.WebhookURL, cfg.WebhookURL)
 
 	if webhookURL == "" {
@@ -601,12 +1309,13 @@ func (s *Server) handleAPITestWebhook(w http.ResponseWriter, r *http.Request) {
 
 // handleAPITestEmail tests email notification.
 func (s *Server) handleAPITestEmail(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	req, ok := parseJSON[NotificationTestRequest](s, w, r)
 	if !ok {
 		return
 	}
 
-	cfg := s.config.Snapshot()
+	cfg := prog.config.Snapshot()
 	tenantID := cmp.Or(req.GraphTenantID, cfg.GraphTenantID)
 	clientID := cmp.Or(req.GraphClientID, cfg.GraphClientID)
 	clientSecret := cmp.Or(req.GraphClientSecret, cfg.GraphClientSecret)
@@ -636,12 +1345,13 @@ func (s *Server) handleAPITestEmail(w http.ResponseWriter, r *http.Request) {
 
 // handleAPITestZabbix tests Zabbix trapper notification connectivity.
 func (s *Server) handleAPITestZabbix(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	req, ok := parseJSON[NotificationTestRequest](s, w, r)
 	if !ok {
 		return
 	}
 
-	cfg := s.config.Snapshot()
+	cfg := prog.config.Snapshot()
 	server := cmp.Or(req.ZabbixServer, cfg.ZabbixServer)
 	port := cmp.Or(req.ZabbixPort, cfg.ZabbixPort)
 	host := cmp.Or(req.ZabbixHost, cfg.ZabbixHost)
@@ -662,13 +1372,14 @@ func (s *Server) handleAPITestZabbix(w http.ResponseWriter, r *http.Request) {
 
 // handleAPIRegenerateKey generates a new recording API key.
 func (s *Server) handleAPIRegenerateKey(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	newKey, err := config.GenerateAPIKey()
 	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	if err := s.config.SetRecordingAPIKey(newKey); err != nil {
+	if err := prog.config.SetRecordingAPIKey(newKey); err != nil {
 		s.writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -678,9 +1389,21 @@ func (s *Server) handleAPIRegenerateKey(w http.ResponseWriter, r *http.Request)
 }
 
 // HealthResponse is the response body for the health endpoint.
+// The embedded fields describe the main programme.
 type HealthResponse struct {
 	// Status is the overall health status (healthy or unhealthy).
 	Status string `json:"status"`
+	ProgrammeHealth
+	// Programmes lists the health of additional programmes.
+	Programmes []ProgrammeHealth `json:"programmes,omitempty"`
+}
+
+// ProgrammeHealth is the health of a single programme.
+type ProgrammeHealth struct {
+	// ProgrammeID identifies the programme.
+	ProgrammeID string `json:"programme_id"`
+	// ProgrammeName is the programme display name.
+	ProgrammeName string `json:"programme_name"`
 	// EncoderState is the encoder's current state string.
 	EncoderState string `json:"encoder_state"`
 	// StreamCount is the number of configured streams.
@@ -699,35 +1422,47 @@ type HealthResponse struct {
 
 // handleHealth returns the health status of the encoder.
 // It returns 200 OK if healthy, 503 Service Unavailable if unhealthy.
-// Health is defined as: encoder running AND FFmpeg available.
+// Health is defined as: every programme's encoder running AND FFmpeg available.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	cfg := s.config.Snapshot()
-	encoderStatus := s.encoder.Status()
-	streamStatuses := s.encoder.StreamStatuses(cfg.Streams)
-	recorderStatuses := s.encoder.RecorderStatuses()
-
-	streamsStable := countStableStreams(streamStatuses)
-	recordersRunning := countRunningRecorders(recorderStatuses)
-
-	isHealthy := s.ffmpegAvailable && encoderStatus.State == types.StateRunning
+	isHealthy := s.ffmpegAvailable
+	var resp HealthResponse
+
+	for _, prog := range s.programmeList() {
+		health := prog.health()
+		isHealthy = isHealthy && health.EncoderState == string(types.StateRunning)
+		if prog.config.Snapshot().IsMainProgramme {
+			resp.ProgrammeHealth = health
+		} else {
+			resp.Programmes = append(resp.Programmes, health)
+		}
+	}
 
-	status := "healthy"
+	resp.Status = "healthy"
 	httpStatus := http.StatusOK
 	if !isHealthy {
-		status = "unhealthy"
+		resp.Status = "unhealthy"
 		httpStatus = http.StatusServiceUnavailable
 	}
 
-	s.writeJSON(w, httpStatus, HealthResponse{
-		Status:           status,
+	s.writeJSON(w, httpStatus, resp)
+}
+
+// health returns the programme's current health.
+func (p *programme) health() ProgrammeHealth {
+	cfg := p.config.Snapshot()
+	encoderStatus := p.encoder.Status()
+
+	return ProgrammeHealth{
+		ProgrammeID:      cfg.ProgrammeID,
+		ProgrammeName:    cfg.ProgrammeName,
 		EncoderState:     string(encoderStatus.State),
 		StreamCount:      len(cfg.Streams),
-		StreamsStable:    streamsStable,
+		StreamsStable:    countStableStreams(p.encoder.StreamStatuses(cfg.Streams)),
 		RecorderCount:    len(cfg.Recorders),
-		RecordersRunning: recordersRunning,
+		RecordersRunning: countRunningRecorders(p.encoder.RecorderStatuses()),
 		UptimeSeconds:    encoderStatus.UptimeSeconds,
-		SilenceDetected:  s.encoder.AudioLevels().SilenceLevel == audio.SilenceLevelActive,
-	})
+		SilenceDetected:  p.encoder.AudioLevels().SilenceLevel == audio.SilenceLevelActive,
+	}
 }
 
 func countStableStreams(statuses map[string]types.ProcessStatus) int {
@@ -752,6 +1487,7 @@ func countRunningRecorders(statuses map[string]types.ProcessStatus) int {
 
 // handleAPIEvents returns events from the event log.
 func (s *Server) handleAPIEvents(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	emptyResponse := map[string]any{
 		"events":   []eventlog.Event{},
 		"has_more": false,
@@ -787,7 +1523,7 @@ func (s *Server) handleAPIEvents(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get event log path from encoder
-	logPath := s.encoder.EventLogPath()
+	logPath := prog.encoder.EventLogPath()
 	if logPath == "" {
 		s.writeJSON(w, http.StatusOK, emptyResponse)
 		return
@@ -7,6 +7,7 @@ ENCODER_SERVICE_URL="https://raw.githubusercontent.com/${GITHUB_REPO}/main/deplo
 INSTALL_DIR="/usr/local/bin"
 CONFIG_DIR="/etc/encoder"
 CONFIG_FILE="${CONFIG_DIR}/config.json"
+STATE_DIR="/var/lib/encoder"
 SERVICE_PATH="/etc/systemd/system/encoder.service"
 
 # Functions library (v2)
@@ -254,6 +255,12 @@ mkdir -p "$CONFIG_DIR"
 chown encoder:encoder "$CONFIG_DIR"
 chmod 700 "$CONFIG_DIR"
 
+# Create state directory for recordings waiting for their upload
+# (systemd creates it as well through StateDirectory=)
+mkdir -p "$STATE_DIR"
+chown encoder:encoder "$STATE_DIR"
+chmod 750 "$STATE_DIR"
+
 # Migrate config from old location if it exists
 OLD_CONFIG="${INSTALL_DIR}/config.json"
 if [ -f "$OLD_CONFIG" ] && [ ! -f "$CONFIG_FILE" ]; then
@@ -25,12 +25,13 @@ type CaptureConfig struct {
 	UsesFFmpeg bool
 
 	// BuildArgs returns the command arguments for audio capture.
-	// The device parameter is the audio input device identifier.
-	BuildArgs func(device string) []string
+	// The device parameter is the audio input device identifier and
+	// channels is the number of interleaved channels to capture.
+	BuildArgs func(device string, channels int) []string
 }
 
 // BuildCaptureCommand returns the command and arguments for audio capture.
-func BuildCaptureCommand(device, ffmpegPath string) (cmd string, args []string, err error) {
+func BuildCaptureCommand(device, ffmpegPath string, channels int) (cmd string, args []string, err error) {
 	cfg := getPlatformConfig()
 
 	if device == "" {
@@ -52,5 +53,5 @@ func BuildCaptureCommand(device, ffmpegPath string) (cmd string, args []string,
 		command = ffmpegPath
 	}
 
-	return command, cfg.BuildArgs(device), nil
+	return command, cfg.BuildArgs(device, channels), nil
 }
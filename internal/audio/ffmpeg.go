@@ -2,8 +2,10 @@
 
 package audio
 
+import "strconv"
+
 // buildFFmpegCaptureArgs constructs FFmpeg arguments for audio capture.
-func buildFFmpegCaptureArgs(inputFormat, device string) []string {
+func buildFFmpegCaptureArgs(inputFormat, device string, channels int) []string {
 	return []string{
 		"-f", inputFormat,
 		"-i", device,
@@ -12,7 +14,7 @@ func buildFFmpegCaptureArgs(inputFormat, device string) []string {
 		"-loglevel", "warning",
 		"-vn",
 		"-f", "s16le",
-		"-ac", "2",
+		"-ac", strconv.Itoa(channels),
 		"-ar", "48000",
 		"pipe:1",
 	}
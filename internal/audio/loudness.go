@@ -0,0 +1,249 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	// loudnessBlockFrames is the measurement step (100 ms).
+	loudnessBlockFrames = SampleRate / 10
+	// loudnessGatingBlocks is the number of steps in one BS.1770 gating block (400 ms).
+	loudnessGatingBlocks = 4
+	// loudnessShortTermBlocks is the short-term loudness window (3 s).
+	loudnessShortTermBlocks = 30
+	// loudnessWindowBlocks is the window over which gated loudness is integrated (30 s).
+	loudnessWindowBlocks = 300
+	// loudnessFreezeLUFS is the short-term loudness below which the gain is frozen.
+	loudnessFreezeLUFS = -50.0
+	// loudnessAbsoluteGateLUFS is the BS.1770 absolute gating threshold.
+	loudnessAbsoluteGateLUFS = -70.0
+	// loudnessRelativeGateLU is the BS.1770 relative gating threshold.
+	loudnessRelativeGateLU = -10.0
+	// loudnessSlewDBPerSecond limits how fast the applied gain changes.
+	loudnessSlewDBPerSecond = 1.0
+	// loudnessCeilingDB is the peak level the AGC output limiter holds to.
+	loudnessCeilingDB = -1.0
+)
+
+// LoudnessConfig configures automatic loudness normalisation of one output.
+type LoudnessConfig struct {
+	Enabled    bool    `json:"enabled"`
+	TargetLUFS float64 `json:"target_lufs"` // Gated loudness target over a 30 s window
+	MaxGainDB  float64 `json:"max_gain_db"` // Largest boost
+	MaxCutDB   float64 `json:"max_cut_db"`  // Largest reduction
+}
+
+// Validate reports an error if the configuration is enabled with settings out of range.
+func (c *LoudnessConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.TargetLUFS < -36 || c.TargetLUFS > -10 {
+		return fmt.Errorf("loudness.target_lufs: must be between -36 and -10")
+	}
+	if c.MaxGainDB < 0 || c.MaxGainDB > MaxGainDB {
+		return fmt.Errorf("loudness.max_gain_db: must be between 0 and %g", MaxGainDB)
+	}
+	if c.MaxCutDB < 0 || c.MaxCutDB > MaxGainDB {
+		return fmt.Errorf("loudness.max_cut_db: must be between 0 and %g", MaxGainDB)
+	}
+	return nil
+}
+
+// LoudnessStatus reports the state of a loudness AGC.
+type LoudnessStatus struct {
+	GainDB       float64 `json:"gain_db"`       // Gain currently applied
+	LoudnessLUFS float64 `json:"loudness_lufs"` // Measured input loudness
+	Frozen       bool    `json:"frozen,omitempty"`
+}
+
+// AGC is a slow loudness normaliser for stereo s16le PCM. It measures
+// K-weighted loudness (ITU-R BS.1770) of its input with the absolute and
+// relative gates applied over a sliding 30 second window, and moves the gain
+// towards the target at a limited rate. The gain is held while the input is
+// silent so it does not creep up during breaks. A lookahead peak limiter
+// after the gain keeps transients that follow a quiet passage from clipping.
+type AGC struct {
+	mu  sync.Mutex
+	cfg LoudnessConfig
+
+	shelf, highPass [Channels]biquad
+
+	blockSum    float64
+	blockFrames int
+	blocks      [loudnessWindowBlocks]float64 // Mean square of each 100 ms step
+	blockCount  int
+	blockPos    int
+
+	gating [loudnessWindowBlocks]float64 // Mean square of each overlapping 400 ms gating block; 0 = none yet
+
+	power    float64 // Gated mean square over the window; 0 = not yet measured
+	frozen   bool
+	gainDB   float64
+	gain     float64 // Linear gain applied to the current sample
+	gainStep float64 // Per-frame change of gain towards the block target
+
+	limiter *limiter
+	ceiling float64
+	frame   [Channels]float64
+
+	out []byte
+}
+
+// NewAGC returns an AGC with the given configuration.
+func NewAGC(cfg LoudnessConfig) *AGC {
+	a := &AGC{
+		cfg:     cfg,
+		gain:    1,
+		limiter: newLimiter(Channels, int(DefaultLimiterLookaheadMs*SampleRate/1000)),
+		ceiling: maxSampleOut * dbToGain(loudnessCeilingDB),
+	}
+	a.limiter.release = 1 - math.Exp(-1/(DefaultLimiterReleaseMs*SampleRate/1000))
+	for ch := range Channels {
+		// BS.1770 K-weighting at 48 kHz: high-shelf pre-filter and RLB high-pass.
+		a.shelf[ch] = biquad{b0: 1.53512485958697, b1: -2.69169618940638, b2: 1.19839281085285, a1: -1.69065929318241, a2: 0.73248077421585}
+		a.highPass[ch] = biquad{b0: 1, b1: -2, b2: 1, a1: -1.99004745483398, a2: 0.99007225036621}
+	}
+	return a
+}
+
+// SetConfig replaces the configuration. Measurement state is kept.
+func (a *AGC) SetConfig(cfg LoudnessConfig) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cfg = cfg
+}
+
+// Status returns the applied gain and measured loudness.
+func (a *AGC) Status() LoudnessStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	lufs := MinDB
+	if a.power > 0 {
+		lufs = powerToLUFS(a.power)
+	}
+	return LoudnessStatus{
+		GainDB:       math.Round(a.gainDB*10) / 10,
+		LoudnessLUFS: math.Round(lufs*10) / 10,
+		Frozen:       a.frozen,
+	}
+}
+
+// Process returns pcm with the AGC gain applied, or pcm itself when disabled.
+// The returned slice is only valid until the next call.
+func (a *AGC) Process(pcm []byte) []byte {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.cfg.Enabled {
+		return pcm
+	}
+
+	a.out = a.out[:0]
+	for i := 0; i+3 < len(pcm); i += 4 {
+		for ch := range Channels {
+			x := float64(int16(binary.LittleEndian.Uint16(pcm[i+ch*2:]))) //nolint:gosec // Intentional reinterpretation of unsigned PCM to signed
+			k := a.highPass[ch].process(a.shelf[ch].process(x / (maxSampleOut + 1)))
+			a.blockSum += k * k
+			a.frame[ch] = x * a.gain
+		}
+		a.gain += a.gainStep
+
+		a.limiter.process(a.frame[:], a.ceiling, true)
+		for _, y := range a.frame {
+			y = math.Round(math.Max(-maxSampleOut-1, math.Min(maxSampleOut, y)))
+			a.out = binary.LittleEndian.AppendUint16(a.out, uint16(int16(y))) //nolint:gosec // Value is clamped to the int16 range
+		}
+
+		a.blockFrames++
+		if a.blockFrames == loudnessBlockFrames {
+			a.endBlock()
+		}
+	}
+	return a.out
+}
+
+// endBlock updates the loudness estimate and gain target after each 100 ms step.
+func (a *AGC) endBlock() {
+	block := a.blockSum / float64(a.blockFrames)
+	a.blocks[a.blockPos] = block
+	a.blockCount = min(a.blockCount+1, loudnessWindowBlocks)
+	a.blockSum, a.blockFrames = 0, 0
+
+	shortTerm := a.recentPower(loudnessShortTermBlocks)
+	if a.blockCount >= loudnessGatingBlocks {
+		a.gating[a.blockPos] = a.recentPower(loudnessGatingBlocks)
+	}
+	a.blockPos = (a.blockPos + 1) % loudnessWindowBlocks
+
+	a.frozen = belowGate(block) || belowGate(shortTerm)
+	if !a.frozen {
+		if power := a.gatedPower(); power > 0 {
+			a.power = power
+		}
+	}
+
+	target := a.gainDB
+	if !a.frozen && a.power > 0 {
+		target = math.Max(-a.cfg.MaxCutDB, math.Min(a.cfg.MaxGainDB, a.cfg.TargetLUFS-powerToLUFS(a.power)))
+	}
+
+	// Ramp towards the target over the next block
+	step := loudnessSlewDBPerSecond / 10
+	a.gainDB += math.Max(-step, math.Min(step, target-a.gainDB))
+	a.gainStep = (dbToGain(a.gainDB) - a.gain) / loudnessBlockFrames
+}
+
+// recentPower returns the mean square of the newest n steps, or of all
+// steps measured so far if there are fewer.
+func (a *AGC) recentPower(n int) float64 {
+	n = min(n, a.blockCount)
+	var sum float64
+	for i := range n {
+		sum += a.blocks[(a.blockPos-i+loudnessWindowBlocks)%loudnessWindowBlocks]
+	}
+	return sum / float64(n)
+}
+
+// gatedPower returns the BS.1770 gated mean square of the gating blocks in
+// the window: blocks below the absolute gate are dropped, then blocks more
+// than 10 LU below the loudness of the remainder. It returns 0 when no
+// block passes the gates.
+func (a *AGC) gatedPower() float64 {
+	blocks := a.gating[:]
+	absolute := gatedMean(blocks, loudnessAbsoluteGateLUFS)
+	if absolute == 0 {
+		return 0
+	}
+	return gatedMean(blocks, powerToLUFS(absolute)+loudnessRelativeGateLU)
+}
+
+// gatedMean returns the mean of the blocks louder than gate LUFS, or 0 if none are.
+func gatedMean(blocks []float64, gate float64) float64 {
+	var sum float64
+	var n int
+	for _, p := range blocks {
+		if p > 0 && powerToLUFS(p) > gate {
+			sum += p
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// belowGate reports whether a mean square is too quiet to adjust the gain for.
+func belowGate(power float64) bool {
+	return power == 0 || powerToLUFS(power) < loudnessFreezeLUFS
+}
+
+// powerToLUFS converts a summed K-weighted mean square to LUFS.
+func powerToLUFS(power float64) float64 {
+	return -0.691 + 10*math.Log10(power)
+}
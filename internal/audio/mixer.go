@@ -0,0 +1,50 @@
+package audio
+
+import "errors"
+
+// ErrMixerUnsupported is returned when the platform has no mixer control support.
+var ErrMixerUnsupported = errors.New("mixer control is not supported on this platform")
+
+// ErrMixerControlNotFound is returned when a mixer control does not exist on the card.
+var ErrMixerControlNotFound = errors.New("mixer control not found")
+
+// MixerControlType identifies the kind of value a mixer control accepts.
+type MixerControlType string
+
+const (
+	// MixerVolume is a numeric gain control.
+	MixerVolume MixerControlType = "volume"
+	// MixerSwitch is an on/off control.
+	MixerSwitch MixerControlType = "switch"
+	// MixerEnum is a selector with a fixed set of items (e.g. input source).
+	MixerEnum MixerControlType = "enum"
+)
+
+// MixerControl describes a capture control on the selected sound card.
+type MixerControl struct {
+	Name  string           `json:"name"`
+	Type  MixerControlType `json:"type"`
+	Min   int              `json:"min,omitzero"`
+	Max   int              `json:"max,omitzero"`
+	Items []string         `json:"items,omitempty"` // Enum items
+	Value string           `json:"value"`           // Raw volume, "on"/"off", or enum item
+	DB    string           `json:"db,omitzero"`     // Current gain as reported by the driver
+}
+
+// MixerSetting is a persisted value for a single mixer control.
+type MixerSetting struct {
+	Control string `json:"control"`
+	Value   string `json:"value"`
+}
+
+// ApplyMixerSettings sets each persisted control value on the device's card.
+// All settings are attempted; errors are joined together.
+func ApplyMixerSettings(device string, settings []MixerSetting) error {
+	var errs []error
+	for _, s := range settings {
+		if err := SetMixerControl(device, s.Control, s.Value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,206 @@
+//go:build linux
+
+package audio
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mixerCommandTimeout bounds each amixer invocation.
+const mixerCommandTimeout = 5 * time.Second
+
+var (
+	// cardPattern extracts the card from ALSA device strings such as
+	// "default:CARD=sndrpihifiberry", "hw:CARD=x,DEV=0" or "plughw:1,0".
+	cardPattern = regexp.MustCompile(`CARD=([^,]+)|^(?:plug)?hw:(\d+)`)
+
+	controlPattern = regexp.MustCompile(`^Simple mixer control '(.+)',(\d+)$`)
+	limitsPattern  = regexp.MustCompile(`Capture\s+(-?\d+)\s+-\s+(-?\d+)`)
+	valuePattern   = regexp.MustCompile(`Capture\s+(-?\d+)(?:\s+\[\d+%\])?(?:\s+\[(-?[\d.]+dB)\])?`)
+	switchPattern  = regexp.MustCompile(`\[(on|off)\]`)
+	itemsPattern   = regexp.MustCompile(`'([^']*)'`)
+)
+
+// mixerCard returns the ALSA card identifier for a capture device string.
+func mixerCard(device string) string {
+	if device == "" {
+		device = getPlatformConfig().DefaultDevice
+	}
+	m := cardPattern.FindStringSubmatch(device)
+	switch {
+	case m == nil:
+		return ""
+	case m[1] != "":
+		return m[1]
+	default:
+		return m[2]
+	}
+}
+
+// runAmixer executes amixer against the card of the given device.
+func runAmixer(device string, args ...string) (string, error) {
+	card := mixerCard(device)
+	if card == "" {
+		return "", fmt.Errorf("cannot determine sound card for device %q", device)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mixerCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "amixer", append([]string{"-c", card}, args...)...) //nolint:gosec // Card and control names are validated against amixer output
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("amixer: %s", cmp.Or(strings.TrimSpace(string(output)), err.Error()))
+	}
+	return string(output), nil
+}
+
+// MixerControls returns the capture controls of the card behind device.
+func MixerControls(device string) ([]MixerControl, error) {
+	output, err := runAmixer(device, "scontents")
+	if err != nil {
+		return nil, err
+	}
+	return parseMixerControls(output), nil
+}
+
+// parseMixerControls parses "amixer scontents" output and keeps only
+// controls that affect capture: capture volumes, capture switches and enums.
+func parseMixerControls(output string) []MixerControl {
+	var controls []MixerControl
+	var current *MixerControl
+	var caps []string
+
+	flush := func() {
+		if current != nil && current.Type != "" {
+			controls = append(controls, *current)
+		}
+		current, caps = nil, nil
+	}
+
+	for line := range strings.Lines(output) {
+		line = strings.TrimRight(line, "\r\n")
+		if m := controlPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			name := m[1]
+			if m[2] != "0" {
+				name += "," + m[2]
+			}
+			current = &MixerControl{Name: name}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		field, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case field == "Capabilities":
+			caps = strings.Fields(value)
+			switch {
+			case hasCap(caps, "cvolume"):
+				current.Type = MixerVolume
+			case hasCap(caps, "cswitch"):
+				current.Type = MixerSwitch
+			case hasCap(caps, "enum", "cenum"):
+				current.Type = MixerEnum
+			}
+		case field == "Limits" && current.Type == MixerVolume:
+			if m := limitsPattern.FindStringSubmatch(value); m != nil {
+				current.Min, _ = strconv.Atoi(m[1])
+				current.Max, _ = strconv.Atoi(m[2])
+			}
+		case field == "Items" && current.Type == MixerEnum:
+			for _, m := range itemsPattern.FindAllStringSubmatch(value, -1) {
+				current.Items = append(current.Items, m[1])
+			}
+		case strings.HasPrefix(field, "Item") && current.Type == MixerEnum:
+			// The first item line reflects the selection for the first channel.
+			if current.Value == "" {
+				current.Value = strings.Trim(value, "'")
+			}
+		case current.Value == "" && strings.Contains(value, "Capture"):
+			switch current.Type {
+			case MixerVolume:
+				if m := valuePattern.FindStringSubmatch(value); m != nil {
+					current.Value = m[1]
+					current.DB = m[2]
+				}
+			case MixerSwitch:
+				if m := switchPattern.FindStringSubmatch(value); m != nil {
+					current.Value = m[1]
+				}
+			}
+		}
+	}
+	flush()
+
+	return controls
+}
+
+// hasCap reports whether any capability starts with one of the given prefixes.
+// Prefix matching covers the "-joined" variants reported by amixer.
+func hasCap(caps []string, prefixes ...string) bool {
+	return slices.ContainsFunc(caps, func(c string) bool {
+		for _, p := range prefixes {
+			if c == p || strings.HasPrefix(c, p+"-") {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// SetMixerControl validates value against the control and applies it.
+func SetMixerControl(device, name, value string) error {
+	controls, err := MixerControls(device)
+	if err != nil {
+		return err
+	}
+
+	idx := slices.IndexFunc(controls, func(c MixerControl) bool { return c.Name == name })
+	if idx < 0 {
+		return fmt.Errorf("%w: %s", ErrMixerControlNotFound, name)
+	}
+	ctl := controls[idx]
+
+	var arg string
+	switch ctl.Type {
+	case MixerVolume:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < ctl.Min || n > ctl.Max {
+			return fmt.Errorf("%s: value must be between %d and %d", name, ctl.Min, ctl.Max)
+		}
+		arg = strconv.Itoa(n)
+	case MixerSwitch:
+		switch value {
+		case "on":
+			arg = "cap"
+		case "off":
+			arg = "nocap"
+		default:
+			return fmt.Errorf("%s: value must be on or off", name)
+		}
+	case MixerEnum:
+		if !slices.Contains(ctl.Items, value) {
+			return fmt.Errorf("%s: value must be one of %s", name, strings.Join(ctl.Items, ", "))
+		}
+		arg = value
+	}
+
+	_, err = runAmixer(device, "-q", "sset", name, arg)
+	return err
+}
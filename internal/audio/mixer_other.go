@@ -0,0 +1,13 @@
+//go:build !linux
+
+package audio
+
+// MixerControls returns the capture controls of the card behind device.
+func MixerControls(string) ([]MixerControl, error) {
+	return nil, ErrMixerUnsupported
+}
+
+// SetMixerControl validates value against the control and applies it.
+func SetMixerControl(string, string, string) error {
+	return ErrMixerUnsupported
+}
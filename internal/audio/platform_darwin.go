@@ -13,8 +13,8 @@ func getPlatformConfig() CaptureConfig {
 	}
 }
 
-func buildDarwinArgs(device string) []string {
-	return buildFFmpegCaptureArgs("avfoundation", device)
+func buildDarwinArgs(device string, channels int) []string {
+	return buildFFmpegCaptureArgs("avfoundation", device, channels)
 }
 
 // Devices returns the available audio input devices.
@@ -2,7 +2,10 @@
 
 package audio
 
-import "regexp"
+import (
+	"regexp"
+	"strconv"
+)
 
 func getPlatformConfig() CaptureConfig {
 	return CaptureConfig{
@@ -12,12 +15,12 @@ func getPlatformConfig() CaptureConfig {
 	}
 }
 
-func buildLinuxArgs(device string) []string {
+func buildLinuxArgs(device string, channels int) []string {
 	return []string{
 		"-D", device,
 		"-f", "S16_LE",
 		"-r", "48000",
-		"-c", "2",
+		"-c", strconv.Itoa(channels),
 		"-t", "raw",
 		"-q",
 		"-",
@@ -16,8 +16,8 @@ func getPlatformConfig() CaptureConfig {
 	}
 }
 
-func buildWindowsArgs(device string) []string {
-	return buildFFmpegCaptureArgs("dshow", device)
+func buildWindowsArgs(device string, channels int) []string {
+	return buildFFmpegCaptureArgs("dshow", device, channels)
 }
 
 // Devices returns the available audio input devices.
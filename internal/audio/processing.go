@@ -0,0 +1,309 @@
+package audio
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	// MaxGainDB is the largest gain or trim accepted in either direction.
+	MaxGainDB = 24.0
+	// DefaultLimiterCeilingDB is the limiter ceiling suggested for new configurations.
+	DefaultLimiterCeilingDB = -1.0
+	// DefaultLimiterLookaheadMs is the limiter lookahead used when none is configured.
+	DefaultLimiterLookaheadMs = 5.0
+	// DefaultLimiterReleaseMs is the limiter release time used when none is configured.
+	DefaultLimiterReleaseMs = 150.0
+
+	// dcCutoffHz is the corner frequency of the DC blocking filter.
+	dcCutoffHz = 5.0
+	// maxSampleOut is the largest positive 16-bit sample value.
+	maxSampleOut = 32767.0
+)
+
+// ProcessingConfig configures the DSP chain applied to captured audio before
+// it is metered and distributed. Stages run in field order.
+type ProcessingConfig struct {
+	Enabled            bool    `json:"enabled"`
+	GainDB             float64 `json:"gain_db"`            // Input gain/trim
+	DCRemoval          bool    `json:"dc_removal"`         // Remove DC offset
+	HighPassHz         float64 `json:"highpass_hz"`        // 0 = off
+	LimiterEnabled     bool    `json:"limiter_enabled"`    // Brickwall lookahead limiter
+	LimiterCeilingDB   float64 `json:"limiter_ceiling_db"` // dBFS
+	LimiterLookaheadMs float64 `json:"limiter_lookahead_ms"`
+	LimiterReleaseMs   float64 `json:"limiter_release_ms"`
+}
+
+// Validate reports an error if any setting is out of range.
+func (c *ProcessingConfig) Validate() error {
+	if math.Abs(c.GainDB) > MaxGainDB {
+		return fmt.Errorf("gain_db: must be between %g and %g", -MaxGainDB, MaxGainDB)
+	}
+	if c.HighPassHz != 0 && (c.HighPassHz < 10 || c.HighPassHz > 300) {
+		return fmt.Errorf("highpass_hz: must be 0 (off) or between 10 and 300")
+	}
+	if c.LimiterCeilingDB < -20 || c.LimiterCeilingDB > 0 {
+		return fmt.Errorf("limiter_ceiling_db: must be between -20 and 0")
+	}
+	if c.LimiterLookaheadMs != 0 && (c.LimiterLookaheadMs < 1 || c.LimiterLookaheadMs > 20) {
+		return fmt.Errorf("limiter_lookahead_ms: must be between 1 and 20")
+	}
+	if c.LimiterReleaseMs != 0 && (c.LimiterReleaseMs < 10 || c.LimiterReleaseMs > 2000) {
+		return fmt.Errorf("limiter_release_ms: must be between 10 and 2000")
+	}
+	return nil
+}
+
+// ApplyDefaults fills unset limiter timings with their defaults.
+func (c *ProcessingConfig) ApplyDefaults() {
+	c.LimiterLookaheadMs = cmp.Or(c.LimiterLookaheadMs, DefaultLimiterLookaheadMs)
+	c.LimiterReleaseMs = cmp.Or(c.LimiterReleaseMs, DefaultLimiterReleaseMs)
+}
+
+// ValidateTrim reports an error if a per-output trim is out of range.
+func ValidateTrim(trimDB float64) error {
+	if math.Abs(trimDB) > MaxGainDB {
+		return fmt.Errorf("trim_db: must be between %g and %g", -MaxGainDB, MaxGainDB)
+	}
+	return nil
+}
+
+// dbToGain converts decibels to a linear gain factor.
+func dbToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// Processor applies the DSP chain to interleaved s16le PCM in place.
+// Configuration may be changed while audio is being processed.
+// The limiter links gain within each channel pair (1-2, 3-4, ...) so
+// independent programmes on one interface do not duck each other.
+type Processor struct {
+	mu       sync.Mutex
+	cfg      ProcessingConfig
+	channels int
+
+	gain     float64
+	dcCoef   float64
+	dc       []dcBlocker
+	highPass []biquad
+	limiters []*limiter
+	ceiling  float64
+	work     []float64
+}
+
+// NewProcessor returns a Processor for audio with the given channel count.
+func NewProcessor(channels int, cfg ProcessingConfig) *Processor {
+	p := &Processor{
+		channels: channels,
+		dcCoef:   1 - 2*math.Pi*dcCutoffHz/SampleRate,
+		dc:       make([]dcBlocker, channels),
+		highPass: make([]biquad, channels),
+		work:     make([]float64, channels),
+	}
+	p.SetConfig(cfg)
+	return p
+}
+
+// SetConfig replaces the processing settings. Filter state is kept so level
+// changes apply without clicks; the limiter restarts only when its lookahead
+// changes. Toggling the limiter keeps its delay line so latency does not jump.
+func (p *Processor) SetConfig(cfg ProcessingConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	prev := p.cfg
+	p.cfg = cfg
+	p.gain = dbToGain(cfg.GainDB)
+	p.ceiling = maxSampleOut * dbToGain(cfg.LimiterCeilingDB)
+
+	if cfg.HighPassHz != prev.HighPassHz || p.limiters == nil {
+		for ch := range p.highPass {
+			p.highPass[ch].setHighPass(cfg.HighPassHz)
+		}
+	}
+
+	lookahead := int(cmp.Or(cfg.LimiterLookaheadMs, DefaultLimiterLookaheadMs) * SampleRate / 1000)
+	release := cmp.Or(cfg.LimiterReleaseMs, DefaultLimiterReleaseMs) * SampleRate / 1000
+	if p.limiters == nil || p.limiters[0].lookahead != lookahead {
+		p.limiters = p.limiters[:0]
+		for first := 0; first < p.channels; first += 2 {
+			p.limiters = append(p.limiters, newLimiter(min(2, p.channels-first), lookahead))
+		}
+	}
+	for _, l := range p.limiters {
+		l.release = 1 - math.Exp(-1/release)
+	}
+}
+
+// Process runs the chain over data, which must hold whole frames.
+func (p *Processor) Process(data []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.cfg.Enabled {
+		return
+	}
+
+	frameSize := p.channels * 2
+	for off := 0; off+frameSize <= len(data); off += frameSize {
+		for ch := range p.channels {
+			x := float64(int16(binary.LittleEndian.Uint16(data[off+ch*2:]))) * p.gain //nolint:gosec // Intentional reinterpretation of unsigned PCM to signed
+			if p.cfg.DCRemoval {
+				x = p.dc[ch].process(x, p.dcCoef)
+			}
+			if p.cfg.HighPassHz > 0 {
+				x = p.highPass[ch].process(x)
+			}
+			p.work[ch] = x
+		}
+
+		for i, l := range p.limiters {
+			l.process(p.work[i*2:i*2+l.channels], p.ceiling, p.cfg.LimiterEnabled)
+		}
+
+		for ch, x := range p.work {
+			x = math.Round(math.Max(-maxSampleOut-1, math.Min(maxSampleOut, x)))
+			binary.LittleEndian.PutUint16(data[off+ch*2:], uint16(int16(x))) //nolint:gosec // Value is clamped to the int16 range
+		}
+	}
+}
+
+// dcBlocker is a first-order DC blocking filter.
+type dcBlocker struct {
+	x1, y1 float64
+}
+
+func (f *dcBlocker) process(x, coef float64) float64 {
+	y := x - f.x1 + coef*f.y1
+	f.x1, f.y1 = x, y
+	return y
+}
+
+// biquad is a second-order IIR filter section.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+// setHighPass configures a Butterworth high-pass filter at freq Hz.
+func (f *biquad) setHighPass(freq float64) {
+	if freq <= 0 {
+		return
+	}
+	w0 := 2 * math.Pi * freq / SampleRate
+	cosW, sinW := math.Cos(w0), math.Sin(w0)
+	alpha := sinW / math.Sqrt2 // Q = 1/sqrt(2)
+	a0 := 1 + alpha
+
+	f.b0 = (1 + cosW) / 2 / a0
+	f.b1 = -(1 + cosW) / a0
+	f.b2 = f.b0
+	f.a1 = -2 * cosW / a0
+	f.a2 = (1 - alpha) / a0
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// limiter is a lookahead peak limiter for a group of linked channels.
+// Audio is delayed by the lookahead so gain reduction starts before a peak
+// reaches the output. When bypassed the delay stays in place and the gain
+// releases to unity, so switching the limiter on or off does not glitch.
+type limiter struct {
+	channels  int
+	lookahead int
+	release   float64
+
+	delay [][]float64 // Per-channel delay lines
+	pos   int
+
+	// Sliding-window minimum of the gain each frame requires.
+	window []gainPoint
+	head   int
+	size   int
+	n      int
+	env    float64
+	attack float64
+}
+
+type gainPoint struct {
+	n    int
+	gain float64
+}
+
+func newLimiter(channels, lookahead int) *limiter {
+	l := &limiter{
+		channels:  channels,
+		lookahead: lookahead,
+		delay:     make([][]float64, channels),
+		window:    make([]gainPoint, lookahead+1),
+		env:       1,
+		// Reach the target gain within the lookahead.
+		attack: 1 - math.Exp(-3/float64(lookahead)),
+	}
+	for ch := range l.delay {
+		l.delay[ch] = make([]float64, lookahead)
+	}
+	return l
+}
+
+// process replaces frame with the delayed, gain-reduced frame. Active
+// limiting also clamps the output at the ceiling, catching any overshoot
+// left by the smoothed gain envelope.
+func (l *limiter) process(frame []float64, ceiling float64, active bool) {
+	peak := 0.0
+	for _, x := range frame {
+		peak = math.Max(peak, math.Abs(x))
+	}
+	required := 1.0
+	if active && peak > ceiling {
+		required = ceiling / peak
+	}
+	target := l.push(required)
+
+	if target < l.env {
+		l.env += (target - l.env) * l.attack
+	} else {
+		l.env += (target - l.env) * l.release
+	}
+
+	for ch, x := range frame {
+		delayed := l.delay[ch][l.pos]
+		l.delay[ch][l.pos] = x
+		y := delayed * l.env
+		if active {
+			y = math.Max(-ceiling, math.Min(ceiling, y))
+		}
+		frame[ch] = y
+	}
+	l.pos = (l.pos + 1) % l.lookahead
+}
+
+// push adds the required gain of the newest frame and returns the lowest
+// gain required by any frame still inside the lookahead window.
+func (l *limiter) push(gain float64) float64 {
+	capacity := len(l.window)
+	for l.size > 0 {
+		last := (l.head + l.size - 1) % capacity
+		if l.window[last].gain < gain {
+			break
+		}
+		l.size--
+	}
+	l.window[(l.head+l.size)%capacity] = gainPoint{n: l.n, gain: gain}
+	l.size++
+
+	for l.window[l.head].n <= l.n-capacity {
+		l.head = (l.head + 1) % capacity
+		l.size--
+	}
+	l.n++
+	return l.window[l.head].gain
+}
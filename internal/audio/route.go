@@ -0,0 +1,144 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MaxInputChannels is the highest supported capture channel count.
+const MaxInputChannels = 8
+
+// Route selects the input channels an output receives, using 1-based channel
+// numbers. A single channel is duplicated to both sides (mono); a pair maps to
+// left and right. An empty route selects channels 1 and 2.
+type Route []int
+
+// defaultRoute is the stereo pair used when no route is configured.
+var defaultRoute = Route{1, 2}
+
+// Normalize returns the effective route, substituting the default pair for an empty route.
+func (r Route) Normalize() Route {
+	if len(r) == 0 {
+		return defaultRoute
+	}
+	return r
+}
+
+// IsDefault reports whether the route selects channels 1 and 2.
+func (r Route) IsDefault() bool {
+	n := r.Normalize()
+	return len(n) == 2 && n[0] == 1 && n[1] == 2
+}
+
+// Valid reports whether the route selects one or two channels within range.
+func (r Route) Valid(inputChannels int) bool {
+	if len(r) > 2 {
+		return false
+	}
+	for _, ch := range r {
+		if ch < 1 || ch > inputChannels {
+			return false
+		}
+	}
+	return true
+}
+
+// Key returns a compact label such as "1-2" or "3" that identifies the route.
+func (r Route) Key() string {
+	parts := make([]string, 0, 2)
+	for _, ch := range r.Normalize() {
+		parts = append(parts, strconv.Itoa(ch))
+	}
+	return strings.Join(parts, "-")
+}
+
+// Frame is a block of interleaved s16le PCM as delivered by the capture device.
+// Routed stereo views are extracted on demand and cached until the next Reset.
+// A Frame is not safe for concurrent use.
+type Frame struct {
+	data     []byte
+	channels int
+	routed   map[string][]byte
+}
+
+// NewFrame returns a Frame for audio captured with the given channel count.
+func NewFrame(channels int) *Frame {
+	return &Frame{
+		channels: channels,
+		routed:   make(map[string][]byte),
+	}
+}
+
+// Reset replaces the frame contents with freshly captured data.
+func (f *Frame) Reset(data []byte) {
+	f.data = data
+	for key, buf := range f.routed {
+		f.routed[key] = buf[:0]
+	}
+}
+
+// Route returns interleaved stereo s16le PCM for the given route.
+// The returned slice is only valid until the next Reset.
+func (f *Frame) Route(r Route) []byte {
+	if f.channels == Channels && r.IsDefault() {
+		return f.data
+	}
+
+	key := r.Key()
+	buf := f.routed[key]
+	if len(buf) > 0 {
+		return buf
+	}
+
+	r = r.Normalize()
+	left, right := r[0]-1, r[0]-1
+	if len(r) == 2 {
+		right = r[1] - 1
+	}
+
+	frameSize := f.channels * 2
+	frames := len(f.data) / frameSize
+	buf = buf[:0]
+	for i := range frames {
+		off := i * frameSize
+		buf = appendSample(buf, f.data, off, left, f.channels)
+		buf = appendSample(buf, f.data, off, right, f.channels)
+	}
+	f.routed[key] = buf
+	return buf
+}
+
+// Output returns the routed stereo PCM for an output with its trim applied.
+// The returned slice is only valid until the next Reset.
+func (f *Frame) Output(r Route, trimDB float64) []byte {
+	routed := f.Route(r)
+	if trimDB == 0 {
+		return routed
+	}
+
+	key := r.Key() + "@" + strconv.FormatFloat(trimDB, 'f', -1, 64)
+	buf := f.routed[key]
+	if len(buf) > 0 {
+		return buf
+	}
+
+	gain := dbToGain(trimDB)
+	buf = buf[:0]
+	for i := 0; i+1 < len(routed); i += 2 {
+		x := math.Round(float64(int16(binary.LittleEndian.Uint16(routed[i:]))) * gain)                                   //nolint:gosec // Intentional reinterpretation of unsigned PCM to signed
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(int16(math.Max(-maxSampleOut-1, math.Min(maxSampleOut, x))))) //nolint:gosec // Value is clamped to the int16 range
+	}
+	f.routed[key] = buf
+	return buf
+}
+
+// appendSample appends the 16-bit sample of channel ch, or silence when the
+// channel is not present in the capture.
+func appendSample(dst, src []byte, off, ch, channels int) []byte {
+	if ch >= channels {
+		return append(dst, 0, 0)
+	}
+	return append(dst, src[off+ch*2], src[off+ch*2+1])
+}
@@ -17,6 +17,9 @@ type AudioLevels struct {
 	SilenceLevel      SilenceLevel `json:"silence_level,omitzero"`
 	ClipLeft          int          `json:"clip_left,omitzero"`
 	ClipRight         int          `json:"clip_right,omitzero"`
+
+	// Routes holds levels for input routes other than channels 1-2, keyed by route (e.g. "3-4").
+	Routes map[string]AudioLevels `json:"routes,omitempty"`
 }
 
 // Device represents an available audio input device.
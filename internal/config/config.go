@@ -16,6 +16,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/oszuidwest/zwfm-encoder/internal/audio"
+	"github.com/oszuidwest/zwfm-encoder/internal/metadata"
 	"github.com/oszuidwest/zwfm-encoder/internal/types"
 	"github.com/oszuidwest/zwfm-encoder/internal/util"
 )
@@ -52,6 +54,10 @@ const (
 	DefaultStationColorDark = "#E6007E"
 	// DefaultRecordingMaxDurationMinutes is the default max duration for on-demand recordings (4 hours).
 	DefaultRecordingMaxDurationMinutes = 240
+	// MaxRecordingPreRollSeconds limits the audio kept for the start of on-demand recordings (5 minutes).
+	MaxRecordingPreRollSeconds = 300
+	// ExecAllowlistName is the file next to the config file that restricts the commands of exec outputs.
+	ExecAllowlistName = "exec-allowlist.txt"
 )
 
 // SystemConfig holds system-level configuration.
@@ -80,6 +86,14 @@ type WebConfig struct {
 type AudioConfig struct {
 	// Input is the audio input device identifier (platform-specific).
 	Input string `json:"input"`
+	// Channels is the number of interleaved channels captured from the input.
+	Channels int `json:"channels,omitempty"`
+	// Mixer holds persisted capture mixer values for the input's sound card.
+	Mixer []audio.MixerSetting `json:"mixer,omitempty"`
+	// Processing configures the DSP chain applied before distribution.
+	Processing audio.ProcessingConfig `json:"processing"`
+	// TapSocket is the path of a Unix socket serving the programme PCM, or empty for none.
+	TapSocket string `json:"tap_socket,omitempty"`
 }
 
 // SilenceDetectionConfig holds silence detection settings.
@@ -136,31 +150,34 @@ type RecordingConfig struct {
 	APIKey string `json:"api_key"`
 	// MaxDurationMinutes is the maximum allowed duration for on-demand recordings.
 	MaxDurationMinutes int `json:"max_duration_minutes"`
+	// PreRollSeconds is how much audio before the start an on-demand recording includes.
+	PreRollSeconds int `json:"pre_roll_seconds,omitempty"`
 	// Recorders lists configured recording destinations.
 	Recorders []types.Recorder `json:"recorders"`
 }
 
 // Config holds all application configuration and is safe for concurrent use.
+//
+// A Config is scoped to one programme. The root Config returned by [New] is
+// scoped to the main programme; [Config.ForProgramme] returns views for the others
+// that share the root's lock and file.
 type Config struct {
 	// System contains system-level configuration.
 	System SystemConfig `json:"system"`
 	// Web contains web UI branding settings.
 	Web WebConfig `json:"web"`
-	// Audio contains audio input settings.
-	Audio AudioConfig `json:"audio"`
-	// SilenceDetection contains silence detection settings.
-	SilenceDetection SilenceDetectionConfig `json:"silence_detection"`
-	// SilenceDump contains silence dump settings.
-	SilenceDump types.SilenceDumpConfig `json:"silence_dump"`
-	// Notifications contains notification settings.
-	Notifications NotificationsConfig `json:"notifications"`
-	// Streaming contains stream settings.
-	Streaming StreamingConfig `json:"streaming"`
-	// Recording contains recording settings.
-	Recording RecordingConfig `json:"recording"`
-
-	mu       sync.RWMutex
+	// Programme is the main programme. Its sections are stored at the top
+	// level so single-programme config files keep working unchanged.
+	Programme
+	// Programmes lists additional programmes.
+	Programmes []*Programme `json:"programmes,omitempty"`
+
+	mu       *sync.RWMutex
 	filePath string
+
+	// root and prog are set on programme views only.
+	root *Config
+	prog *Programme
 }
 
 // New returns a Config with default values.
@@ -176,16 +193,9 @@ func New(filePath string) *Config {
 			ColorLight:  DefaultStationColorLight,
 			ColorDark:   DefaultStationColorDark,
 		},
-		Audio:            AudioConfig{},
-		SilenceDetection: SilenceDetectionConfig{},
-		SilenceDump: types.SilenceDumpConfig{
-			Enabled:       true, // Enabled by default when FFmpeg is available
-			RetentionDays: types.DefaultSilenceDumpRetentionDays,
-		},
-		Notifications: NotificationsConfig{},
-		Streaming:     StreamingConfig{Streams: []types.Stream{}},
-		Recording:     RecordingConfig{Recorders: []types.Recorder{}},
-		filePath:      filePath,
+		Programme: newProgramme(MainProgrammeID, ""),
+		mu:        new(sync.RWMutex),
+		filePath:  filePath,
 	}
 }
 
@@ -228,6 +238,35 @@ func (c *Config) validate() error {
 	if !util.StationColorPattern.MatchString(c.Web.ColorDark) {
 		return fmt.Errorf("invalid color_dark %q: must be hex format (#RRGGBB)", c.Web.ColorDark)
 	}
+	// Validate programmes
+	ids := map[string]bool{c.Programme.ID: true}
+	for _, p := range c.Programmes {
+		if p.ID == "" || ids[p.ID] {
+			return fmt.Errorf("invalid programme id %q: must be unique and non-empty", p.ID)
+		}
+		if err := ValidateProgrammeName(p.Name); err != nil {
+			return fmt.Errorf("invalid programme %q: %w", p.ID, err)
+		}
+		ids[p.ID] = true
+	}
+	// Validate recording pre-roll
+	for _, p := range append([]*Programme{&c.Programme}, c.Programmes...) {
+		if pr := p.Recording.PreRollSeconds; pr < 0 || pr > MaxRecordingPreRollSeconds {
+			return fmt.Errorf("invalid recording.pre_roll_seconds %d: must be between 0 and %d", pr, MaxRecordingPreRollSeconds)
+		}
+	}
+	// Validate tap sockets: a programme would take over the socket of another
+	sockets := make(map[string]string)
+	for _, p := range append([]*Programme{&c.Programme}, c.Programmes...) {
+		if p.Audio.TapSocket == "" {
+			continue
+		}
+		path := filepath.Clean(p.Audio.TapSocket)
+		if other, ok := sockets[path]; ok {
+			return fmt.Errorf("invalid audio.tap_socket %q: used by programmes %q and %q", p.Audio.TapSocket, other, cmp.Or(p.Name, p.ID))
+		}
+		sockets[path] = cmp.Or(p.Name, p.ID)
+	}
 	return nil
 }
 
@@ -240,32 +279,49 @@ func (c *Config) applyDefaults() {
 	c.Web.StationName = cmp.Or(c.Web.StationName, DefaultStationName)
 	c.Web.ColorLight = cmp.Or(c.Web.ColorLight, DefaultStationColorLight)
 	c.Web.ColorDark = cmp.Or(c.Web.ColorDark, DefaultStationColorDark)
+	// Programme defaults
+	c.Programme.ID = cmp.Or(c.Programme.ID, MainProgrammeID)
+	c.Programme.applyDefaults()
+	for _, p := range c.Programmes {
+		p.applyDefaults()
+	}
+}
+
+// applyDefaults fills unset programme settings with their defaults.
+func (p *Programme) applyDefaults() {
+	// Audio defaults
+	p.Audio.Channels = cmp.Or(p.Audio.Channels, audio.Channels)
+	if p.Audio.Processing == (audio.ProcessingConfig{}) {
+		p.Audio.Processing.LimiterCeilingDB = audio.DefaultLimiterCeilingDB
+	}
+	p.Audio.Processing.ApplyDefaults()
 	// Silence detection defaults
-	c.SilenceDetection.ThresholdDB = cmp.Or(c.SilenceDetection.ThresholdDB, DefaultSilenceThreshold)
-	c.SilenceDetection.DurationMs = cmp.Or(c.SilenceDetection.DurationMs, DefaultSilenceDurationMs)
-	c.SilenceDetection.RecoveryMs = cmp.Or(c.SilenceDetection.RecoveryMs, DefaultSilenceRecoveryMs)
-	c.SilenceDetection.PeakHoldMs = cmp.Or(c.SilenceDetection.PeakHoldMs, DefaultPeakHoldMs)
+	p.SilenceDetection.ThresholdDB = cmp.Or(p.SilenceDetection.ThresholdDB, DefaultSilenceThreshold)
+	p.SilenceDetection.DurationMs = cmp.Or(p.SilenceDetection.DurationMs, DefaultSilenceDurationMs)
+	p.SilenceDetection.RecoveryMs = cmp.Or(p.SilenceDetection.RecoveryMs, DefaultSilenceRecoveryMs)
+	p.SilenceDetection.PeakHoldMs = cmp.Or(p.SilenceDetection.PeakHoldMs, DefaultPeakHoldMs)
 	// Streaming defaults
-	if c.Streaming.Streams == nil {
-		c.Streaming.Streams = []types.Stream{}
+	if p.Streaming.Streams == nil {
+		p.Streaming.Streams = []types.Stream{}
 	}
-	for i := range c.Streaming.Streams {
-		if c.Streaming.Streams[i].CreatedAt == 0 {
-			c.Streaming.Streams[i].CreatedAt = time.Now().UnixMilli()
+	for i := range p.Streaming.Streams {
+		if p.Streaming.Streams[i].CreatedAt == 0 {
+			p.Streaming.Streams[i].CreatedAt = time.Now().UnixMilli()
 		}
 	}
 	// Recording defaults
-	if c.Recording.Recorders == nil {
-		c.Recording.Recorders = []types.Recorder{}
+	if p.Recording.Recorders == nil {
+		p.Recording.Recorders = []types.Recorder{}
 	}
-	for i := range c.Recording.Recorders {
-		if c.Recording.Recorders[i].CreatedAt == 0 {
-			c.Recording.Recorders[i].CreatedAt = time.Now().UnixMilli()
+	for i := range p.Recording.Recorders {
+		if p.Recording.Recorders[i].CreatedAt == 0 {
+			p.Recording.Recorders[i].CreatedAt = time.Now().UnixMilli()
 		}
 	}
 }
 
 func (c *Config) saveLocked() error {
+	c = c.base()
 	data, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
 		return util.WrapError("marshal config", err)
@@ -287,34 +343,37 @@ func (c *Config) saveLocked() error {
 
 // ConfiguredStreams returns a copy of all streams.
 func (c *Config) ConfiguredStreams() []types.Stream {
+	p := c.programme()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return slices.Clone(c.Streaming.Streams)
+	return slices.Clone(p.Streaming.Streams)
 }
 
 // Stream returns the stream with the given ID, or nil if not found.
 func (c *Config) Stream(id string) *types.Stream {
+	p := c.programme()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	idx := slices.IndexFunc(c.Streaming.Streams, func(s types.Stream) bool {
+	idx := slices.IndexFunc(p.Streaming.Streams, func(s types.Stream) bool {
 		return s.ID == id
 	})
 	if idx == -1 {
 		return nil
 	}
-	return &c.Streaming.Streams[idx]
+	return &p.Streaming.Streams[idx]
 }
 
-func (c *Config) findStreamIndex(id string) int {
-	return slices.IndexFunc(c.Streaming.Streams, func(s types.Stream) bool {
+func (p *Programme) findStreamIndex(id string) int {
+	return slices.IndexFunc(p.Streaming.Streams, func(s types.Stream) bool {
 		return s.ID == id
 	})
 }
 
 // AddStream adds a stream to the configuration and persists the change.
 func (c *Config) AddStream(stream *types.Stream) error {
-	if err := stream.Validate(); err != nil {
+	p := c.programme()
+	if err := c.validateStream(stream); err != nil {
 		return err
 	}
 
@@ -332,39 +391,41 @@ func (c *Config) AddStream(stream *types.Stream) error {
 	stream.Enabled = true
 	stream.CreatedAt = time.Now().UnixMilli()
 
-	c.Streaming.Streams = append(c.Streaming.Streams, *stream)
+	p.Streaming.Streams = append(p.Streaming.Streams, *stream)
 	return c.saveLocked()
 }
 
 // RemoveStream removes a stream from the configuration and persists the change.
 func (c *Config) RemoveStream(id string) error {
+	p := c.programme()
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	i := c.findStreamIndex(id)
+	i := p.findStreamIndex(id)
 	if i == -1 {
 		return fmt.Errorf("%w: %s", ErrStreamNotFound, id)
 	}
 
-	c.Streaming.Streams = slices.Delete(c.Streaming.Streams, i, i+1)
+	p.Streaming.Streams = slices.Delete(p.Streaming.Streams, i, i+1)
 	return c.saveLocked()
 }
 
 // UpdateStream updates a stream in the configuration and persists the change.
 func (c *Config) UpdateStream(stream *types.Stream) error {
-	if err := stream.Validate(); err != nil {
+	p := c.programme()
+	if err := c.validateStream(stream); err != nil {
 		return err
 	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	i := c.findStreamIndex(stream.ID)
+	i := p.findStreamIndex(stream.ID)
 	if i == -1 {
 		return fmt.Errorf("%w: %s", ErrStreamNotFound, stream.ID)
 	}
 
-	c.Streaming.Streams[i] = *stream
+	p.Streaming.Streams[i] = *stream
 	return c.saveLocked()
 }
 
@@ -372,26 +433,28 @@ func (c *Config) UpdateStream(stream *types.Stream) error {
 
 // Recorder returns the recorder with the given ID, or nil if not found.
 func (c *Config) Recorder(id string) *types.Recorder {
+	p := c.programme()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	idx := slices.IndexFunc(c.Recording.Recorders, func(r types.Recorder) bool {
+	idx := slices.IndexFunc(p.Recording.Recorders, func(r types.Recorder) bool {
 		return r.ID == id
 	})
 	if idx == -1 {
 		return nil
 	}
-	return &c.Recording.Recorders[idx]
+	return &p.Recording.Recorders[idx]
 }
 
-func (c *Config) findRecorderIndex(id string) int {
-	return slices.IndexFunc(c.Recording.Recorders, func(r types.Recorder) bool {
+func (p *Programme) findRecorderIndex(id string) int {
+	return slices.IndexFunc(p.Recording.Recorders, func(r types.Recorder) bool {
 		return r.ID == id
 	})
 }
 
 // AddRecorder adds a recorder to the configuration and persists the change.
 func (c *Config) AddRecorder(recorder *types.Recorder) error {
+	p := c.programme()
 	if err := recorder.Validate(); err != nil {
 		return err
 	}
@@ -412,26 +475,28 @@ func (c *Config) AddRecorder(recorder *types.Recorder) error {
 	recorder.Enabled = true
 	recorder.CreatedAt = time.Now().UnixMilli()
 
-	c.Recording.Recorders = append(c.Recording.Recorders, *recorder)
+	p.Recording.Recorders = append(p.Recording.Recorders, *recorder)
 	return c.saveLocked()
 }
 
 // RemoveRecorder removes a recorder from the configuration and persists the change.
 func (c *Config) RemoveRecorder(id string) error {
+	p := c.programme()
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	i := c.findRecorderIndex(id)
+	i := p.findRecorderIndex(id)
 	if i == -1 {
 		return fmt.Errorf("%w: %s", ErrRecorderNotFound, id)
 	}
 
-	c.Recording.Recorders = slices.Delete(c.Recording.Recorders, i, i+1)
+	p.Recording.Recorders = slices.Delete(p.Recording.Recorders, i, i+1)
 	return c.saveLocked()
 }
 
 // UpdateRecorder updates a recorder in the configuration and persists the change.
 func (c *Config) UpdateRecorder(recorder *types.Recorder) error {
+	p := c.programme()
 	if err := recorder.Validate(); err != nil {
 		return err
 	}
@@ -439,12 +504,12 @@ func (c *Config) UpdateRecorder(recorder *types.Recorder) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	i := c.findRecorderIndex(recorder.ID)
+	i := p.findRecorderIndex(recorder.ID)
 	if i == -1 {
 		return fmt.Errorf("%w: %s", ErrRecorderNotFound, recorder.ID)
 	}
 
-	c.Recording.Recorders[i] = *recorder
+	p.Recording.Recorders[i] = *recorder
 	return c.saveLocked()
 }
 
@@ -452,45 +517,123 @@ func (c *Config) UpdateRecorder(recorder *types.Recorder) error {
 
 // AudioInput returns the configured audio input device.
 func (c *Config) AudioInput() string {
+	p := c.programme()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return p.Audio.Input
+}
+
+// AudioChannels returns the number of channels captured from the audio input.
+func (c *Config) AudioChannels() int {
+	p := c.programme()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return p.Audio.Channels
+}
+
+// TapSocket returns the path of the PCM tap socket, or empty if disabled.
+func (c *Config) TapSocket() string {
+	p := c.programme()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return p.Audio.TapSocket
+}
+
+// MetadataListeners returns the now-playing listener settings.
+func (c *Config) MetadataListeners() metadata.Config {
+	p := c.programme()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return p.Metadata
+}
+
+// MixerSettings returns a copy of the persisted mixer control values.
+func (c *Config) MixerSettings() []audio.MixerSetting {
+	p := c.programme()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.Audio.Input
+	return slices.Clone(p.Audio.Mixer)
+}
+
+// Processing returns the audio processing settings.
+func (c *Config) Processing() audio.ProcessingConfig {
+	p := c.programme()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return p.Audio.Processing
 }
 
 // FFmpegPath returns the configured FFmpeg binary path.
 func (c *Config) FFmpegPath() string {
+	b := c.base()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.System.FFmpegPath
+	return b.System.FFmpegPath
+}
+
+// ExecAllowlistPath returns the path of the file listing the commands exec
+// outputs may run. It sits next to the config file, so it cannot be changed
+// through the web interface or API.
+func (c *Config) ExecAllowlistPath() string {
+	return filepath.Join(filepath.Dir(c.filePath), ExecAllowlistName)
+}
+
+// validateStream reports an error if the stream is invalid or runs a
+// command outside the exec allowlist.
+func (c *Config) validateStream(stream *types.Stream) error {
+	if err := stream.Validate(); err != nil {
+		return err
+	}
+	if stream.Output() == types.OutputExec {
+		return stream.Exec.CheckAllowlist(c.ExecAllowlistPath())
+	}
+	return nil
 }
 
 // GraphConfig returns a copy of the current Graph/Email configuration.
 func (c *Config) GraphConfig() types.GraphConfig {
+	p := c.programme()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return types.GraphConfig{
-		TenantID:     c.Notifications.Email.TenantID,
-		ClientID:     c.Notifications.Email.ClientID,
-		ClientSecret: c.Notifications.Email.ClientSecret,
-		FromAddress:  c.Notifications.Email.FromAddress,
-		Recipients:   c.Notifications.Email.Recipients,
+		TenantID:     p.Notifications.Email.TenantID,
+		ClientID:     p.Notifications.Email.ClientID,
+		ClientSecret: p.Notifications.Email.ClientSecret,
+		FromAddress:  p.Notifications.Email.FromAddress,
+		Recipients:   p.Notifications.Email.Recipients,
 	}
 }
 
 // RecordingAPIKey returns the API key for recording REST endpoints.
 func (c *Config) RecordingAPIKey() string {
+	p := c.programme()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.Recording.APIKey
+	return p.Recording.APIKey
 }
 
 // Individual setters.
 
 // SetRecordingAPIKey updates the recording API key and persists the change.
 func (c *Config) SetRecordingAPIKey(key string) error {
+	p := c.programme()
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.Recording.APIKey = key
+	p.Recording.APIKey = key
+	return c.saveLocked()
+}
+
+// SetMixerSetting stores a mixer control value and persists the change.
+func (c *Config) SetMixerSetting(setting audio.MixerSetting) error {
+	p := c.programme()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	idx := slices.IndexFunc(p.Audio.Mixer, func(m audio.MixerSetting) bool { return m.Control == setting.Control })
+	if idx >= 0 {
+		p.Audio.Mixer[idx] = setting
+	} else {
+		p.Audio.Mixer = append(p.Audio.Mixer, setting)
+	}
 	return c.saveLocked()
 }
 
@@ -506,14 +649,27 @@ type Snapshot struct {
 	WebPassword string
 
 	// StationName is the station display name shown in the web UI header.
+	// For additional programmes the programme name is appended, so
+	// notifications identify the programme they concern.
 	StationName string
 	// StationColorLight is the accent color for light theme (#RRGGBB).
 	StationColorLight string
 	// StationColorDark is the accent color for dark theme (#RRGGBB).
 	StationColorDark string
 
+	// ProgrammeID identifies the programme this snapshot is scoped to.
+	ProgrammeID string
+	// ProgrammeName is the programme display name.
+	ProgrammeName string
+	// IsMainProgramme reports whether the snapshot is scoped to the main programme.
+	IsMainProgramme bool
+
 	// AudioInput is the audio input device identifier (platform-specific).
 	AudioInput string
+	// AudioChannels is the number of channels captured from the audio input.
+	AudioChannels int
+	// Processing configures the DSP chain applied before distribution.
+	Processing audio.ProcessingConfig
 
 	// SilenceThreshold is the audio level in dB below which silence is detected.
 	SilenceThreshold float64
@@ -556,6 +712,8 @@ type Snapshot struct {
 	RecordingAPIKey string
 	// RecordingMaxDurationMinutes is the maximum allowed duration for on-demand recordings.
 	RecordingMaxDurationMinutes int
+	// RecordingPreRollSeconds is how much audio before the start an on-demand recording includes.
+	RecordingPreRollSeconds int
 
 	// Streams lists configured stream destinations.
 	Streams []types.Stream
@@ -563,58 +721,83 @@ type Snapshot struct {
 	Recorders []types.Recorder
 }
 
+// SetProcessing validates and stores the audio processing settings and persists the change.
+func (c *Config) SetProcessing(processing audio.ProcessingConfig) error {
+	if err := processing.Validate(); err != nil {
+		return err
+	}
+
+	processing.ApplyDefaults()
+
+	p := c.programme()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p.Audio.Processing = processing
+	return c.saveLocked()
+}
+
 // Snapshot returns a point-in-time copy of all configuration values.
 func (c *Config) Snapshot() Snapshot {
+	b := c.base()
+	p := c.programme()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	return Snapshot{
 		// System
-		WebPort:     c.System.Port,
-		WebUser:     c.System.Username,
-		WebPassword: c.System.Password,
+		WebPort:     b.System.Port,
+		WebUser:     b.System.Username,
+		WebPassword: b.System.Password,
 
 		// Web/Branding
-		StationName:       c.Web.StationName,
-		StationColorLight: c.Web.ColorLight,
-		StationColorDark:  c.Web.ColorDark,
+		StationName:       c.stationName(),
+		StationColorLight: b.Web.ColorLight,
+		StationColorDark:  b.Web.ColorDark,
+
+		// Programme
+		ProgrammeID:     p.ID,
+		ProgrammeName:   c.programmeName(),
+		IsMainProgramme: c.root == nil,
 
 		// Audio
-		AudioInput: c.Audio.Input,
+		AudioInput:    p.Audio.Input,
+		AudioChannels: p.Audio.Channels,
+		Processing:    p.Audio.Processing,
 
 		// Silence Detection (with defaults)
-		SilenceThreshold:  cmp.Or(c.SilenceDetection.ThresholdDB, DefaultSilenceThreshold),
-		SilenceDurationMs: cmp.Or(c.SilenceDetection.DurationMs, DefaultSilenceDurationMs),
-		SilenceRecoveryMs: cmp.Or(c.SilenceDetection.RecoveryMs, DefaultSilenceRecoveryMs),
-		PeakHoldMs:        cmp.Or(c.SilenceDetection.PeakHoldMs, DefaultPeakHoldMs),
+		SilenceThreshold:  cmp.Or(p.SilenceDetection.ThresholdDB, DefaultSilenceThreshold),
+		SilenceDurationMs: cmp.Or(p.SilenceDetection.DurationMs, DefaultSilenceDurationMs),
+		SilenceRecoveryMs: cmp.Or(p.SilenceDetection.RecoveryMs, DefaultSilenceRecoveryMs),
+		PeakHoldMs:        cmp.Or(p.SilenceDetection.PeakHoldMs, DefaultPeakHoldMs),
 
 		// Silence Dump
-		SilenceDumpEnabled:       c.SilenceDump.Enabled,
-		SilenceDumpRetentionDays: cmp.Or(c.SilenceDump.RetentionDays, types.DefaultSilenceDumpRetentionDays),
+		SilenceDumpEnabled:       p.SilenceDump.Enabled,
+		SilenceDumpRetentionDays: cmp.Or(p.SilenceDump.RetentionDays, types.DefaultSilenceDumpRetentionDays),
 
 		// Notifications
-		WebhookURL: c.Notifications.Webhook.URL,
+		WebhookURL: p.Notifications.Webhook.URL,
 
 		// Zabbix
-		ZabbixServer: c.Notifications.Zabbix.Server,
-		ZabbixPort:   cmp.Or(c.Notifications.Zabbix.Port, 10051),
-		ZabbixHost:   c.Notifications.Zabbix.Host,
-		ZabbixKey:    c.Notifications.Zabbix.Key,
+		ZabbixServer: p.Notifications.Zabbix.Server,
+		ZabbixPort:   cmp.Or(p.Notifications.Zabbix.Port, 10051),
+		ZabbixHost:   p.Notifications.Zabbix.Host,
+		ZabbixKey:    p.Notifications.Zabbix.Key,
 
 		// Microsoft Graph
-		GraphTenantID:     c.Notifications.Email.TenantID,
-		GraphClientID:     c.Notifications.Email.ClientID,
-		GraphClientSecret: c.Notifications.Email.ClientSecret,
-		GraphFromAddress:  c.Notifications.Email.FromAddress,
-		GraphRecipients:   c.Notifications.Email.Recipients,
+		GraphTenantID:     p.Notifications.Email.TenantID,
+		GraphClientID:     p.Notifications.Email.ClientID,
+		GraphClientSecret: p.Notifications.Email.ClientSecret,
+		GraphFromAddress:  p.Notifications.Email.FromAddress,
+		GraphRecipients:   p.Notifications.Email.Recipients,
 
 		// Recording
-		RecordingAPIKey:             c.Recording.APIKey,
-		RecordingMaxDurationMinutes: cmp.Or(c.Recording.MaxDurationMinutes, DefaultRecordingMaxDurationMinutes),
+		RecordingAPIKey:             p.Recording.APIKey,
+		RecordingMaxDurationMinutes: cmp.Or(p.Recording.MaxDurationMinutes, DefaultRecordingMaxDurationMinutes),
+		RecordingPreRollSeconds:     p.Recording.PreRollSeconds,
 
 		// Entities
-		Streams:   slices.Clone(c.Streaming.Streams),
-		Recorders: slices.Clone(c.Recording.Recorders),
+		Streams:   slices.Clone(p.Streaming.Streams),
+		Recorders: slices.Clone(p.Recording.Recorders),
 	}
 }
 
@@ -640,6 +823,8 @@ func (s *Snapshot) HasZabbix() bool {
 type SettingsUpdate struct {
 	// AudioInput is the audio input device identifier (platform-specific).
 	AudioInput string `json:"audio_input"`
+	// AudioChannels is the number of channels to capture (0 keeps the default).
+	AudioChannels int `json:"audio_channels"`
 	// SilenceThreshold is the audio level in dB below which silence is detected.
 	SilenceThreshold float64 `json:"silence_threshold"`
 	// SilenceDurationMs is how long audio must be below threshold before alerting.
@@ -678,6 +863,11 @@ type SettingsUpdate struct {
 func (s *SettingsUpdate) Validate() []string {
 	var errs []string
 
+	// Capture channel count
+	if s.AudioChannels != 0 && (s.AudioChannels < audio.Channels || s.AudioChannels > audio.MaxInputChannels) {
+		errs = append(errs, fmt.Sprintf("audio_channels: must be between %d and %d", audio.Channels, audio.MaxInputChannels))
+	}
+
 	// Silence detection thresholds
 	if s.SilenceThreshold > 0 || s.SilenceThreshold < -60 {
 		errs = append(errs, "silence_threshold: must be between -60 and 0 dB")
@@ -723,30 +913,35 @@ func (s *SettingsUpdate) Validate() []string {
 // ApplySettings updates all settings atomically with a single file write.
 // Validation should be performed before calling this method.
 func (c *Config) ApplySettings(s *SettingsUpdate) error {
+	p := c.programme()
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Audio
-	c.Audio.Input = s.AudioInput
+	// Audio: mixer values belong to the previous card and are dropped on change.
+	if p.Audio.Input != s.AudioInput {
+		p.Audio.Mixer = nil
+	}
+	p.Audio.Input = s.AudioInput
+	p.Audio.Channels = cmp.Or(s.AudioChannels, audio.Channels)
 
 	// Silence detection
-	c.SilenceDetection.ThresholdDB = s.SilenceThreshold
-	c.SilenceDetection.DurationMs = s.SilenceDurationMs
-	c.SilenceDetection.RecoveryMs = s.SilenceRecoveryMs
-	c.SilenceDump.Enabled = s.SilenceDumpEnabled
-	c.SilenceDump.RetentionDays = s.SilenceDumpRetentionDays
+	p.SilenceDetection.ThresholdDB = s.SilenceThreshold
+	p.SilenceDetection.DurationMs = s.SilenceDurationMs
+	p.SilenceDetection.RecoveryMs = s.SilenceRecoveryMs
+	p.SilenceDump.Enabled = s.SilenceDumpEnabled
+	p.SilenceDump.RetentionDays = s.SilenceDumpRetentionDays
 
 	// Notifications
-	c.Notifications.Webhook.URL = s.WebhookURL
-	c.Notifications.Zabbix.Server = s.ZabbixServer
-	c.Notifications.Zabbix.Port = s.ZabbixPort
-	c.Notifications.Zabbix.Host = s.ZabbixHost
-	c.Notifications.Zabbix.Key = s.ZabbixKey
-	c.Notifications.Email.TenantID = s.GraphTenantID
-	c.Notifications.Email.ClientID = s.GraphClientID
-	c.Notifications.Email.ClientSecret = s.GraphClientSecret
-	c.Notifications.Email.FromAddress = s.GraphFromAddress
-	c.Notifications.Email.Recipients = s.GraphRecipients
+	p.Notifications.Webhook.URL = s.WebhookURL
+	p.Notifications.Zabbix.Server = s.ZabbixServer
+	p.Notifications.Zabbix.Port = s.ZabbixPort
+	p.Notifications.Zabbix.Host = s.ZabbixHost
+	p.Notifications.Zabbix.Key = s.ZabbixKey
+	p.Notifications.Email.TenantID = s.GraphTenantID
+	p.Notifications.Email.ClientID = s.GraphClientID
+	p.Notifications.Email.ClientSecret = s.GraphClientSecret
+	p.Notifications.Email.FromAddress = s.GraphFromAddress
+	p.Notifications.Email.Recipients = s.GraphRecipients
 
 	return c.saveLocked()
 }
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/metadata"
+	"github.com/oszuidwest/zwfm-encoder/internal/types"
+)
+
+const (
+	// MainProgrammeID is the ID of the main programme stored at the top level of the config file.
+	MainProgrammeID = "main"
+	// DefaultMainProgrammeName is the display name of the main programme when none is configured.
+	DefaultMainProgrammeName = "Main"
+)
+
+var (
+	// ErrProgrammeNotFound is returned when a programme ID does not exist in config.
+	ErrProgrammeNotFound = errors.New("programme not found")
+
+	// ErrMainProgramme is returned when an operation is not allowed on the main programme.
+	ErrMainProgramme = errors.New("the main programme cannot be removed")
+)
+
+// Programme holds the settings of one independent audio chain: its own input,
+// silence detection, notifications, streams and recorders.
+type Programme struct {
+	// ID uniquely identifies the programme.
+	ID string `json:"id,omitempty"`
+	// Name is the programme display name.
+	Name string `json:"name,omitempty"`
+	// Audio contains audio input settings.
+	Audio AudioConfig `json:"audio"`
+	// SilenceDetection contains silence detection settings.
+	SilenceDetection SilenceDetectionConfig `json:"silence_detection"`
+	// SilenceDump contains silence dump settings.
+	SilenceDump types.SilenceDumpConfig `json:"silence_dump"`
+	// Notifications contains notification settings.
+	Notifications NotificationsConfig `json:"notifications"`
+	// Streaming contains stream settings.
+	Streaming StreamingConfig `json:"streaming"`
+	// Recording contains recording settings.
+	Recording RecordingConfig `json:"recording"`
+	// Metadata contains the now-playing listeners for playout systems.
+	Metadata metadata.Config `json:"metadata,omitzero"`
+}
+
+// ProgrammeInfo identifies a programme.
+type ProgrammeInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Main bool   `json:"main,omitzero"`
+}
+
+// newProgramme returns a programme with default settings.
+func newProgramme(id, name string) Programme {
+	return Programme{
+		ID:   id,
+		Name: name,
+		SilenceDump: types.SilenceDumpConfig{
+			Enabled:       true, // Enabled by default when FFmpeg is available
+			RetentionDays: types.DefaultSilenceDumpRetentionDays,
+		},
+		Streaming: StreamingConfig{Streams: []types.Stream{}},
+		Recording: RecordingConfig{Recorders: []types.Recorder{}},
+	}
+}
+
+// base returns the root config that owns the file and the system settings.
+func (c *Config) base() *Config {
+	if c.root != nil {
+		return c.root
+	}
+	return c
+}
+
+// programme returns the programme this config is scoped to.
+func (c *Config) programme() *Programme {
+	if c.prog != nil {
+		return c.prog
+	}
+	return &c.Programme
+}
+
+// programmeName returns the display name of the scoped programme.
+func (c *Config) programmeName() string {
+	if c.root == nil {
+		return cmp.Or(c.Programme.Name, DefaultMainProgrammeName)
+	}
+	return c.prog.Name
+}
+
+// stationName returns the station name, suffixed with the programme name for additional programmes.
+func (c *Config) stationName() string {
+	name := c.base().Web.StationName
+	if c.root != nil {
+		name += " - " + c.prog.Name
+	}
+	return name
+}
+
+// ForProgramme returns a config scoped to the programme with the given ID,
+// or nil if it does not exist. An empty ID selects the main programme.
+func (c *Config) ForProgramme(id string) *Config {
+	b := c.base()
+	if id == "" || id == b.Programme.ID {
+		return b
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	idx := slices.IndexFunc(b.Programmes, func(p *Programme) bool { return p.ID == id })
+	if idx == -1 {
+		return nil
+	}
+	return b.view(b.Programmes[idx])
+}
+
+// view returns a config scoped to an additional programme.
+func (c *Config) view(p *Programme) *Config {
+	return &Config{
+		mu:       c.mu,
+		filePath: c.filePath,
+		root:     c,
+		prog:     p,
+	}
+}
+
+// ProgrammeList returns all programmes, main programme first.
+func (c *Config) ProgrammeList() []ProgrammeInfo {
+	b := c.base()
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	list := []ProgrammeInfo{{
+		ID:   b.Programme.ID,
+		Name: cmp.Or(b.Programme.Name, DefaultMainProgrammeName),
+		Main: true,
+	}}
+	for _, p := range b.Programmes {
+		list = append(list, ProgrammeInfo{ID: p.ID, Name: p.Name})
+	}
+	return list
+}
+
+// ValidateProgrammeName reports an error if name is not a usable programme display name.
+func ValidateProgrammeName(name string) error {
+	if strings.TrimSpace(name) == "" || len(name) > 30 {
+		return fmt.Errorf("name: must be 1-30 characters")
+	}
+	return nil
+}
+
+// AddProgramme adds a programme with default settings, persists the change
+// and returns a config scoped to it.
+func (c *Config) AddProgramme(name string) (*Config, error) {
+	if err := ValidateProgrammeName(name); err != nil {
+		return nil, err
+	}
+
+	b := c.base()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	shortID, err := generateShortID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ID: %w", err)
+	}
+
+	p := newProgramme("programme-"+shortID, strings.TrimSpace(name))
+	p.applyDefaults()
+	b.Programmes = append(b.Programmes, &p)
+	if err := b.saveLocked(); err != nil {
+		return nil, err
+	}
+	return b.view(&p), nil
+}
+
+// RenameProgramme changes a programme's display name and persists the change.
+func (c *Config) RenameProgramme(id, name string) error {
+	if err := ValidateProgrammeName(name); err != nil {
+		return err
+	}
+
+	b := c.base()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if id == b.Programme.ID {
+		b.Programme.Name = strings.TrimSpace(name)
+		return b.saveLocked()
+	}
+
+	idx := slices.IndexFunc(b.Programmes, func(p *Programme) bool { return p.ID == id })
+	if idx == -1 {
+		return fmt.Errorf("%w: %s", ErrProgrammeNotFound, id)
+	}
+	b.Programmes[idx].Name = strings.TrimSpace(name)
+	return b.saveLocked()
+}
+
+// RemoveProgramme removes an additional programme and persists the change.
+func (c *Config) RemoveProgramme(id string) error {
+	b := c.base()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if id == b.Programme.ID {
+		return ErrMainProgramme
+	}
+
+	idx := slices.IndexFunc(b.Programmes, func(p *Programme) bool { return p.ID == id })
+	if idx == -1 {
+		return fmt.Errorf("%w: %s", ErrProgrammeNotFound, id)
+	}
+	b.Programmes = slices.Delete(b.Programmes, idx, idx+1)
+	return b.saveLocked()
+}
@@ -0,0 +1,297 @@
+// Package delay provides a time-shift (profanity delay) buffer for PCM audio.
+package delay
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/audio"
+)
+
+const (
+	// MaxSeconds is the longest delay that can be configured.
+	MaxSeconds = 600
+
+	// frameSize is the size of one stereo s16le frame in bytes.
+	frameSize = audio.Channels * 2
+	// rampRate is how much slower than real time audio plays while the delay
+	// is built back up. 2% adds 1.2 seconds of delay per minute.
+	rampRate = 0.02
+)
+
+// Storage selects where delayed audio is held.
+type Storage string
+
+const (
+	// StorageMemory holds the delay in RAM (192 kB per second of delay).
+	StorageMemory Storage = "memory"
+	// StorageDisk holds the delay in a temporary file.
+	StorageDisk Storage = "disk"
+)
+
+// Config configures the delay of one output.
+type Config struct {
+	Seconds int     `json:"seconds"`           // 0 = no delay
+	Storage Storage `json:"storage,omitempty"` // Empty = memory
+}
+
+// Validate reports an error if the delay or storage is invalid.
+func (c *Config) Validate() error {
+	if c.Seconds < 0 || c.Seconds > MaxSeconds {
+		return fmt.Errorf("delay.seconds: must be between 0 and %d", MaxSeconds)
+	}
+	switch c.Storage {
+	case "", StorageMemory, StorageDisk:
+		return nil
+	default:
+		return fmt.Errorf("delay.storage: must be memory or disk")
+	}
+}
+
+// Status reports the state of a delay line.
+type Status struct {
+	TargetSeconds  float64 `json:"target_seconds"`
+	CurrentSeconds float64 `json:"current_seconds"`
+	Ramping        bool    `json:"ramping,omitempty"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// store is the ring buffer backing a delay line.
+type store interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+}
+
+// memoryStore is a store held in RAM.
+type memoryStore []byte
+
+func (m memoryStore) ReadAt(p []byte, off int64) (int, error) {
+	return copy(p, m[off:]), nil
+}
+
+func (m memoryStore) WriteAt(p []byte, off int64) (int, error) {
+	return copy(m[off:], p), nil
+}
+
+func (m memoryStore) Close() error {
+	return nil
+}
+
+// diskStore is a store in a temporary file that is removed on close.
+type diskStore struct {
+	*os.File
+}
+
+func (d diskStore) Close() error {
+	name := d.Name()
+	err := d.File.Close()
+	if rmErr := os.Remove(name); rmErr != nil && err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// Line delays stereo s16le PCM by a fixed time. It starts fully delayed with
+// silence. Dump skips to live audio; Ramp then builds the delay back up by
+// playing audio slightly slower than real time.
+type Line struct {
+	mu     sync.Mutex
+	config Config
+	store  store
+	size   int64 // Ring capacity in frames
+	target int64 // Configured delay in frames
+
+	written int64   // Total frames written
+	read    float64 // Read position in frames; fractional while ramping
+	ramping bool
+	err     error
+
+	in, out []byte
+}
+
+// New creates a delay line with the given configuration. If its buffer cannot
+// be created the line outputs silence and reports the error in its status.
+func New(cfg Config) *Line {
+	target := int64(cfg.Seconds) * audio.SampleRate
+	// One second of headroom keeps the newest chunk from overwriting unread audio.
+	size := target + audio.SampleRate
+
+	l := &Line{
+		config:  cfg,
+		size:    size,
+		target:  target,
+		written: target,
+	}
+
+	if cfg.Storage == StorageDisk {
+		f, err := newDiskStore(size * frameSize)
+		if err != nil {
+			l.fail(err, 0)
+			return l
+		}
+		l.store = f
+	} else {
+		l.store = make(memoryStore, size*frameSize)
+	}
+	return l
+}
+
+// newDiskStore creates a temporary file of the given size.
+func newDiskStore(size int64) (diskStore, error) {
+	f, err := os.CreateTemp("", "encoder-delay-*.pcm")
+	if err != nil {
+		return diskStore{}, fmt.Errorf("create delay file: %w", err)
+	}
+	// Extend sparsely so unwritten audio reads as silence
+	if err := f.Truncate(size); err != nil {
+		_ = diskStore{f}.Close() //nolint:errcheck // Best-effort cleanup after failure
+		return diskStore{}, fmt.Errorf("size delay file: %w", err)
+	}
+	return diskStore{f}, nil
+}
+
+// Config returns the configuration the line was created with.
+func (l *Line) Config() Config {
+	return l.config
+}
+
+// Process writes pcm into the line and returns the same amount of delayed audio.
+// The returned slice is only valid until the next call.
+func (l *Line) Process(pcm []byte) []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	frames := int64(len(pcm) / frameSize)
+	if l.store == nil {
+		return l.fail(l.err, frames)
+	}
+
+	if err := l.ring(l.store.WriteAt, pcm[:frames*frameSize], l.written); err != nil {
+		return l.fail(err, frames)
+	}
+	l.written += frames
+
+	rate := 1.0
+	if l.ramping {
+		// Play slower so the delay grows, without overshooting the target
+		missing := float64(l.target) - (float64(l.written-frames) - l.read)
+		rate = 1 - min(rampRate, max(0, missing)/float64(frames))
+	}
+
+	if err := l.resample(frames, rate); err != nil {
+		return l.fail(err, frames)
+	}
+	l.read += float64(frames) * rate
+
+	if l.ramping && float64(l.written)-l.read >= float64(l.target)-0.5 {
+		l.ramping = false
+		l.read = float64(l.written - l.target)
+	}
+	return l.out
+}
+
+// resample appends frames of output read from l.read at the given playback rate.
+// Must be called with l.mu held.
+func (l *Line) resample(frames int64, rate float64) error {
+	l.out = l.out[:0]
+	start := int64(math.Floor(l.read))
+	if rate == 1 && float64(start) == l.read {
+		l.out = append(l.out, make([]byte, frames*frameSize)...)
+		return l.ring(l.store.ReadAt, l.out, start)
+	}
+
+	// Read one frame beyond the span for interpolation
+	span := int64(math.Floor(l.read+float64(frames-1)*rate)) - start + 2
+	span = min(span, l.written-start)
+	l.in = append(l.in[:0], make([]byte, span*frameSize)...)
+	if err := l.ring(l.store.ReadAt, l.in, start); err != nil {
+		return err
+	}
+
+	for i := range frames {
+		pos := l.read + float64(i)*rate - float64(start)
+		idx := int64(pos)
+		frac := pos - float64(idx)
+		next := min(idx+1, span-1)
+		for ch := range int64(audio.Channels) {
+			a := float64(int16(binary.LittleEndian.Uint16(l.in[idx*frameSize+ch*2:])))               //nolint:gosec // Intentional reinterpretation of unsigned PCM to signed
+			b := float64(int16(binary.LittleEndian.Uint16(l.in[next*frameSize+ch*2:])))              //nolint:gosec // Intentional reinterpretation of unsigned PCM to signed
+			l.out = binary.LittleEndian.AppendUint16(l.out, uint16(int16(math.Round(a+(b-a)*frac)))) //nolint:gosec // Interpolation stays within the int16 range
+		}
+	}
+	return nil
+}
+
+// ring performs a read or write at frame position pos, wrapping around the end of the store.
+func (l *Line) ring(op func([]byte, int64) (int, error), p []byte, pos int64) error {
+	off := (pos % l.size) * frameSize
+	first := min(int64(len(p)), l.size*frameSize-off)
+	if _, err := op(p[:first], off); err != nil {
+		return err
+	}
+	if first < int64(len(p)) {
+		if _, err := op(p[first:], 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fail records a storage error and returns silence, so undelayed audio never
+// reaches the output. Must be called with l.mu held.
+func (l *Line) fail(err error, frames int64) []byte {
+	if l.err == nil {
+		slog.Error("delay buffer failed, sending silence", "error", err)
+	}
+	l.err = err
+	l.out = append(l.out[:0], make([]byte, frames*frameSize)...)
+	return l.out
+}
+
+// Dump discards the delayed audio so the output continues with live audio.
+func (l *Line) Dump() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.read = float64(l.written)
+	l.ramping = false
+}
+
+// Ramp builds the delay back up to the configured time.
+func (l *Line) Ramp() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ramping = float64(l.written)-l.read < float64(l.target)
+}
+
+// Status returns the configured and current delay.
+func (l *Line) Status() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	status := Status{
+		TargetSeconds:  float64(l.target) / audio.SampleRate,
+		CurrentSeconds: math.Round((float64(l.written)-l.read)/audio.SampleRate*10) / 10,
+		Ramping:        l.ramping,
+	}
+	if l.err != nil {
+		status.Error = l.err.Error()
+	}
+	return status
+}
+
+// Close releases the buffer and removes its file, if any.
+func (l *Line) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.store == nil {
+		return nil
+	}
+	return l.store.Close()
+}
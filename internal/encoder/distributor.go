@@ -1,10 +1,12 @@
 package encoder
 
 import (
+	"log/slog"
 	"time"
 
 	"github.com/oszuidwest/zwfm-encoder/internal/audio"
 	"github.com/oszuidwest/zwfm-encoder/internal/config"
+	"github.com/oszuidwest/zwfm-encoder/internal/eventlog"
 	"github.com/oszuidwest/zwfm-encoder/internal/notify"
 	"github.com/oszuidwest/zwfm-encoder/internal/silencedump"
 )
@@ -21,10 +23,21 @@ type Distributor struct {
 	peakHolder         *audio.PeakHolder
 	config             *config.Config
 	callback           AudioLevelCallback
+	eventLogger        *eventlog.Logger
+
+	// routes meters input routes other than channels 1-2, keyed by route.
+	routes map[string]*routeMeter
+}
+
+// routeMeter tracks levels and silence for a single routed channel pair.
+type routeMeter struct {
+	levelData     *audio.LevelData
+	peakHolder    *audio.PeakHolder
+	silenceDetect *audio.SilenceDetector
 }
 
 // NewDistributor returns a new Distributor.
-func NewDistributor(silenceDetect *audio.SilenceDetector, silenceNotifier *notify.SilenceNotifier, silenceDumpManager *silencedump.Manager, peakHolder *audio.PeakHolder, cfg *config.Config, callback AudioLevelCallback) *Distributor {
+func NewDistributor(silenceDetect *audio.SilenceDetector, silenceNotifier *notify.SilenceNotifier, silenceDumpManager *silencedump.Manager, peakHolder *audio.PeakHolder, cfg *config.Config, eventLogger *eventlog.Logger, callback AudioLevelCallback) *Distributor {
 	return &Distributor{
 		levelData:          &audio.LevelData{},
 		silenceDetect:      silenceDetect,
@@ -33,6 +46,92 @@ func NewDistributor(silenceDetect *audio.SilenceDetector, silenceNotifier *notif
 		peakHolder:         peakHolder,
 		config:             cfg,
 		callback:           callback,
+		eventLogger:        eventLogger,
+		routes:             make(map[string]*routeMeter),
+	}
+}
+
+// ProcessRoutes accumulates level data for every routed pair other than channels 1-2.
+// Meters for routes no longer in use are discarded.
+func (d *Distributor) ProcessRoutes(frame *audio.Frame, routes []audio.Route) {
+	active := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		if route.IsDefault() {
+			continue
+		}
+		key := route.Key()
+		active[key] = true
+		meter, ok := d.routes[key]
+		if !ok {
+			meter = &routeMeter{
+				levelData:     &audio.LevelData{},
+				peakHolder:    audio.NewPeakHolder(),
+				silenceDetect: audio.NewSilenceDetector(),
+			}
+			d.routes[key] = meter
+		}
+		pcm := frame.Route(route)
+		audio.ProcessSamples(pcm, len(pcm), meter.levelData)
+	}
+	for key := range d.routes {
+		if !active[key] {
+			delete(d.routes, key)
+		}
+	}
+}
+
+// routeLevels calculates levels and silence state for all metered routes.
+// Silence transitions are recorded in the event log and sent as alerts.
+//
+//nolint:gocritic // hugeParam: called once per level update
+func (d *Distributor) routeLevels(cfg config.Snapshot, silenceCfg audio.SilenceConfig, now time.Time) map[string]audio.AudioLevels {
+	if len(d.routes) == 0 {
+		return nil
+	}
+
+	result := make(map[string]audio.AudioLevels, len(d.routes))
+	for key, meter := range d.routes {
+		levels := audio.CalculateLevels(meter.levelData)
+		meter.levelData.Reset()
+
+		meter.peakHolder.SetHoldDuration(time.Duration(cfg.PeakHoldMs) * time.Millisecond)
+		heldPeakL, heldPeakR := meter.peakHolder.Update(levels.PeakLeft, levels.PeakRight, now)
+
+		event := meter.silenceDetect.Update(levels.RMSLeft, levels.RMSRight, silenceCfg, now)
+		d.logRouteSilence(key, &event, silenceCfg.Threshold)
+		d.silenceNotifier.HandleRouteEvent(key, event)
+
+		result[key] = audio.AudioLevels{
+			Left:              levels.RMSLeft,
+			Right:             levels.RMSRight,
+			PeakLeft:          heldPeakL,
+			PeakRight:         heldPeakR,
+			Silence:           event.InSilence,
+			SilenceDurationMs: event.DurationMs,
+			SilenceLevel:      event.Level,
+			ClipLeft:          levels.ClipLeft,
+			ClipRight:         levels.ClipRight,
+		}
+	}
+	return result
+}
+
+func (d *Distributor) logRouteSilence(key string, event *audio.SilenceEvent, threshold float64) {
+	var err error
+	switch {
+	case event.JustEntered:
+		slog.Warn("silence detected on input route", "channels", key)
+		if d.eventLogger != nil {
+			err = d.eventLogger.LogRouteSilence(eventlog.SilenceStart, key, 0, event.CurrentLevelL, event.CurrentLevelR, threshold)
+		}
+	case event.JustRecovered:
+		slog.Info("audio recovered on input route", "channels", key, "duration_ms", event.TotalDurationMs)
+		if d.eventLogger != nil {
+			err = d.eventLogger.LogRouteSilence(eventlog.SilenceEnd, key, event.TotalDurationMs, event.CurrentLevelL, event.CurrentLevelR, threshold)
+		}
+	}
+	if err != nil {
+		slog.Warn("failed to log route silence", "channels", key, "error", err)
 	}
 }
 
@@ -68,6 +167,9 @@ func (d *Distributor) ProcessSamples(buf []byte, n int) {
 			d.silenceDumpManager.HandleSilenceEvent(silenceEvent)
 		}
 
+		// Routed pairs are metered and checked for silence on the same cadence
+		routes := d.routeLevels(cfg, silenceCfg, now)
+
 		if d.callback != nil {
 			d.callback(&audio.AudioLevels{
 				Left:              levels.RMSLeft,
@@ -79,6 +181,7 @@ func (d *Distributor) ProcessSamples(buf []byte, n int) {
 				SilenceLevel:      silenceEvent.Level,
 				ClipLeft:          levels.ClipLeft,
 				ClipRight:         levels.ClipRight,
+				Routes:            routes,
 			})
 		}
 
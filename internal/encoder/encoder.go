@@ -10,17 +10,25 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"os/exec"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/oszuidwest/zwfm-encoder/internal/audio"
 	"github.com/oszuidwest/zwfm-encoder/internal/config"
+	"github.com/oszuidwest/zwfm-encoder/internal/delay"
 	"github.com/oszuidwest/zwfm-encoder/internal/eventlog"
+	"github.com/oszuidwest/zwfm-encoder/internal/hls"
+	"github.com/oszuidwest/zwfm-encoder/internal/listen"
+	"github.com/oszuidwest/zwfm-encoder/internal/metadata"
 	"github.com/oszuidwest/zwfm-encoder/internal/notify"
 	"github.com/oszuidwest/zwfm-encoder/internal/recording"
+	"github.com/oszuidwest/zwfm-encoder/internal/rtp"
 	"github.com/oszuidwest/zwfm-encoder/internal/silencedump"
 	"github.com/oszuidwest/zwfm-encoder/internal/streaming"
+	"github.com/oszuidwest/zwfm-encoder/internal/tap"
 	"github.com/oszuidwest/zwfm-encoder/internal/types"
 	"github.com/oszuidwest/zwfm-encoder/internal/util"
 )
@@ -43,6 +51,13 @@ var ErrStreamDisabled = errors.New("stream is disabled")
 // ErrStreamNotFound is returned when the stream was not found.
 var ErrStreamNotFound = errors.New("stream not found")
 
+// ErrOutsideSchedule is returned when a stream is not started because it is
+// outside its schedule.
+var ErrOutsideSchedule = errors.New("stream is outside its schedule")
+
+// ErrNoDelay is returned when a stream has no active delay.
+var ErrNoDelay = errors.New("stream has no active delay")
+
 // Encoder is the audio capture and distribution engine.
 type Encoder struct {
 	config              *config.Config
@@ -51,9 +66,18 @@ type Encoder struct {
 	recordingManager    *recording.Manager
 	silenceDumpManager  *silencedump.Manager
 	eventLogger         *eventlog.Logger
+	listenHub           *listen.Hub
+	tapServer           *tap.Server        // Nil when no tap socket is configured
+	metadataListener    *metadata.Listener // Nil when no listener is configured
+	nowPlaying          metadata.NowPlaying
 	sourceCmd           *exec.Cmd
 	sourceCancel        context.CancelFunc
 	sourceStdout        io.ReadCloser
+	sourceChannels      int
+	processor           *audio.Processor
+	loudness            map[string]*audio.AGC  // Per-stream loudness AGC, keyed by stream ID
+	delays              map[string]*delay.Line // Per-stream delay lines, keyed by stream ID
+	outputsMu           sync.Mutex             // Protects loudness and delays
 	state               types.EncoderState
 	stopChan            chan struct{}
 	mu                  sync.RWMutex
@@ -74,6 +98,12 @@ func New(cfg *config.Config, ffmpegPath string) (*Encoder, error) {
 	graphCfg := cfg.GraphConfig()
 	snap := cfg.Snapshot()
 
+	// Additional programmes keep their logs and dumps apart from the main programme.
+	var programme string
+	if !snap.IsMainProgramme {
+		programme = snap.ProgrammeID
+	}
+
 	// Create notifier first (no dependencies)
 	notifier := notify.NewSilenceNotifier(cfg)
 
@@ -81,13 +111,14 @@ func New(cfg *config.Config, ffmpegPath string) (*Encoder, error) {
 	dumpManager := silencedump.NewManager(
 		ffmpegPath,
 		snap.WebPort,
+		programme,
 		snap.SilenceDumpEnabled,
 		snap.SilenceDumpRetentionDays,
 		notifier.OnDumpReady,
 	)
 
 	// Create event logger with platform-specific path
-	eventLogPath := eventlog.DefaultLogPath(snap.WebPort)
+	eventLogPath := eventlog.DefaultLogPath(snap.WebPort, programme)
 	logger, err := eventlog.NewLogger(eventLogPath)
 	if err != nil {
 		return nil, fmt.Errorf("create event logger at %s: %w", eventLogPath, err)
@@ -97,7 +128,7 @@ func New(cfg *config.Config, ffmpegPath string) (*Encoder, error) {
 	notifier.SetEventLogger(logger)
 
 	// Create stream manager and wire up event callback
-	streamMgr := streaming.NewManager(ffmpegPath)
+	streamMgr := streaming.NewManager(ffmpegPath, cfg.ExecAllowlistPath())
 
 	e := &Encoder{
 		config:              cfg,
@@ -105,6 +136,9 @@ func New(cfg *config.Config, ffmpegPath string) (*Encoder, error) {
 		streamManager:       streamMgr,
 		silenceDumpManager:  dumpManager,
 		eventLogger:         logger,
+		listenHub:           listen.NewHub(ffmpegPath),
+		loudness:            make(map[string]*audio.AGC),
+		delays:              make(map[string]*delay.Line),
 		state:               types.StateStopped,
 		backoff:             util.NewBackoff(types.InitialRetryDelay, types.MaxRetryDelay),
 		silenceDetect:       audio.NewSilenceDetector(),
@@ -116,6 +150,16 @@ func New(cfg *config.Config, ffmpegPath string) (*Encoder, error) {
 	// Set event callback on stream manager
 	streamMgr.SetEventCallback(e.onStreamEvent, e.getStreamName)
 
+	// Listeners and sockets that cannot be created must not keep the encoder from running
+	if e.metadataListener, err = metadata.Listen(cfg.MetadataListeners(), e.SetNowPlaying); err != nil {
+		slog.Warn("failed to start metadata listener", "error", err)
+	}
+	if path := cfg.TapSocket(); path != "" {
+		if e.tapServer, err = tap.Listen(path); err != nil {
+			slog.Warn("failed to create PCM tap socket", "path", path, "error", err)
+		}
+	}
+
 	return e, nil
 }
 
@@ -127,6 +171,15 @@ func (e *Encoder) onStreamEvent(streamID, streamName, eventType, message, errMsg
 	if err := e.eventLogger.LogStream(eventlog.EventType(eventType), streamID, streamName, message, errMsg, retryCount, maxRetries); err != nil {
 		slog.Warn("failed to log stream event", "error", err)
 	}
+
+	// A reconnected Icecast mount has lost its title
+	if eventlog.EventType(eventType) == eventlog.StreamStable {
+		if np := e.NowPlaying(); !np.IsZero() {
+			if stream := e.config.Stream(streamID); stream != nil && stream.IcecastURL != "" {
+				go pushIcecast(stream.ID, stream.IcecastURL, np)
+			}
+		}
+	}
 }
 
 func (e *Encoder) getStreamName(streamID string) string {
@@ -134,7 +187,7 @@ func (e *Encoder) getStreamName(streamID string) string {
 	if stream == nil {
 		return ""
 	}
-	return fmt.Sprintf("%s:%d", stream.Host, stream.Port)
+	return stream.Destination()
 }
 
 // EventLogPath returns the path to the event log file.
@@ -152,9 +205,22 @@ func (e *Encoder) InitRecording() error {
 
 	snap := e.config.Snapshot()
 
-	mgr, err := recording.NewManager(e.ffmpegPath, "", snap.RecordingMaxDurationMinutes, e.eventLogger)
+	// Additional programmes keep their pending uploads apart from the main programme.
+	var programme string
+	if !snap.IsMainProgramme {
+		programme = snap.ProgrammeID
+	}
+	tempDir := recording.DefaultTempDir(snap.WebPort, programme)
+
+	mgr, err := recording.NewManager(e.ffmpegPath, tempDir, snap.StationName, snap.RecordingMaxDurationMinutes, snap.RecordingPreRollSeconds, e.eventLogger)
 	if err != nil {
-		return fmt.Errorf("create recording manager: %w", err)
+		// Recorders must keep working; only pending uploads lose their reboot safety
+		fallback := recording.FallbackTempDir(snap.WebPort, programme)
+		slog.Error("recording directory unavailable, pending uploads will not survive a reboot",
+			"dir", tempDir, "fallback", fallback, "error", err)
+		if mgr, err = recording.NewManager(e.ffmpegPath, fallback, snap.StationName, snap.RecordingMaxDurationMinutes, snap.RecordingPreRollSeconds, e.eventLogger); err != nil {
+			return fmt.Errorf("create recording manager: %w", err)
+		}
 	}
 
 	// Add all configured recorders
@@ -164,15 +230,86 @@ func (e *Encoder) InitRecording() error {
 		}
 	}
 
+	mgr.SetGapHandler(e.notifyRecordingGap)
+
 	e.recordingManager = mgr
 	return nil
 }
 
+// notifyRecordingGap alerts the configured notification channels of a gap in
+// the recordings of a recorder.
+func (e *Encoder) notifyRecordingGap(recorderName string, gap recording.CoverageGap) {
+	go notify.NotifyRecordingGap(e.config.Snapshot(), &notify.RecordingGap{
+		Recorder: recorderName,
+		Kind:     string(gap.Kind),
+		Start:    gap.Start,
+		End:      gap.End,
+		File:     gap.File,
+	})
+}
+
 // RecorderStatuses returns status for all configured recorders.
 func (e *Encoder) RecorderStatuses() map[string]types.ProcessStatus {
 	return e.recordingManager.Statuses()
 }
 
+// Recordings lists the files of a recorder, newest first.
+func (e *Encoder) Recordings(ctx context.Context, id string) ([]recording.Recording, error) {
+	return e.recordingManager.Recordings(ctx, id)
+}
+
+// OpenRecording opens a file of a recorder for reading.
+func (e *Encoder) OpenRecording(ctx context.Context, id, name string) (io.ReadSeekCloser, time.Time, error) {
+	return e.recordingManager.OpenRecording(ctx, id, name)
+}
+
+// PresignRecording returns a time-limited S3 download URL for a file of a recorder.
+func (e *Encoder) PresignRecording(ctx context.Context, id, name string) (string, error) {
+	return e.recordingManager.PresignRecording(ctx, id, name)
+}
+
+// StartExport queues a job that exports a time range from the files of a recorder.
+func (e *Encoder) StartExport(id string, start, end time.Time, codec types.Codec) (recording.Export, error) {
+	return e.recordingManager.StartExport(id, start, end, codec)
+}
+
+// VerifyChain walks the hash chain of the files of a compliance recorder that
+// started in [from, to).
+func (e *Encoder) VerifyChain(ctx context.Context, id string, from, to time.Time) (*recording.ChainReport, error) {
+	return e.recordingManager.VerifyChain(ctx, id, from, to)
+}
+
+// RecordingCoverage compares the files of a recorder in [from, to) with the
+// files its rotation mode should have written.
+func (e *Encoder) RecordingCoverage(ctx context.Context, id string, from, to time.Time) (*recording.CoverageReport, error) {
+	return e.recordingManager.Coverage(ctx, id, from, to)
+}
+
+// Exports returns the export jobs of a recorder, newest first.
+func (e *Encoder) Exports(id string) []recording.Export {
+	return e.recordingManager.Exports(id)
+}
+
+// Export returns an export job of a recorder.
+func (e *Encoder) Export(id, jobID string) (recording.Export, error) {
+	return e.recordingManager.Export(id, jobID)
+}
+
+// OpenExport opens the result of a finished export job for reading.
+func (e *Encoder) OpenExport(id, jobID string) (*os.File, recording.Export, error) {
+	return e.recordingManager.OpenExport(id, jobID)
+}
+
+// DeleteExport cancels and removes an export job.
+func (e *Encoder) DeleteExport(id, jobID string) error {
+	return e.recordingManager.DeleteExport(id, jobID)
+}
+
+// RecorderSchedules returns the scheduled shows of all recorders.
+func (e *Encoder) RecorderSchedules() []recording.ShowStatus {
+	return e.recordingManager.Schedules()
+}
+
 // State returns the current encoder state.
 func (e *Encoder) State() types.EncoderState {
 	e.mu.RLock()
@@ -239,6 +376,7 @@ func (e *Encoder) StreamStatuses(streams []types.Stream) map[string]types.Proces
 	})
 
 	// Build complete status map for all configured streams
+	now := time.Now()
 	result := make(map[string]types.ProcessStatus, len(streams))
 	for _, stream := range streams {
 		if status, exists := processStatuses[stream.ID]; exists {
@@ -254,6 +392,12 @@ func (e *Encoder) StreamStatuses(streams []types.Stream) map[string]types.Proces
 				State:      types.ProcessDisabled,
 				MaxRetries: stream.MaxRetriesOrDefault(),
 			}
+		} else if e.IsRunning() && !stream.Schedule.Allows(now) {
+			// Stream is enabled but outside its schedule
+			result[stream.ID] = types.ProcessStatus{
+				State:      types.ProcessScheduled,
+				MaxRetries: stream.MaxRetriesOrDefault(),
+			}
 		} else {
 			// Stream is enabled but has no process (encoder not running)
 			result[stream.ID] = types.ProcessStatus{
@@ -261,6 +405,21 @@ func (e *Encoder) StreamStatuses(streams []types.Stream) map[string]types.Proces
 				MaxRetries: stream.MaxRetriesOrDefault(),
 			}
 		}
+		status := result[stream.ID]
+		if stream.IsEnabled() && stream.Schedule.IsSet() {
+			status.NextChange = stream.Schedule.Next(now)
+		}
+		e.outputsMu.Lock()
+		if agc := e.loudness[stream.ID]; agc != nil && stream.Loudness.Enabled {
+			loudness := agc.Status()
+			status.Loudness = &loudness
+		}
+		if line := e.delays[stream.ID]; line != nil {
+			delayStatus := line.Status()
+			status.Delay = &delayStatus
+		}
+		result[stream.ID] = status
+		e.outputsMu.Unlock()
 	}
 	return result
 }
@@ -287,6 +446,7 @@ func (e *Encoder) Start() error {
 	e.peakHolder.Reset()
 
 	go e.runSourceLoop()
+	go e.runScheduler(e.stopChan)
 
 	return nil
 }
@@ -324,6 +484,9 @@ func (e *Encoder) Stop() error {
 		errs = append(errs, fmt.Errorf("stop recording: %w", err))
 	}
 
+	// End monitoring; listeners reconnect once the encoder runs again
+	e.listenHub.Close()
+
 	// Send graceful termination signal to source.
 	if sourceProcess != nil && sourceProcess.Process != nil {
 		if err := util.GracefulSignal(sourceProcess.Process); err != nil {
@@ -372,6 +535,21 @@ func (e *Encoder) Stop() error {
 	return errors.Join(errs...)
 }
 
+// Close stops the encoder and releases its event log, tap socket and metadata listener. The encoder cannot be reused afterwards.
+func (e *Encoder) Close() error {
+	err := e.Stop()
+	if e.tapServer != nil {
+		e.tapServer.Close()
+	}
+	if e.metadataListener != nil {
+		e.metadataListener.Close()
+	}
+	if e.eventLogger != nil {
+		err = errors.Join(err, e.eventLogger.Close())
+	}
+	return err
+}
+
 // Restart stops and restarts the encoder.
 func (e *Encoder) Restart() error {
 	if err := e.Stop(); err != nil {
@@ -400,6 +578,9 @@ func (e *Encoder) StartStream(streamID string) error {
 	if !stream.IsEnabled() {
 		return ErrStreamDisabled
 	}
+	if !stream.Schedule.Allows(time.Now()) {
+		return ErrOutsideSchedule
+	}
 
 	// Start preserves existing retry state automatically
 	if err := e.streamManager.Start(stream); err != nil {
@@ -416,6 +597,115 @@ func (e *Encoder) StopStream(streamID string) error {
 	return e.streamManager.Stop(streamID)
 }
 
+// HLS returns the packager of an HLS stream, or nil if the stream has not
+// been started since it was last stopped.
+func (e *Encoder) HLS(streamID string) *hls.Packager {
+	return e.streamManager.Packager(streamID)
+}
+
+// RTP returns the sender of an RTP stream, or nil if the stream has not
+// been started since it was last stopped.
+func (e *Encoder) RTP(streamID string) *rtp.Sender {
+	return e.streamManager.Sender(streamID)
+}
+
+// NowPlaying returns the item on air, or a zero value if none has been received.
+func (e *Encoder) NowPlaying() metadata.NowPlaying {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.nowPlaying
+}
+
+// SetNowPlaying records a new item on air. It is forwarded to the Icecast
+// mounts of running streams, in-band to Ogg streams and the Opus listen
+// stream, and to the cue sheets of recordings.
+func (e *Encoder) SetNowPlaying(np metadata.NowPlaying) {
+	e.mu.Lock()
+	e.nowPlaying = np
+	running := e.state == types.StateRunning
+	e.mu.Unlock()
+
+	slog.Info("now playing", "artist", np.Artist, "title", np.Title)
+
+	e.streamManager.SetTitle(np.Artist, np.Title)
+	e.listenHub.SetTitle(np.Artist, np.Title)
+
+	if running {
+		for _, stream := range e.config.ConfiguredStreams() {
+			if stream.IsEnabled() && stream.IcecastURL != "" {
+				go pushIcecast(stream.ID, stream.IcecastURL, np)
+			}
+		}
+	}
+	// Titles can arrive before recording is initialised
+	if e.recordingManager != nil {
+		e.recordingManager.SetNowPlaying(np)
+	}
+}
+
+// pushIcecast sends a title to the Icecast mount of a stream, logging failures.
+func pushIcecast(streamID, url string, np metadata.NowPlaying) {
+	if err := metadata.PushIcecast(context.Background(), url, np); err != nil {
+		slog.Warn("failed to update icecast metadata", "stream_id", streamID, "error", err)
+	}
+}
+
+// Taps returns the readers connected to the PCM tap socket.
+func (e *Encoder) Taps() []tap.Status {
+	if e.tapServer == nil {
+		return nil
+	}
+	return e.tapServer.Statuses()
+}
+
+// Listen subscribes to a live monitoring encode of the programme audio or,
+// for a stream ID, of the audio sent to that stream.
+func (e *Encoder) Listen(source string, format listen.Format) (*listen.Listener, error) {
+	if e.State() != types.StateRunning {
+		return nil, ErrNotRunning
+	}
+	if source != listen.ProgrammeSource && e.config.Stream(source) == nil {
+		return nil, ErrStreamNotFound
+	}
+	return e.listenHub.Subscribe(source, format)
+}
+
+// DumpDelay discards the delayed audio of a stream so it continues with live audio.
+func (e *Encoder) DumpDelay(streamID string) error {
+	line, err := e.delayLine(streamID)
+	if err != nil {
+		return err
+	}
+	line.Dump()
+	return nil
+}
+
+// RampDelay builds the delay of a stream back up to its configured time.
+func (e *Encoder) RampDelay(streamID string) error {
+	line, err := e.delayLine(streamID)
+	if err != nil {
+		return err
+	}
+	line.Ramp()
+	return nil
+}
+
+// delayLine returns the active delay line of a stream.
+func (e *Encoder) delayLine(streamID string) (*delay.Line, error) {
+	if e.config.Stream(streamID) == nil {
+		return nil, ErrStreamNotFound
+	}
+
+	e.outputsMu.Lock()
+	defer e.outputsMu.Unlock()
+
+	line := e.delays[streamID]
+	if line == nil {
+		return nil, ErrNoDelay
+	}
+	return line, nil
+}
+
 // TriggerTestEmail sends a test email.
 func (e *Encoder) TriggerTestEmail() error {
 	cfg := e.config.Snapshot()
@@ -445,6 +735,17 @@ func (e *Encoder) UpdateSilenceConfig() {
 	}
 }
 
+// UpdateProcessing applies the configured audio processing to running capture.
+func (e *Encoder) UpdateProcessing() {
+	e.mu.RLock()
+	processor := e.processor
+	e.mu.RUnlock()
+
+	if processor != nil {
+		processor.SetConfig(e.config.Processing())
+	}
+}
+
 // UpdateSilenceDumpConfig applies the current silence dump configuration.
 func (e *Encoder) UpdateSilenceDumpConfig() {
 	snap := e.config.Snapshot()
@@ -532,13 +833,21 @@ func (e *Encoder) runSourceLoop() {
 
 // runSource starts the audio capture process and blocks until it exits.
 func (e *Encoder) runSource() (string, error) {
-	audioInput := e.config.Snapshot().AudioInput
-	cmdName, args, err := audio.BuildCaptureCommand(audioInput, e.ffmpegPath)
+	snap := e.config.Snapshot()
+	audioInput := snap.AudioInput
+	cmdName, args, err := audio.BuildCaptureCommand(audioInput, e.ffmpegPath, snap.AudioChannels)
 	if err != nil {
 		return "", err
 	}
 
-	slog.Info("starting audio capture", "command", cmdName, "input", audioInput)
+	// Re-apply mixer values: cards may reset them after re-enumeration.
+	if mixer := e.config.MixerSettings(); len(mixer) > 0 {
+		if err := audio.ApplyMixerSettings(audioInput, mixer); err != nil {
+			slog.Warn("failed to apply mixer settings", "input", audioInput, "error", err)
+		}
+	}
+
+	slog.Info("starting audio capture", "command", cmdName, "input", audioInput, "channels", snap.AudioChannels)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	cmd := exec.CommandContext(ctx, cmdName, args...) //nolint:gosec // cmdName is from internal platform config (arecord/ffmpeg)
@@ -564,6 +873,7 @@ func (e *Encoder) runSource() (string, error) {
 		e.sourceCmd = cmd
 		e.sourceCancel = cancel
 		e.sourceStdout = stdoutPipe
+		e.sourceChannels = snap.AudioChannels
 		e.state = types.StateRunning
 		e.startTime = time.Now()
 		e.lastError = ""
@@ -606,7 +916,10 @@ func (e *Encoder) startEnabledStreams() {
 			slog.Info("skipping disabled stream", "stream_id", stream.ID)
 			continue
 		}
-		if err := e.StartStream(stream.ID); err != nil {
+		switch err := e.StartStream(stream.ID); {
+		case errors.Is(err, ErrOutsideSchedule):
+			slog.Info("stream outside schedule, not starting", "stream_id", stream.ID)
+		case err != nil:
 			slog.Error("failed to start stream", "stream_id", stream.ID, "error", err)
 		}
 	}
@@ -618,8 +931,21 @@ func (e *Encoder) startEnabledStreams() {
 }
 
 // runDistributor reads PCM audio and distributes it to streams, recorders, and silence detection.
+// Each output receives the stereo pair (or duplicated mono channel) selected by its channel route.
 func (e *Encoder) runDistributor() {
-	buf := make([]byte, 19200) // ~100ms of audio at 48kHz stereo
+	e.mu.RLock()
+	channels := e.sourceChannels
+	e.mu.RUnlock()
+
+	// ~100ms of audio at 48kHz, read in whole frames so routing stays channel-aligned
+	buf := make([]byte, audio.SampleRate/10*channels*2)
+	frame := audio.NewFrame(channels)
+	processor := audio.NewProcessor(channels, e.config.Processing())
+
+	e.mu.Lock()
+	e.processor = processor
+	e.mu.Unlock()
+	defer e.closeDelays()
 
 	distributor := NewDistributor(
 		e.silenceDetect,
@@ -627,6 +953,7 @@ func (e *Encoder) runDistributor() {
 		e.silenceDumpManager,
 		e.peakHolder,
 		e.config,
+		e.eventLogger,
 		e.updateAudioLevels,
 	)
 
@@ -647,36 +974,153 @@ func (e *Encoder) runDistributor() {
 		default:
 		}
 
-		n, err := reader.Read(buf)
+		n, err := io.ReadFull(reader, buf)
 		if err != nil {
 			return
 		}
-		if n == 0 {
-			continue
-		}
+		processor.Process(buf[:n])
+		frame.Reset(buf[:n])
+		primary := frame.Route(nil)
 
 		// Feed audio to silence dump manager
 		if e.silenceDumpManager != nil {
-			e.silenceDumpManager.WriteAudio(buf[:n])
+			e.silenceDumpManager.WriteAudio(primary)
 		}
 
-		distributor.ProcessSamples(buf, n)
+		distributor.ProcessSamples(primary, len(primary))
+		e.listenHub.WriteAudio(listen.ProgrammeSource, primary)
+		if e.tapServer != nil {
+			e.tapServer.WriteAudio(primary)
+		}
 
-		for _, stream := range e.config.ConfiguredStreams() {
+		streams := e.config.ConfiguredStreams()
+		distributor.ProcessRoutes(frame, e.activeRoutes(streams))
+
+		for i := range streams {
+			stream := &streams[i]
+			pcm := frame.Output(stream.Channels, stream.TrimDB)
+			if agc := e.streamLoudness(stream); agc != nil {
+				pcm = agc.Process(pcm)
+			}
+			if line := e.streamDelay(stream); line != nil {
+				pcm = line.Process(pcm)
+			}
+			e.listenHub.WriteAudio(stream.ID, pcm)
 			// WriteAudio logs errors internally and marks stream as stopped
-			_ = e.streamManager.WriteAudio(stream.ID, buf[:n]) //nolint:errcheck // Errors logged internally by WriteAudio
+			_ = e.streamManager.WriteAudio(stream.ID, pcm) //nolint:errcheck // Errors logged internally by WriteAudio
 		}
+		e.pruneOutputs(streams)
 
 		// Send audio to recording manager
-		_ = e.recordingManager.WriteAudio(buf[:n]) //nolint:errcheck // Errors logged internally by recording manager
+		_ = e.recordingManager.WriteAudio(frame) //nolint:errcheck // Errors logged internally by recording manager
 	}
 }
 
+// streamLoudness returns the loudness AGC for a stream, creating it on first
+// use, or nil if the stream does not use loudness normalisation.
+func (e *Encoder) streamLoudness(stream *types.Stream) *audio.AGC {
+	e.outputsMu.Lock()
+	defer e.outputsMu.Unlock()
+
+	if !stream.Loudness.Enabled {
+		delete(e.loudness, stream.ID)
+		return nil
+	}
+	agc, exists := e.loudness[stream.ID]
+	if !exists {
+		agc = audio.NewAGC(stream.Loudness)
+		e.loudness[stream.ID] = agc
+	} else {
+		agc.SetConfig(stream.Loudness)
+	}
+	return agc
+}
+
+// streamDelay returns the delay line for an enabled stream, or nil if the
+// stream is not delayed. A line is recreated when the delay settings change.
+func (e *Encoder) streamDelay(stream *types.Stream) *delay.Line {
+	e.outputsMu.Lock()
+	defer e.outputsMu.Unlock()
+
+	line := e.delays[stream.ID]
+	if line != nil && (!stream.IsEnabled() || line.Config() != stream.Delay) {
+		e.closeDelay(stream.ID)
+		line = nil
+	}
+	if line == nil && stream.IsEnabled() && stream.Delay.Seconds > 0 {
+		line = delay.New(stream.Delay)
+		e.delays[stream.ID] = line
+	}
+	return line
+}
+
+// pruneOutputs discards the loudness and delay state of streams that were removed.
+func (e *Encoder) pruneOutputs(streams []types.Stream) {
+	e.outputsMu.Lock()
+	defer e.outputsMu.Unlock()
+
+	if len(e.loudness) <= len(streams) && len(e.delays) <= len(streams) {
+		return
+	}
+	configured := func(id string) bool {
+		return slices.ContainsFunc(streams, func(s types.Stream) bool { return s.ID == id })
+	}
+	for id := range e.loudness {
+		if !configured(id) {
+			delete(e.loudness, id)
+		}
+	}
+	for id := range e.delays {
+		if !configured(id) {
+			e.closeDelay(id)
+		}
+	}
+}
+
+// closeDelays releases all delay lines so a restart begins with a full delay.
+func (e *Encoder) closeDelays() {
+	e.outputsMu.Lock()
+	defer e.outputsMu.Unlock()
+
+	for id := range e.delays {
+		e.closeDelay(id)
+	}
+}
+
+// closeDelay releases the delay line of a stream. Must be called with e.outputsMu held.
+func (e *Encoder) closeDelay(id string) {
+	if err := e.delays[id].Close(); err != nil {
+		slog.Warn("failed to release delay buffer", "stream_id", id, "error", err)
+	}
+	delete(e.delays, id)
+}
+
+// activeRoutes returns the channel routes used by enabled streams and recorders.
+func (e *Encoder) activeRoutes(streams []types.Stream) []audio.Route {
+	var routes []audio.Route
+	for i := range streams {
+		if streams[i].IsEnabled() {
+			routes = append(routes, streams[i].Channels)
+		}
+	}
+	for _, recorder := range e.config.Snapshot().Recorders {
+		if recorder.IsEnabled() {
+			routes = append(routes, recorder.Channels)
+		}
+	}
+	return routes
+}
+
 func (e *Encoder) updateAudioLevels(levels *audio.AudioLevels) {
 	e.mu.Lock()
-	defer e.mu.Unlock()
 	e.audioLevels = *levels
 	e.lastKnownLevels = *levels // Update cache for TryRLock fallback
+	e.mu.Unlock()
+
+	// Recordings note silence on their channels in their sidecars
+	if e.recordingManager != nil {
+		e.recordingManager.SetSilence(levels)
+	}
 }
 
 // pollUntil signals when the given condition becomes true.
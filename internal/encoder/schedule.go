@@ -0,0 +1,62 @@
+package encoder
+
+import (
+	"log/slog"
+	"time"
+)
+
+// scheduleInterval is how often stream schedules are evaluated.
+const scheduleInterval = 5 * time.Second
+
+// runScheduler starts and stops scheduled streams at the edges of their
+// windows until stopChan is closed. Streams are only acted on when their
+// schedule changes, so a stream stopped by other means stays stopped until
+// its next window opens.
+func (e *Encoder) runScheduler(stopChan <-chan struct{}) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	allowed := make(map[string]bool)
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+		}
+		if !e.IsRunning() {
+			continue
+		}
+
+		now := time.Now()
+		seen := make(map[string]bool)
+		for _, stream := range e.config.ConfiguredStreams() {
+			if !stream.IsEnabled() || !stream.Schedule.IsSet() {
+				continue
+			}
+			seen[stream.ID] = true
+
+			allow := stream.Schedule.Allows(now)
+			prev, known := allowed[stream.ID]
+			allowed[stream.ID] = allow
+			_, _, exists := e.streamManager.StreamInfo(stream.ID)
+
+			switch {
+			case allow && known && !prev && !exists:
+				slog.Info("starting stream for schedule window", "stream_id", stream.ID)
+				if err := e.StartStream(stream.ID); err != nil {
+					slog.Error("failed to start scheduled stream", "stream_id", stream.ID, "error", err)
+				}
+			case !allow && (!known || prev) && exists:
+				slog.Info("stopping stream outside schedule window", "stream_id", stream.ID)
+				if err := e.streamManager.StopScheduled(stream.ID); err != nil {
+					slog.Error("failed to stop scheduled stream", "stream_id", stream.ID, "error", err)
+				}
+			}
+		}
+		for id := range allowed {
+			if !seen[id] {
+				delete(allowed, id)
+			}
+		}
+	}
+}
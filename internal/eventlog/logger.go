@@ -59,6 +59,8 @@ const (
 	UploadAbandoned EventType = "upload_abandoned"
 	// CleanupCompleted indicates a cleanup completed event.
 	CleanupCompleted EventType = "cleanup_completed"
+	// RecorderGap indicates a hole in the archive of a recorder.
+	RecorderGap EventType = "recorder_gap"
 )
 
 // Event is a single log entry with type-specific details.
@@ -80,9 +82,10 @@ type StreamDetails struct {
 
 // SilenceDetails holds silence event information.
 type SilenceDetails struct {
-	LevelLeftDB   float64 `json:"level_left_db"`  // dB
-	LevelRightDB  float64 `json:"level_right_db"` // dB
-	ThresholdDB   float64 `json:"threshold_db"`   // dB
+	Channels      string  `json:"channels,omitempty"` // Input route, e.g. "3-4"; omitted for channels 1-2
+	LevelLeftDB   float64 `json:"level_left_db"`      // dB
+	LevelRightDB  float64 `json:"level_right_db"`     // dB
+	ThresholdDB   float64 `json:"threshold_db"`       // dB
 	DurationMs    int64   `json:"duration_ms,omitempty"`
 	DumpPath      string  `json:"dump_path,omitempty"`
 	DumpFilename  string  `json:"dump_filename,omitempty"`
@@ -99,8 +102,14 @@ type RecorderDetails struct {
 	S3Key        string `json:"s3_key,omitempty"`
 	Error        string `json:"error,omitempty"`
 	RetryCount   int    `json:"retry,omitempty"`
+	SHA256       string `json:"sha256,omitempty"` // Checksum of a verified upload
 	FilesDeleted int    `json:"files_deleted,omitempty"`
 	StorageType  string `json:"storage_type,omitempty"`
+	StartSample  *int64 `json:"start_sample,omitempty"` // First frame of the file in the recording
+	StartTime    string `json:"start_time,omitempty"`   // Wall-clock time of StartSample
+	GapKind      string `json:"gap_kind,omitempty"`     // missing, short or gap
+	GapStart     string `json:"gap_start,omitempty"`
+	GapEnd       string `json:"gap_end,omitempty"`
 }
 
 // RecorderEventParams provides optional fields for [Logger.LogRecorder].
@@ -112,8 +121,14 @@ type RecorderEventParams struct {
 	S3Key        string
 	Error        string
 	RetryCount   int
+	SHA256       string
 	FilesDeleted int
 	StorageType  string
+	StartSample  int64     // Only logged when StartTime is set
+	StartTime    time.Time // Zero for events other than new files
+	GapKind      string
+	GapStart     time.Time // Zero for events other than gaps
+	GapEnd       time.Time
 }
 
 // Logger records events to a JSON lines file.
@@ -125,8 +140,9 @@ type Logger struct {
 }
 
 // DefaultLogPath returns the platform-specific log file path.
-func DefaultLogPath(port int) string {
-	portStr := strconv.Itoa(port)
+// Additional programmes pass their ID to get a separate log file.
+func DefaultLogPath(port int, programme string) string {
+	portStr := filepath.Join(strconv.Itoa(port), programme)
 	switch runtime.GOOS {
 	case "windows":
 		// %PROGRAMDATA% is typically C:\ProgramData
@@ -218,8 +234,33 @@ func (l *Logger) LogSilenceEnd(durationMs int64, levelL, levelR, threshold float
 	})
 }
 
+// LogRouteSilence records a silence transition on an input channel route other than 1-2.
+func (l *Logger) LogRouteSilence(eventType EventType, channels string, durationMs int64, levelL, levelR, threshold float64) error {
+	return l.Log(&Event{
+		Type: eventType,
+		Details: &SilenceDetails{
+			Channels:     channels,
+			LevelLeftDB:  levelL,
+			LevelRightDB: levelR,
+			ThresholdDB:  threshold,
+			DurationMs:   durationMs,
+		},
+	})
+}
+
 // LogRecorder records a recorder lifecycle or upload event.
 func (l *Logger) LogRecorder(eventType EventType, p *RecorderEventParams) error {
+	var startSample *int64
+	var startTime string
+	if !p.StartTime.IsZero() {
+		startSample = &p.StartSample
+		startTime = p.StartTime.Format(time.RFC3339Nano)
+	}
+	var gapStart, gapEnd string
+	if !p.GapStart.IsZero() {
+		gapStart = p.GapStart.Format(time.RFC3339)
+		gapEnd = p.GapEnd.Format(time.RFC3339)
+	}
 	return l.Log(&Event{
 		Type: eventType,
 		Details: &RecorderDetails{
@@ -230,8 +271,14 @@ func (l *Logger) LogRecorder(eventType EventType, p *RecorderEventParams) error
 			S3Key:        p.S3Key,
 			Error:        p.Error,
 			RetryCount:   p.RetryCount,
+			SHA256:       p.SHA256,
 			FilesDeleted: p.FilesDeleted,
 			StorageType:  p.StorageType,
+			StartSample:  startSample,
+			StartTime:    startTime,
+			GapKind:      p.GapKind,
+			GapStart:     gapStart,
+			GapEnd:       gapEnd,
 		},
 	})
 }
@@ -373,7 +420,7 @@ func IsRecorderEvent(t EventType) bool {
 	switch t {
 	case RecorderStarted, RecorderStopped, RecorderError, RecorderFile,
 		UploadQueued, UploadCompleted, UploadFailed, UploadRetry, UploadAbandoned,
-		CleanupCompleted:
+		CleanupCompleted, RecorderGap:
 		return true
 	default:
 		return false
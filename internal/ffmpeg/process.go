@@ -11,6 +11,7 @@ import (
 	"os"
 	"os/exec"
 	"sync"
+	"time"
 
 	"github.com/oszuidwest/zwfm-encoder/internal/audio"
 	"github.com/oszuidwest/zwfm-encoder/internal/util"
@@ -30,6 +31,10 @@ type StartResult struct {
 	stdin   io.WriteCloser
 	stdinMu sync.Mutex // protects stdin field
 
+	// Command reading FFmpeg's output, for pipelines
+	next       *exec.Cmd
+	nextStderr *bytes.Buffer
+
 	waitOnce sync.Once
 	waitErr  error
 	waitDone chan struct{}
@@ -47,24 +52,51 @@ func BaseInputArgs() []string {
 
 // StartProcess launches an FFmpeg subprocess.
 func StartProcess(ffmpegPath string, args []string) (*StartResult, error) {
+	result, _, err := startProcess("ffmpeg", ffmpegPath, args, false)
+	return result, err
+}
+
+// StartOutputProcess launches an FFmpeg subprocess that writes its output to
+// stdout, and returns the reader for that output.
+func StartOutputProcess(ffmpegPath string, args []string) (*StartResult, io.ReadCloser, error) {
+	return startProcess("ffmpeg", ffmpegPath, args, true)
+}
+
+// StartCommand launches any command that reads its input from stdin, with
+// the same lifecycle handling as an FFmpeg subprocess.
+func StartCommand(command string, args []string) (*StartResult, error) {
+	result, _, err := startProcess(command, command, args, false)
+	return result, err
+}
+
+// startProcess launches path; name identifies it in errors.
+func startProcess(name, path string, args []string, withStdout bool) (*StartResult, io.ReadCloser, error) {
 	ctx, cancel := context.WithCancelCause(context.Background())
-	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+	cmd := exec.CommandContext(ctx, path, args...)
 
 	stdinPipe, err := cmd.StdinPipe()
 	if err != nil {
 		cancel(fmt.Errorf("create stdin pipe: %w", err))
-		return nil, fmt.Errorf("create stdin pipe: %w", err)
+		return nil, nil, fmt.Errorf("create stdin pipe: %w", err)
+	}
+
+	var stdoutPipe io.ReadCloser
+	if withStdout {
+		if stdoutPipe, err = cmd.StdoutPipe(); err != nil {
+			cancel(fmt.Errorf("create stdout pipe: %w", err))
+			return nil, nil, fmt.Errorf("create stdout pipe: %w", err)
+		}
 	}
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
 	if err := cmd.Start(); err != nil {
-		cancel(fmt.Errorf("start ffmpeg: %w", err))
+		cancel(fmt.Errorf("start %s: %w", name, err))
 		if closeErr := stdinPipe.Close(); closeErr != nil {
 			slog.Warn("failed to close stdin pipe", "error", closeErr)
 		}
-		return nil, fmt.Errorf("start ffmpeg: %w", err)
+		return nil, nil, fmt.Errorf("start %s: %w", name, err)
 	}
 
 	return &StartResult{
@@ -74,9 +106,111 @@ func StartProcess(ffmpegPath string, args []string) (*StartResult, error) {
 		stderr:   &stderr,
 		stdin:    stdinPipe,
 		waitDone: make(chan struct{}),
+	}, stdoutPipe, nil
+}
+
+// StartPipeline launches an FFmpeg subprocess whose output is piped into the
+// stdin of a second command. The result covers both: Wait returns when both
+// have exited, and Cancel, Signal and Kill apply to both.
+func StartPipeline(ffmpegPath string, args []string, command string, commandArgs []string) (*StartResult, error) {
+	return startPipeline(ffmpegPath, args, command, commandArgs, nil)
+}
+
+// StartFilteredPipeline is StartPipeline with filter copying FFmpeg's output
+// to the command, so that it can be rewritten on the way. FFmpeg's output ends
+// when filter returns, and the command's input when FFmpeg's output is exhausted.
+func StartFilteredPipeline(ffmpegPath string, args []string, command string, commandArgs []string, filter func(dst io.Writer, src io.Reader) error) (*StartResult, error) {
+	return startPipeline(ffmpegPath, args, command, commandArgs, filter)
+}
+
+// startPipeline launches a pipeline, with filter between FFmpeg and the command if set.
+func startPipeline(ffmpegPath string, args []string, command string, commandArgs []string, filter func(io.Writer, io.Reader) error) (*StartResult, error) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	next := exec.CommandContext(ctx, command, commandArgs...)
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		cancel(fmt.Errorf("create output pipe: %w", err))
+		return nil, fmt.Errorf("create output pipe: %w", err)
+	}
+	// Both ends are inherited by the children; the parent's copies are not needed
+	defer closePipe(pw)
+
+	// Without a filter the command reads FFmpeg's output directly
+	input := pr
+	var filterIn, filterOut *os.File // Kept open for the filter
+	if filter != nil {
+		if input, filterOut, err = os.Pipe(); err != nil {
+			closePipe(pr)
+			cancel(fmt.Errorf("create filter pipe: %w", err))
+			return nil, fmt.Errorf("create filter pipe: %w", err)
+		}
+		filterIn = pr
+	}
+	defer closePipe(input)
+
+	var nextStderr bytes.Buffer
+	next.Stdin = input
+	next.Stderr = &nextStderr
+	if err := next.Start(); err != nil {
+		closeFilter(filterIn, filterOut)
+		cancel(fmt.Errorf("start %s: %w", command, err))
+		return nil, fmt.Errorf("start %s: %w", command, err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+	cmd.Stdout = pw
+	cmd.Stderr = &stderr
+	stdinPipe, err := cmd.StdinPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		closeFilter(filterIn, filterOut)
+		cancel(fmt.Errorf("start ffmpeg: %w", err))
+		_ = next.Wait() //nolint:errcheck // Killed by the cancelled context
+		return nil, fmt.Errorf("start ffmpeg: %w", err)
+	}
+
+	if filter != nil {
+		go func() {
+			// Closing the read end stops FFmpeg if the command went away
+			defer closeFilter(filterIn, filterOut)
+			if err := filter(filterOut, filterIn); err != nil {
+				slog.Warn("pipeline filter stopped", "command", command, "error", err)
+			}
+		}()
+	}
+
+	return &StartResult{
+		cmd:        cmd,
+		ctx:        ctx,
+		cancel:     cancel,
+		stderr:     &stderr,
+		stdin:      stdinPipe,
+		next:       next,
+		nextStderr: &nextStderr,
+		waitDone:   make(chan struct{}),
 	}, nil
 }
 
+// closeFilter closes the pipe ends a pipeline filter reads from and writes to.
+func closeFilter(in, out *os.File) {
+	if in == nil {
+		return
+	}
+	closePipe(in)
+	closePipe(out)
+}
+
+// closePipe closes one end of a pipe, logging failures.
+func closePipe(f *os.File) {
+	if err := f.Close(); err != nil {
+		slog.Warn("failed to close pipe", "error", err)
+	}
+}
+
 // WriteStdin writes data to the process stdin in a thread-safe manner.
 // Returns ErrStdinClosed if stdin has been closed.
 func (r *StartResult) WriteStdin(data []byte) (int, error) {
@@ -113,17 +247,44 @@ func (r *StartResult) CloseStdin() {
 func (r *StartResult) Wait() error {
 	r.waitOnce.Do(func() {
 		r.waitErr = r.cmd.Wait()
+		if r.next != nil {
+			// A failing command explains FFmpeg's broken pipe, so its error wins
+			if err := r.waitNext(); err != nil {
+				r.waitErr = err
+			}
+		}
 		close(r.waitDone)
 	})
 	<-r.waitDone
 	return r.waitErr
 }
 
+// waitNext waits for the command of a pipeline after FFmpeg has exited,
+// killing it if it does not exit on its own once its input has ended.
+func (r *StartResult) waitNext() error {
+	done := make(chan error, 1)
+	go func() {
+		done <- r.next.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		slog.Warn("pipeline command did not exit after end of input, killing", "command", r.next.Path)
+		_ = r.next.Process.Kill() //nolint:errcheck // Process may have exited meanwhile
+		return <-done
+	}
+}
+
 // Signal sends SIGTERM (Unix) or soft termination (Windows) for graceful shutdown.
 func (r *StartResult) Signal() error {
 	if r.cmd.Process == nil {
 		return nil
 	}
+	if r.next != nil {
+		_ = util.GracefulSignal(r.next.Process) //nolint:errcheck // FFmpeg's result is reported
+	}
 	return util.GracefulSignal(r.cmd.Process)
 }
 
@@ -132,6 +293,9 @@ func (r *StartResult) Kill() error {
 	if r.cmd.Process == nil {
 		return nil
 	}
+	if r.next != nil {
+		_ = r.next.Process.Kill() //nolint:errcheck // FFmpeg's result is reported
+	}
 	return r.cmd.Process.Kill()
 }
 
@@ -154,6 +318,10 @@ func (r *StartResult) Stderr() string {
 	if r.stderr == nil {
 		return ""
 	}
+	if r.next != nil {
+		// Command output last, so its errors are found first
+		return r.stderr.String() + r.nextStderr.String()
+	}
 	return r.stderr.String()
 }
 
@@ -0,0 +1,110 @@
+package hls
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// errNoFrame is returned by parseFrame for bytes that do not start a frame.
+var errNoFrame = errors.New("no frame header")
+
+// Bitrates in kbit/s by bitrate index.
+var (
+	mpeg1Layer2Bitrates = [15]int{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384}
+	mpeg1Layer3Bitrates = [15]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+	mpeg2Bitrates       = [15]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160}
+)
+
+var (
+	mpegSampleRates = [3]int{44100, 48000, 32000}
+	adtsSampleRates = [13]int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+)
+
+// frame is one encoded audio frame.
+type frame struct {
+	data       []byte
+	samples    int
+	sampleRate int
+	adts       bool // AAC in ADTS; otherwise MPEG audio
+}
+
+// parseFrame returns the size, sample count and sample rate of the frame
+// whose header starts h, which must be at least 7 bytes.
+func parseFrame(h []byte) (size, samples, sampleRate int, adts bool, err error) {
+	if h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return 0, 0, 0, false, errNoFrame
+	}
+
+	layer := (h[1] >> 1) & 3
+	if layer == 0 {
+		// ADTS: 12-bit sync word and layer 0
+		if h[1]&0xF0 != 0xF0 {
+			return 0, 0, 0, false, errNoFrame
+		}
+		rate := int(h[2]>>2) & 0xF
+		size = int(h[3]&3)<<11 | int(h[4])<<3 | int(h[5]>>5)
+		if rate >= len(adtsSampleRates) || size < 7 {
+			return 0, 0, 0, false, errNoFrame
+		}
+		return size, 1024 * (int(h[6]&3) + 1), adtsSampleRates[rate], true, nil
+	}
+
+	// MPEG audio Layer II or III; Layer I is not produced by the encoders used here
+	version := (h[1] >> 3) & 3
+	bitrateIndex := int(h[2] >> 4)
+	rateIndex := int(h[2]>>2) & 3
+	padding := int(h[2]>>1) & 1
+	if version == 1 || layer == 3 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return 0, 0, 0, false, errNoFrame
+	}
+
+	sampleRate = mpegSampleRates[rateIndex]
+	var bitrate int
+	switch {
+	case version == 3 && layer == 2:
+		bitrate, samples = mpeg1Layer2Bitrates[bitrateIndex], 1152
+	case version == 3:
+		bitrate, samples = mpeg1Layer3Bitrates[bitrateIndex], 1152
+	default:
+		// MPEG-2 runs at half and MPEG-2.5 at quarter rate
+		if version == 2 {
+			sampleRate /= 2
+		} else {
+			sampleRate /= 4
+		}
+		bitrate, samples = mpeg2Bitrates[bitrateIndex], 1152
+		if layer == 1 {
+			samples = 576
+		}
+	}
+	return samples/8*bitrate*1000/sampleRate + padding, samples, sampleRate, false, nil
+}
+
+// readFrame reads the next frame, skipping bytes that do not start one.
+func readFrame(r *bufio.Reader) (frame, error) {
+	for {
+		h, err := r.Peek(7)
+		if err != nil {
+			if len(h) > 0 && errors.Is(err, io.EOF) {
+				return frame{}, io.ErrUnexpectedEOF
+			}
+			return frame{}, err
+		}
+
+		size, samples, sampleRate, adts, err := parseFrame(h)
+		if err != nil {
+			// Resynchronise on the next byte
+			if _, err := r.Discard(1); err != nil {
+				return frame{}, err
+			}
+			continue
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return frame{}, err
+		}
+		return frame{data: data, samples: samples, sampleRate: sampleRate, adts: adts}, nil
+	}
+}
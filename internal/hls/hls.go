@@ -0,0 +1,232 @@
+// Package hls packages encoded audio into an HTTP Live Streaming playlist held in memory.
+package hls
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// PlaylistName is the file name of the media playlist.
+	PlaylistName = "index.m3u8"
+	// PlaylistContentType is the HTTP content type of the media playlist.
+	PlaylistContentType = "application/vnd.apple.mpegurl"
+
+	// DefaultSegmentSeconds is the segment length used when none is configured.
+	DefaultSegmentSeconds = 4
+	// DefaultWindowSegments is the playlist length used when none is configured.
+	DefaultWindowSegments = 6
+
+	// timestampOwner identifies the ID3 PRIV frame that carries the timestamp
+	// of a packed audio segment (RFC 8216, section 3.4).
+	timestampOwner = "com.apple.streaming.transportStreamTimestamp"
+)
+
+// Config configures an HLS output.
+type Config struct {
+	SegmentSeconds int  `json:"segment_seconds,omitempty"` // 0 = DefaultSegmentSeconds
+	WindowSegments int  `json:"window_segments,omitempty"` // 0 = DefaultWindowSegments
+	Public         bool `json:"public,omitempty"`          // Serve without login
+}
+
+// Validate reports an error if the segment length or window is out of range.
+func (c *Config) Validate() error {
+	if c.SegmentSeconds != 0 && (c.SegmentSeconds < 1 || c.SegmentSeconds > 30) {
+		return fmt.Errorf("hls.segment_seconds: must be between 1 and 30")
+	}
+	if c.WindowSegments != 0 && (c.WindowSegments < 3 || c.WindowSegments > 60) {
+		return fmt.Errorf("hls.window_segments: must be between 3 and 60")
+	}
+	return nil
+}
+
+// SegmentSecondsOrDefault returns SegmentSeconds, or [DefaultSegmentSeconds] if not set.
+func (c *Config) SegmentSecondsOrDefault() int {
+	if c.SegmentSeconds <= 0 {
+		return DefaultSegmentSeconds
+	}
+	return c.SegmentSeconds
+}
+
+// WindowSegmentsOrDefault returns WindowSegments, or [DefaultWindowSegments] if not set.
+func (c *Config) WindowSegmentsOrDefault() int {
+	if c.WindowSegments <= 0 {
+		return DefaultWindowSegments
+	}
+	return c.WindowSegments
+}
+
+// segment is one packed audio segment.
+type segment struct {
+	seq              int
+	duration         float64
+	data             []byte
+	adts             bool
+	discontinuity    bool // First segment after an encoder restart
+	discontinuitySeq int  // Discontinuities up to and including this segment
+}
+
+// name returns the file name of the segment.
+func (s *segment) name() string {
+	if s.adts {
+		return strconv.Itoa(s.seq) + ".aac"
+	}
+	return strconv.Itoa(s.seq) + ".mp3"
+}
+
+// Packager cuts a stream of ADTS AAC or MPEG audio frames into packed audio
+// segments and keeps a rolling window of them in memory. One packager lives
+// across encoder restarts; each restart starts a new discontinuity.
+type Packager struct {
+	segmentSeconds float64
+	window         int
+
+	mu               sync.RWMutex
+	segments         []*segment // Window plus as many older segments for slow clients
+	nextSeq          int
+	discontinuitySeq int
+	samples          int64 // Total samples packaged, for segment timestamps
+	restarted        bool
+}
+
+// NewPackager returns a packager with the given configuration.
+func NewPackager(cfg Config) *Packager {
+	return &Packager{
+		segmentSeconds: float64(cfg.SegmentSecondsOrDefault()),
+		window:         cfg.WindowSegmentsOrDefault(),
+	}
+}
+
+// Run packages the encoded audio read from r until it ends. A segment that
+// is incomplete when r ends is discarded.
+func (p *Packager) Run(r io.Reader) error {
+	p.mu.Lock()
+	p.restarted = len(p.segments) > 0
+	p.mu.Unlock()
+
+	br := bufio.NewReader(r)
+	var cur *segment
+	var samples, sampleRate int
+	for {
+		f, err := readFrame(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, os.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		if cur != nil && (f.adts != cur.adts || f.sampleRate != sampleRate) {
+			// Format change; drop the mixed segment
+			cur = nil
+		}
+		if cur == nil {
+			cur = &segment{adts: f.adts, data: p.timestampTag(f.sampleRate)}
+			samples, sampleRate = 0, f.sampleRate
+		}
+
+		cur.data = append(cur.data, f.data...)
+		samples += f.samples
+		if float64(samples) >= p.segmentSeconds*float64(sampleRate) {
+			cur.duration = float64(samples) / float64(sampleRate)
+			p.add(cur, samples)
+			cur = nil
+		}
+	}
+}
+
+// timestampTag returns the ID3 tag with the presentation time of the next segment.
+func (p *Packager) timestampTag(sampleRate int) []byte {
+	p.mu.RLock()
+	pts := uint64(p.samples*90000/int64(sampleRate)) & (1<<33 - 1) //nolint:gosec // Sample count is never negative
+	p.mu.RUnlock()
+
+	frameSize := len(timestampOwner) + 1 + 8
+	tag := make([]byte, 0, 20+frameSize)
+	tag = append(tag, "ID3\x04\x00\x00"...)
+	tag = binary.BigEndian.AppendUint32(tag, uint32(10+frameSize)) //nolint:gosec // Fits in a syncsafe integer
+	tag = append(tag, "PRIV"...)
+	tag = binary.BigEndian.AppendUint32(tag, uint32(frameSize)) //nolint:gosec // Fits in a syncsafe integer
+	tag = append(tag, 0, 0)
+	tag = append(tag, timestampOwner...)
+	tag = append(tag, 0)
+	return binary.BigEndian.AppendUint64(tag, pts)
+}
+
+// add appends a completed segment and trims the window.
+func (p *Packager) add(s *segment, samples int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s.seq = p.nextSeq
+	s.discontinuity = p.restarted
+	if s.discontinuity {
+		p.discontinuitySeq++
+	}
+	s.discontinuitySeq = p.discontinuitySeq
+	p.nextSeq++
+	p.samples += int64(samples)
+	p.restarted = false
+
+	p.segments = append(p.segments, s)
+	for len(p.segments) > 2*p.window {
+		p.segments = p.segments[1:]
+	}
+}
+
+// Playlist returns the media playlist, or false before the first segment is complete.
+func (p *Packager) Playlist() ([]byte, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if len(p.segments) == 0 {
+		return nil, false
+	}
+
+	listed := p.segments[max(0, len(p.segments)-p.window):]
+	target := p.segmentSeconds
+	for _, s := range listed {
+		target = max(target, math.Round(s.duration))
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(target))
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", listed[0].seq)
+	if listed[0].discontinuitySeq > 0 {
+		// Discontinuities that left the playlist still count
+		fmt.Fprintf(&b, "#EXT-X-DISCONTINUITY-SEQUENCE:%d\n", listed[0].discontinuitySeq)
+	}
+	for i, s := range listed {
+		if s.discontinuity && i > 0 {
+			b.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s\n", s.duration, s.name())
+	}
+	return []byte(b.String()), true
+}
+
+// Segment returns the segment with the given file name and its content type.
+func (p *Packager) Segment(name string) (data []byte, contentType string, ok bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, s := range p.segments {
+		if s.name() != name {
+			continue
+		}
+		if s.adts {
+			return s.data, "audio/aac", true
+		}
+		return s.data, "audio/mpeg", true
+	}
+	return nil, "", false
+}
@@ -0,0 +1,375 @@
+// Package listen serves live low-bitrate encodes of PCM audio for monitoring.
+package listen
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/ffmpeg"
+	"github.com/oszuidwest/zwfm-encoder/internal/ogg"
+)
+
+const (
+	// ProgrammeSource identifies the programme audio, as opposed to a single stream.
+	ProgrammeSource = ""
+
+	// bitrate is the encoding bitrate of monitoring audio.
+	bitrate = "64k"
+	// audioBufferSize is the number of PCM chunks (~100ms each) queued for an encoder.
+	audioBufferSize = 20
+	// listenerBufferSize is the number of encoded chunks queued for a listener
+	// before it is considered too slow and disconnected.
+	listenerBufferSize = 64
+	// readSize is the chunk size for MP3 output.
+	readSize = 4096
+)
+
+// ErrUnknownFormat is returned for an unsupported listen format.
+var ErrUnknownFormat = errors.New("format: must be mp3 or opus")
+
+// Format is the encoding of monitoring audio.
+type Format string
+
+const (
+	// FormatMP3 is MP3.
+	FormatMP3 Format = "mp3"
+	// FormatOpus is Opus in an Ogg container.
+	FormatOpus Format = "opus"
+)
+
+// ParseFormat returns the format named by s. An empty string selects MP3.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", FormatMP3:
+		return FormatMP3, nil
+	case FormatOpus:
+		return FormatOpus, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// ContentType returns the HTTP content type of the format.
+func (f Format) ContentType() string {
+	if f == FormatOpus {
+		return "audio/ogg; codecs=opus"
+	}
+	return "audio/mpeg"
+}
+
+// args returns the FFmpeg arguments that encode PCM from stdin to stdout.
+func (f Format) args() []string {
+	args := ffmpeg.BaseInputArgs()
+	args = append(args, "-hide_banner", "-loglevel", "warning")
+	if f == FormatOpus {
+		args = append(args, "-codec:a", "libopus", "-b:a", bitrate, "-page_duration", "200000", "-f", "ogg")
+	} else {
+		args = append(args, "-codec:a", "libmp3lame", "-b:a", bitrate, "-f", "mp3")
+	}
+	return append(args, "-flush_packets", "1", "pipe:1")
+}
+
+type sourceKey struct {
+	source string
+	format Format
+}
+
+// Hub runs one encoder per source and format, shared by all its listeners.
+// Encoders start with the first listener and stop when the last one leaves.
+type Hub struct {
+	ffmpegPath string
+
+	mu      sync.Mutex
+	sources map[sourceKey]*source
+	artist  string       // Artist of the title on air
+	title   string       // Title on air
+	active  atomic.Int32 // Number of running encoders
+}
+
+// NewHub returns a Hub that encodes with the given FFmpeg binary.
+func NewHub(ffmpegPath string) *Hub {
+	return &Hub{
+		ffmpegPath: ffmpegPath,
+		sources:    make(map[sourceKey]*source),
+	}
+}
+
+// WriteAudio feeds PCM of a source to its encoders. It never blocks; when an
+// encoder falls behind, its oldest queued audio is dropped.
+func (h *Hub) WriteAudio(source string, pcm []byte) {
+	if h.active.Load() == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var buf []byte
+	for key, src := range h.sources {
+		if key.source != source {
+			continue
+		}
+		if buf == nil {
+			// Copy data — the caller reuses the buffer
+			buf = make([]byte, len(pcm))
+			copy(buf, pcm)
+		}
+		select {
+		case src.audioCh <- buf:
+		default:
+			select {
+			case <-src.audioCh:
+			default:
+			}
+			select {
+			case src.audioCh <- buf:
+			default:
+			}
+		}
+	}
+}
+
+// Subscribe adds a listener to the encoder for source and format, starting it if needed.
+func (h *Hub) Subscribe(source string, format Format) (*Listener, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := sourceKey{source: source, format: format}
+	src := h.sources[key]
+	if src == nil {
+		var err error
+		if src, err = h.start(key); err != nil {
+			return nil, err
+		}
+	}
+
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	l := &Listener{
+		hub: h,
+		src: src,
+		ch:  make(chan []byte, listenerBufferSize),
+	}
+	if src.chain != nil {
+		l.header = src.chain.Header()
+	}
+	src.listeners[l] = struct{}{}
+	return l, nil
+}
+
+// SetTitle sets the title on air, which Opus listeners receive in-band.
+func (h *Hub) SetTitle(artist, title string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.artist, h.title = artist, title
+	for _, src := range h.sources {
+		if src.chain != nil {
+			src.chain.SetTitle(artist, title)
+		}
+	}
+}
+
+// Close stops all encoders. Their listeners see the end of the audio.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, src := range h.sources {
+		h.removeLocked(src)
+	}
+}
+
+// start launches an encoder. Must be called with h.mu held.
+func (h *Hub) start(key sourceKey) (*source, error) {
+	result, stdout, err := ffmpeg.StartOutputProcess(h.ffmpegPath, key.format.args())
+	if err != nil {
+		return nil, err
+	}
+
+	src := &source{
+		key:       key,
+		result:    result,
+		audioCh:   make(chan []byte, audioBufferSize),
+		listeners: make(map[*Listener]struct{}),
+	}
+	if key.format == FormatOpus {
+		src.chain = ogg.NewChainer(h.artist, h.title)
+	}
+	h.sources[key] = src
+	h.active.Add(1)
+
+	go src.runWriter()
+	go h.runReader(src, stdout)
+
+	slog.Info("listen encoder started", "source", key.source, "format", key.format)
+	return src, nil
+}
+
+// remove unregisters an encoder and closes its input, which makes FFmpeg exit.
+func (h *Hub) remove(src *source) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(src)
+}
+
+// removeLocked is remove with h.mu held.
+func (h *Hub) removeLocked(src *source) {
+	if h.sources[src.key] != src {
+		return
+	}
+	delete(h.sources, src.key)
+	h.active.Add(-1)
+	close(src.audioCh)
+}
+
+// runReader fans encoded output out to listeners until FFmpeg exits.
+func (h *Hub) runReader(src *source, stdout io.Reader) {
+	r := bufio.NewReaderSize(stdout, readSize)
+	for {
+		var chunk []byte
+		var err error
+		if src.key.format == FormatOpus {
+			chunk, err = ogg.ReadPage(r)
+		} else {
+			chunk = make([]byte, readSize)
+			var n int
+			n, err = r.Read(chunk)
+			chunk = chunk[:n]
+		}
+		if len(chunk) > 0 {
+			src.broadcast(chunk)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if src.chain != nil {
+		src.broadcastRaw(src.chain.Flush())
+	}
+
+	h.remove(src)
+	src.closeListeners()
+
+	if err := src.result.Wait(); err != nil {
+		slog.Warn("listen encoder exited", "source", src.key.source, "format", src.key.format,
+			"error", err, "stderr", src.result.Stderr())
+		return
+	}
+	slog.Info("listen encoder stopped", "source", src.key.source, "format", src.key.format)
+}
+
+// source is a running encoder and its listeners.
+type source struct {
+	key     sourceKey
+	result  *ffmpeg.StartResult
+	audioCh chan []byte
+
+	chain *ogg.Chainer // Carries titles in Opus output; nil for MP3
+
+	mu        sync.Mutex // Protects listeners, and orders pages with new listeners
+	listeners map[*Listener]struct{}
+}
+
+// runWriter drains audioCh into FFmpeg stdin and closes stdin when the encoder is removed.
+func (s *source) runWriter() {
+	defer s.result.CloseStdin()
+
+	for data := range s.audioCh {
+		// Write errors mean FFmpeg exited; the reader cleans up
+		_, _ = s.result.WriteStdin(data) //nolint:errcheck // Exit is handled by runReader
+	}
+}
+
+// broadcast passes a chunk of encoder output to all listeners. Ogg pages go
+// through the title chainer first.
+func (s *source) broadcast(chunk []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.chain != nil {
+		// Under s.mu, so a new listener's header matches the pages that follow it
+		if chunk = s.chain.Process(chunk); len(chunk) == 0 {
+			return
+		}
+	}
+	s.sendLocked(chunk)
+}
+
+// broadcastRaw sends a chunk to all listeners as it is.
+func (s *source) broadcastRaw(chunk []byte) {
+	if len(chunk) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sendLocked(chunk)
+}
+
+// sendLocked sends a chunk to all listeners and disconnects those that fall behind.
+// Must be called with s.mu held.
+func (s *source) sendLocked(chunk []byte) {
+	for l := range s.listeners {
+		select {
+		case l.ch <- chunk:
+		default:
+			slog.Warn("listener too slow, disconnecting", "source", s.key.source, "format", s.key.format)
+			delete(s.listeners, l)
+			close(l.ch)
+		}
+	}
+}
+
+// closeListeners ends the audio of all listeners.
+func (s *source) closeListeners() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for l := range s.listeners {
+		close(l.ch)
+	}
+	clear(s.listeners)
+}
+
+// Listener receives the encoded audio of one source.
+type Listener struct {
+	hub    *Hub
+	src    *source
+	header []byte
+	ch     chan []byte
+	once   sync.Once
+}
+
+// Header returns the data to send before the audio, if any.
+func (l *Listener) Header() []byte {
+	return l.header
+}
+
+// Audio returns the channel of encoded audio. It is closed when the encoder
+// stops or the listener cannot keep up.
+func (l *Listener) Audio() <-chan []byte {
+	return l.ch
+}
+
+// Close unsubscribes the listener. The encoder stops when no listeners remain.
+func (l *Listener) Close() {
+	l.once.Do(func() {
+		// Hold the hub lock so no listener subscribes between the check and the removal
+		l.hub.mu.Lock()
+		defer l.hub.mu.Unlock()
+
+		l.src.mu.Lock()
+		delete(l.src.listeners, l)
+		idle := len(l.src.listeners) == 0
+		l.src.mu.Unlock()
+
+		if idle {
+			l.hub.removeLocked(l.src)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package metadata
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxMessageSize is the longest message read from a connection or datagram.
+	maxMessageSize = 4096
+	// idleTimeout disconnects TCP clients that send nothing for this long.
+	idleTimeout = 24 * time.Hour
+)
+
+// Config configures the network listeners for playout systems.
+type Config struct {
+	TCP string `json:"tcp,omitempty"` // TCP listen address, e.g. ":9010"; one message per line
+	UDP string `json:"udp,omitempty"` // UDP listen address; one message per datagram
+}
+
+// Handler receives each valid message.
+type Handler func(NowPlaying)
+
+// Listener accepts now-playing messages over TCP and UDP.
+type Listener struct {
+	handle Handler
+
+	tcp net.Listener
+	udp net.PacketConn
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// Listen starts the listeners set in cfg. It returns nil if none is set.
+func Listen(cfg Config, handle Handler) (*Listener, error) {
+	if cfg.TCP == "" && cfg.UDP == "" {
+		return nil, nil
+	}
+
+	l := &Listener{handle: handle, conns: make(map[net.Conn]struct{})}
+	if cfg.TCP != "" {
+		tcp, err := net.Listen("tcp", cfg.TCP)
+		if err != nil {
+			return nil, fmt.Errorf("listen for metadata on tcp %s: %w", cfg.TCP, err)
+		}
+		l.tcp = tcp
+		go l.acceptTCP()
+	}
+	if cfg.UDP != "" {
+		udp, err := net.ListenPacket("udp", cfg.UDP)
+		if err != nil {
+			l.Close()
+			return nil, fmt.Errorf("listen for metadata on udp %s: %w", cfg.UDP, err)
+		}
+		l.udp = udp
+		go l.readUDP()
+	}
+	return l, nil
+}
+
+// acceptTCP serves TCP clients until the listener is closed.
+func (l *Listener) acceptTCP() {
+	for {
+		conn, err := l.tcp.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Warn("metadata listener stopped accepting", "error", err)
+			}
+			return
+		}
+		l.mu.Lock()
+		l.conns[conn] = struct{}{}
+		l.mu.Unlock()
+		go l.serveTCP(conn)
+	}
+}
+
+// serveTCP handles one message per line until the client disconnects.
+func (l *Listener) serveTCP(conn net.Conn) {
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		_ = conn.Close() //nolint:errcheck // Connection is done either way
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 512), maxMessageSize)
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(idleTimeout)); err != nil {
+			return
+		}
+		if !scanner.Scan() {
+			return
+		}
+		l.dispatch(scanner.Text(), conn.RemoteAddr())
+	}
+}
+
+// readUDP handles one message per datagram until the socket is closed.
+func (l *Listener) readUDP() {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := l.udp.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Warn("metadata listener stopped reading", "error", err)
+			}
+			return
+		}
+		l.dispatch(string(buf[:n]), addr)
+	}
+}
+
+// dispatch parses a message and passes it on, ignoring blank lines.
+func (l *Listener) dispatch(msg string, from net.Addr) {
+	if strings.TrimSpace(msg) == "" {
+		return
+	}
+	np, err := Parse(msg)
+	if err != nil {
+		slog.Warn("ignoring invalid metadata", "from", from.String(), "error", err)
+		return
+	}
+	l.handle(np)
+}
+
+// Close stops the listeners and disconnects TCP clients.
+func (l *Listener) Close() {
+	if l.tcp != nil {
+		_ = l.tcp.Close() //nolint:errcheck // Shutting down
+	}
+	if l.udp != nil {
+		_ = l.udp.Close() //nolint:errcheck // Shutting down
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for conn := range l.conns {
+		_ = conn.Close() //nolint:errcheck // Shutting down
+	}
+}
@@ -0,0 +1,131 @@
+// Package metadata ingests now-playing information from playout systems.
+package metadata
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLength is the longest artist or title kept, in bytes.
+const maxLength = 256
+
+// ErrEmpty is returned when a message carries neither artist nor title.
+var ErrEmpty = errors.New("metadata: artist or title is required")
+
+// NowPlaying is the item currently on air.
+type NowPlaying struct {
+	Artist    string    `json:"artist,omitempty"`
+	Title     string    `json:"title"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Text returns the item as "artist - title", or the title alone without artist.
+func (n *NowPlaying) Text() string {
+	if n.Artist == "" {
+		return n.Title
+	}
+	return n.Artist + " - " + n.Title
+}
+
+// IsZero reports whether nothing has been received.
+func (n *NowPlaying) IsZero() bool {
+	return n.Artist == "" && n.Title == ""
+}
+
+// New returns a NowPlaying for artist and title, cleaned of control characters
+// and surrounding space, and stamped with the current time.
+func New(artist, title string) (NowPlaying, error) {
+	np := NowPlaying{
+		Artist:    clean(artist),
+		Title:     clean(title),
+		UpdatedAt: time.Now(),
+	}
+	if np.IsZero() {
+		return NowPlaying{}, ErrEmpty
+	}
+	if np.Title == "" {
+		// An artist alone is shown as the title
+		np.Artist, np.Title = "", np.Artist
+	}
+	return np, nil
+}
+
+// Parse reads one message in any of the supported formats:
+//
+//   - JSON: {"artist": "...", "title": "..."}, or {"text": "..."}
+//   - URL-encoded: artist=...&title=..., or song=... as sent to Icecast
+//   - Plain text: "artist - title", or a title alone.
+func Parse(msg string) (NowPlaying, error) {
+	msg = strings.TrimSpace(strings.TrimPrefix(msg, "\ufeff"))
+
+	if strings.HasPrefix(msg, "{") {
+		var v struct {
+			Artist string `json:"artist"`
+			Title  string `json:"title"`
+			Text   string `json:"text"`
+		}
+		if err := json.Unmarshal([]byte(msg), &v); err != nil {
+			return NowPlaying{}, err
+		}
+		if v.Artist == "" && v.Title == "" {
+			return splitText(v.Text)
+		}
+		return New(v.Artist, v.Title)
+	}
+
+	if values, ok := parseQuery(msg); ok {
+		if song := values.Get("song"); song != "" {
+			return splitText(song)
+		}
+		return New(values.Get("artist"), values.Get("title"))
+	}
+
+	return splitText(msg)
+}
+
+// parseQuery returns msg as URL-encoded values if it has an artist, title or song key.
+func parseQuery(msg string) (url.Values, bool) {
+	if !strings.Contains(msg, "=") {
+		return nil, false
+	}
+	values, err := url.ParseQuery(msg)
+	if err != nil {
+		return nil, false
+	}
+	if !values.Has("artist") && !values.Has("title") && !values.Has("song") {
+		return nil, false
+	}
+	return values, true
+}
+
+// splitText splits "artist - title" at the first separator.
+func splitText(text string) (NowPlaying, error) {
+	if artist, title, ok := strings.Cut(text, " - "); ok {
+		return New(artist, title)
+	}
+	return New("", text)
+}
+
+// clean removes control characters, collapses surrounding space and limits the length.
+func clean(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7F {
+			return ' '
+		}
+		return r
+	}, s)
+	s = strings.TrimSpace(s)
+	if len(s) <= maxLength {
+		return s
+	}
+	// Cut at a rune boundary
+	s = s[:maxLength]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -1,4 +1,4 @@
-// Package notify handles silence detection notifications.
+// Package notify handles silence detection and recording gap notifications.
 package notify
 
 import (
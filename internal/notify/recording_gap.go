@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/config"
+	"github.com/oszuidwest/zwfm-encoder/internal/util"
+)
+
+// RecordingGap is a stretch of time missing from the archive of a recorder.
+type RecordingGap struct {
+	Recorder string
+	Kind     string // missing, short or gap
+	Start    time.Time
+	End      time.Time
+	File     string // The short file, if any
+}
+
+// describe returns a one-line description of the gap.
+func (g *RecordingGap) describe() string {
+	text := fmt.Sprintf("%s: %s recording from %s to %s (%s)",
+		g.Recorder, g.Kind, g.Start.Local().Format(time.DateTime), g.End.Local().Format(time.DateTime), g.End.Sub(g.Start).Round(time.Second))
+	if g.File != "" {
+		text += " in " + g.File
+	}
+	return text
+}
+
+// NotifyRecordingGap sends a recording gap alert to the configured webhook,
+// email and Zabbix.
+//
+//nolint:gocritic // hugeParam: copy is acceptable for infrequent notification events
+func NotifyRecordingGap(cfg config.Snapshot, gap *RecordingGap) {
+	if cfg.HasWebhook() {
+		logNotifyResult(func() error { return sendRecordingGapWebhook(cfg.WebhookURL, gap) }, "Recording gap webhook")
+	}
+	if cfg.HasGraph() {
+		logNotifyResult(func() error { return sendRecordingGapEmail(BuildGraphConfig(cfg), cfg.StationName, gap) }, "Recording gap email")
+	}
+	if cfg.HasZabbix() {
+		logNotifyResult(func() error {
+			return sendZabbixEvent(cfg.ZabbixServer, cfg.ZabbixPort, cfg.ZabbixHost, cfg.ZabbixKey,
+				fmt.Sprintf("event=RECORDING_GAP recorder=%q kind=%s start=%s end=%s",
+					gap.Recorder, gap.Kind, gap.Start.UTC().Format(time.RFC3339), gap.End.UTC().Format(time.RFC3339)))
+		}, "Recording gap zabbix")
+	}
+}
+
+func sendRecordingGapWebhook(webhookURL string, gap *RecordingGap) error {
+	return sendWebhook(webhookURL, &WebhookPayload{
+		Event:     "recording_gap",
+		Message:   gap.describe(),
+		Timestamp: timestampUTC(),
+		Recorder:  gap.Recorder,
+		GapKind:   gap.Kind,
+		GapStart:  gap.Start.UTC().Format(time.RFC3339),
+		GapEnd:    gap.End.UTC().Format(time.RFC3339),
+		File:      gap.File,
+	})
+}
+
+func sendRecordingGapEmail(cfg *GraphConfig, stationName string, gap *RecordingGap) error {
+	subject := "[ALERT] Recording Gap - " + stationName
+	body := fmt.Sprintf(
+		"The encoder found a gap in the recordings at %s.\n\n"+
+			"%s\n\n"+
+			"This part of the programme is not in the archive. Please check the recorder.",
+		util.HumanTime(), gap.describe(),
+	)
+	return sendGraphMail(cfg, subject, body)
+}
@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"fmt"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/audio"
+	"github.com/oszuidwest/zwfm-encoder/internal/config"
+	"github.com/oszuidwest/zwfm-encoder/internal/util"
+)
+
+// HandleRouteEvent sends silence start and recovery alerts for a routed pair
+// other than channels 1-2, such as the input of a second programme on
+// channels 3-4. Each route is tracked by its own detector, so its alerts do
+// not affect those of the main pair. Route alerts have no audio dump.
+func (n *SilenceNotifier) HandleRouteEvent(route string, event audio.SilenceEvent) {
+	switch {
+	case event.JustEntered:
+		go notifyRouteSilence(n.cfg.Snapshot(), route, event.CurrentLevelL, event.CurrentLevelR)
+	case event.JustRecovered:
+		go notifyRouteRecovery(n.cfg.Snapshot(), route, event.TotalDurationMs, event.CurrentLevelL, event.CurrentLevelR)
+	}
+}
+
+//nolint:gocritic // hugeParam: copy is acceptable for infrequent notification events
+func notifyRouteSilence(cfg config.Snapshot, route string, levelL, levelR float64) {
+	if cfg.HasWebhook() {
+		logNotifyResult(func() error {
+			return sendWebhook(cfg.WebhookURL, &WebhookPayload{
+				Event:        "silence_detected",
+				Channels:     route,
+				LevelLeftDB:  levelL,
+				LevelRightDB: levelR,
+				Threshold:    cfg.SilenceThreshold,
+				Timestamp:    timestampUTC(),
+			})
+		}, "Route silence webhook")
+	}
+	if cfg.HasGraph() {
+		logNotifyResult(func() error {
+			subject := fmt.Sprintf("[ALERT] Silence Detected on Channels %s - %s", route, cfg.StationName)
+			body := fmt.Sprintf(
+				"The encoder detected silence on input channels %s at %s.\n\n"+
+					"Audio level dropped below the %.0f dB threshold.\n"+
+					"Current level: Left %.1f dB / Right %.1f dB\n\n"+
+					"Silence is ongoing. Please check the audio source.",
+				route, util.HumanTime(), cfg.SilenceThreshold, levelL, levelR,
+			)
+			return sendGraphMail(BuildGraphConfig(cfg), subject, body)
+		}, "Route silence email")
+	}
+	if cfg.HasZabbix() {
+		logNotifyResult(func() error {
+			return sendZabbixEvent(cfg.ZabbixServer, cfg.ZabbixPort, cfg.ZabbixHost, cfg.ZabbixKey,
+				fmt.Sprintf("event=ROUTE_QUIET channels=%s level_l=%.1f level_r=%.1f threshold=%.1f", route, levelL, levelR, cfg.SilenceThreshold))
+		}, "Route silence zabbix")
+	}
+}
+
+//nolint:gocritic // hugeParam: copy is acceptable for infrequent notification events
+func notifyRouteRecovery(cfg config.Snapshot, route string, durationMs int64, levelL, levelR float64) {
+	if cfg.HasWebhook() {
+		logNotifyResult(func() error {
+			return sendWebhook(cfg.WebhookURL, &WebhookPayload{
+				Event:             "silence_recovered",
+				Channels:          route,
+				SilenceDurationMs: durationMs,
+				LevelLeftDB:       levelL,
+				LevelRightDB:      levelR,
+				Threshold:         cfg.SilenceThreshold,
+				Timestamp:         timestampUTC(),
+			})
+		}, "Route recovery webhook")
+	}
+	if cfg.HasGraph() {
+		logNotifyResult(func() error {
+			subject := fmt.Sprintf("[OK] Audio Restored on Channels %s - %s", route, cfg.StationName)
+			body := fmt.Sprintf(
+				"Audio on input channels %s was restored at %s.\n\n"+
+					"The silence lasted %s.\n"+
+					"Level: Left %.1f dB / Right %.1f dB (threshold: %.1f dB)",
+				route, util.HumanTime(), util.FormatDuration(durationMs), levelL, levelR, cfg.SilenceThreshold,
+			)
+			return sendGraphMail(BuildGraphConfig(cfg), subject, body)
+		}, "Route recovery email")
+	}
+	if cfg.HasZabbix() {
+		logNotifyResult(func() error {
+			return sendZabbixEvent(cfg.ZabbixServer, cfg.ZabbixPort, cfg.ZabbixHost, cfg.ZabbixKey,
+				fmt.Sprintf("event=ROUTE_RESTORED channels=%s duration_ms=%d level_l=%.1f level_r=%.1f threshold=%.1f", route, durationMs, levelL, levelR, cfg.SilenceThreshold))
+		}, "Route recovery zabbix")
+	}
+}
+
+// sendGraphMail sends an email to the configured recipients.
+func sendGraphMail(cfg *GraphConfig, subject, body string) error {
+	client, err := NewGraphClient(cfg)
+	if err != nil {
+		return util.WrapError("create Graph client", err)
+	}
+	recipients := ParseRecipients(cfg.Recipients)
+	if len(recipients) == 0 {
+		return fmt.Errorf("no valid recipients")
+	}
+	if err := client.SendMail(recipients, subject, body); err != nil {
+		return util.WrapError("send email via Graph", err)
+	}
+	return nil
+}
@@ -18,7 +18,14 @@ type WebhookPayload struct {
 	LevelRightDB      float64 `json:"level_right_db,omitempty"` // dB
 	Threshold         float64 `json:"threshold,omitempty"`      // dB
 	Message           string  `json:"message,omitempty"`
-	Timestamp         string  `json:"timestamp"` // RFC3339
+	Timestamp         string  `json:"timestamp"`          // RFC3339
+	Channels          string  `json:"channels,omitempty"` // Input route, such as 3-4; omitted for channels 1-2
+
+	Recorder string `json:"recorder,omitempty"`
+	GapKind  string `json:"gap_kind,omitempty"`  // missing, short or gap
+	GapStart string `json:"gap_start,omitempty"` // RFC3339
+	GapEnd   string `json:"gap_end,omitempty"`   // RFC3339
+	File     string `json:"file,omitempty"`
 
 	AudioDumpBase64    string `json:"audio_dump_base64,omitempty"`
 	AudioDumpFilename  string `json:"audio_dump_filename,omitempty"`
@@ -0,0 +1,206 @@
+package ogg
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"sync"
+)
+
+// Chainer carries titles in an Ogg Opus or Vorbis stream. Ogg holds comments
+// only in the headers of a logical stream, so on each title change it ends
+// the current logical stream and starts a new chained one, with the same
+// codec setup and the new title in its comment header. Players of chained
+// Ogg, Icecast among them, pick up the new title at the boundary.
+//
+// The last audio page is held back until the next arrives, so that it can be
+// marked as the end of its logical stream when the title changes.
+// Streams of other codecs pass through unchanged.
+type Chainer struct {
+	mu sync.Mutex
+
+	artist  string
+	title   string
+	changed bool // Title changed since the current logical stream started
+
+	codec       codec
+	passthrough bool
+	headers     [][]byte // Header packets of the source stream
+	partial     []byte   // Header packet continuing on the next page
+
+	header []byte // Header pages of the current logical stream
+	serial uint32
+	seq    uint32
+	base   int64 // Source granule position at which the current logical stream starts
+
+	held        []byte // Last audio page, not yet passed on
+	heldGranule int64  // Source granule position of held
+}
+
+// NewChainer returns a Chainer that starts with the given title.
+func NewChainer(artist, title string) *Chainer {
+	return &Chainer{
+		artist: artist,
+		title:  title,
+		serial: rand.Uint32(), //nolint:gosec // Serial numbers need not be unpredictable
+	}
+}
+
+// SetTitle sets the title. It takes effect at the next page boundary.
+func (c *Chainer) SetTitle(artist, title string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if artist == c.artist && title == c.title {
+		return
+	}
+	c.artist, c.title = artist, title
+	c.changed = true
+}
+
+// Header returns the header pages of the current logical stream, which a
+// reader joining the stream needs before any audio page. It is empty until
+// the headers of the source stream have been read.
+func (c *Chainer) Header() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.header
+}
+
+// Process takes the next page of the source stream and returns the pages to
+// pass on, which may be none.
+func (c *Chainer) Process(page []byte) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.passthrough {
+		return page
+	}
+	if c.header == nil {
+		return c.readHeader(page)
+	}
+
+	var out []byte
+	if c.held != nil {
+		if c.changed && page[5]&flagContinued == 0 && c.heldGranule != noGranule {
+			c.held[5] |= flagEOS
+			out = append(out, c.finish(c.held)...)
+
+			// The new logical stream continues the timeline of the old one
+			c.base = c.heldGranule
+			if c.codec == codecOpus {
+				c.base -= preSkip(c.headers[0])
+			}
+			c.serial++
+			c.startLocked()
+			out = append(out, c.header...)
+		} else {
+			out = c.finish(c.held)
+		}
+	}
+
+	c.held = page
+	c.heldGranule = Granule(page)
+	return out
+}
+
+// Flush returns the page held back, at the end of the source stream.
+func (c *Chainer) Flush() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.held == nil {
+		return nil
+	}
+	out := c.finish(c.held)
+	c.held = nil
+	return out
+}
+
+// Copy passes the Ogg stream read from src to dst until src ends.
+func (c *Chainer) Copy(dst io.Writer, src io.Reader) error {
+	r := bufio.NewReader(src)
+	for {
+		page, err := ReadPage(r)
+		if err != nil {
+			if _, werr := dst.Write(c.Flush()); werr != nil {
+				return werr
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if _, err := dst.Write(c.Process(page)); err != nil {
+			return err
+		}
+	}
+}
+
+// readHeader collects the header packets of the source stream and returns
+// the header pages of the first logical stream once all have been read.
+// Must be called with c.mu held.
+func (c *Chainer) readHeader(page []byte) []byte {
+	pkts, complete := packets(page)
+	if len(pkts) > 0 && page[5]&flagContinued != 0 {
+		pkts[0] = append(c.partial, pkts[0]...)
+	}
+	c.partial = nil
+	if !complete {
+		c.partial = pkts[len(pkts)-1]
+		pkts = pkts[:len(pkts)-1]
+	}
+	c.headers = append(c.headers, pkts...)
+
+	if c.codec == codecUnknown && len(c.headers) > 0 {
+		if c.codec = detectCodec(c.headers[0]); c.codec == codecUnknown {
+			c.passthrough = true
+			return page
+		}
+	}
+	if len(c.headers) < c.codec.headerCount() {
+		return nil
+	}
+
+	c.headers = c.headers[:c.codec.headerCount()]
+	c.startLocked()
+	return c.header
+}
+
+// startLocked starts a logical stream by building its header pages.
+// Must be called with c.mu held.
+func (c *Chainer) startLocked() {
+	c.seq = 0
+	c.changed = false
+
+	var header []byte
+	for i, pkt := range c.headers {
+		var flags byte
+		if i == 0 {
+			flags = flagBOS
+		}
+		if i == commentIndex {
+			pkt = c.codec.retag(pkt, c.artist, c.title)
+		}
+		var pages []byte
+		pages, c.seq = buildPages(pkt, flags, c.serial, c.seq)
+		header = append(header, pages...)
+	}
+	c.header = header
+}
+
+// finish moves an audio page into the current logical stream and returns it.
+// Must be called with c.mu held.
+func (c *Chainer) finish(page []byte) []byte {
+	page[5] &^= flagBOS
+	if granule := Granule(page); granule != noGranule {
+		setGranule(page, granule-c.base)
+	}
+	binary.LittleEndian.PutUint32(page[14:18], c.serial)
+	binary.LittleEndian.PutUint32(page[18:22], c.seq)
+	c.seq++
+	setChecksum(page)
+	return page
+}
@@ -0,0 +1,123 @@
+package ogg
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// Header packet signatures.
+const (
+	opusHead   = "OpusHead"
+	opusTags   = "OpusTags"
+	vorbisID   = "\x01vorbis"
+	vorbisTags = "\x03vorbis"
+)
+
+// codec is the codec of a logical stream, as far as titles are concerned.
+type codec int
+
+const (
+	codecUnknown codec = iota
+	codecOpus
+	codecVorbis
+)
+
+// detectCodec identifies the codec from the first header packet.
+func detectCodec(pkt []byte) codec {
+	switch {
+	case strings.HasPrefix(string(pkt), opusHead):
+		return codecOpus
+	case strings.HasPrefix(string(pkt), vorbisID):
+		return codecVorbis
+	default:
+		return codecUnknown
+	}
+}
+
+// headerCount returns the number of header packets of the codec.
+func (c codec) headerCount() int {
+	if c == codecVorbis {
+		return 3 // Identification, comment and setup
+	}
+	return 2 // Identification and comment
+}
+
+// commentIndex is the position of the comment header among the header packets.
+const commentIndex = 1
+
+// retag returns a copy of the comment header pkt in which the ARTIST and
+// TITLE comments are replaced by artist and title, if set. Other comments
+// and the vendor string are kept. A malformed header is returned unchanged.
+func (c codec) retag(pkt []byte, artist, title string) []byte {
+	magic := opusTags
+	if c == codecVorbis {
+		magic = vorbisTags
+	}
+	if !strings.HasPrefix(string(pkt), magic) {
+		return pkt
+	}
+
+	rest := pkt[len(magic):]
+	vendor, rest, ok := readField(rest)
+	if !ok || len(rest) < 4 {
+		return pkt
+	}
+	count := binary.LittleEndian.Uint32(rest)
+	rest = rest[4:]
+
+	var comments []string
+	for range count {
+		var comment []byte
+		if comment, rest, ok = readField(rest); !ok {
+			return pkt
+		}
+		key, _, _ := strings.Cut(string(comment), "=")
+		if strings.EqualFold(key, "ARTIST") || strings.EqualFold(key, "TITLE") {
+			continue
+		}
+		comments = append(comments, string(comment))
+	}
+	if artist != "" {
+		comments = append(comments, "ARTIST="+artist)
+	}
+	if title != "" {
+		comments = append(comments, "TITLE="+title)
+	}
+
+	out := append([]byte(magic), appendField(nil, vendor)...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(comments))) //nolint:gosec // Bounded by the source header
+	for _, comment := range comments {
+		out = appendField(out, []byte(comment))
+	}
+	if c == codecVorbis {
+		out = append(out, 1) // Framing bit
+	}
+	return out
+}
+
+// readField reads a length-prefixed string.
+func readField(b []byte) (field, rest []byte, ok bool) {
+	if len(b) < 4 {
+		return nil, nil, false
+	}
+	n := binary.LittleEndian.Uint32(b)
+	if uint64(n) > uint64(len(b)-4) {
+		return nil, nil, false
+	}
+	return b[4 : 4+n], b[4+n:], true
+}
+
+// appendField appends a length-prefixed string.
+func appendField(b, field []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(field))) //nolint:gosec // Comments are short
+	return append(b, field...)
+}
+
+// preSkip returns the number of samples an Opus decoder discards at the
+// start of a stream, from its identification header.
+func preSkip(head []byte) int64 {
+	if len(head) < 12 {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint16(head[10:12]))
+}
@@ -0,0 +1,149 @@
+// Package ogg rewrites Ogg streams so that titles can be changed in-band.
+package ogg
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Page header layout.
+const (
+	headerSize = 27
+
+	flagContinued = 0x01 // Page starts with the rest of a packet
+	flagBOS       = 0x02 // First page of a logical stream
+	flagEOS       = 0x04 // Last page of a logical stream
+
+	// noGranule is the granule position of a page on which no packet ends.
+	noGranule = -1
+)
+
+// ErrInvalidPage is returned for data that is not an Ogg page.
+var ErrInvalidPage = errors.New("invalid ogg page")
+
+// crcTable is the lookup table of the Ogg CRC-32: polynomial 0x04C11DB7,
+// most significant bit first, no reflection and no final XOR.
+var crcTable = func() (t [256]uint32) {
+	for i := range t {
+		c := uint32(i) << 24
+		for range 8 {
+			if c&0x80000000 != 0 {
+				c = c<<1 ^ 0x04C11DB7
+			} else {
+				c <<= 1
+			}
+		}
+		t[i] = c
+	}
+	return t
+}()
+
+// ReadPage reads one complete Ogg page.
+func ReadPage(r *bufio.Reader) ([]byte, error) {
+	page := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, page); err != nil {
+		return nil, err
+	}
+	if string(page[:4]) != "OggS" {
+		return nil, ErrInvalidPage
+	}
+
+	segments := make([]byte, page[26])
+	if _, err := io.ReadFull(r, segments); err != nil {
+		return nil, err
+	}
+	size := 0
+	for _, s := range segments {
+		size += int(s)
+	}
+
+	page = append(page, segments...)
+	body := len(page)
+	page = append(page, make([]byte, size)...)
+	if _, err := io.ReadFull(r, page[body:]); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Granule returns the granule position of a page.
+func Granule(page []byte) int64 {
+	return int64(binary.LittleEndian.Uint64(page[6:14])) //nolint:gosec // Granule positions are signed
+}
+
+// setGranule sets the granule position of a page.
+func setGranule(page []byte, granule int64) {
+	binary.LittleEndian.PutUint64(page[6:14], uint64(granule)) //nolint:gosec // Granule positions are signed
+}
+
+// packets splits the body of a page into its packets. The last one is
+// incomplete when it continues on the next page.
+func packets(page []byte) (pkts [][]byte, complete bool) {
+	segments := page[headerSize : headerSize+int(page[26])]
+	body := page[headerSize+len(segments):]
+
+	var pkt []byte
+	complete = true
+	for _, s := range segments {
+		pkt = append(pkt, body[:s]...)
+		body = body[s:]
+		complete = s < 255
+		if complete {
+			pkts = append(pkts, pkt)
+			pkt = nil
+		}
+	}
+	if !complete {
+		pkts = append(pkts, pkt)
+	}
+	return pkts, complete
+}
+
+// buildPages lays out a packet on as many pages as it needs, all with
+// granule position 0 as header pages require.
+func buildPages(pkt []byte, flags byte, serial, seq uint32) (pages []byte, next uint32) {
+	for first := true; ; first = false {
+		var lacing []byte
+		n := 0
+		for len(lacing) < 255 {
+			s := min(len(pkt)-n, 255)
+			lacing = append(lacing, byte(s))
+			n += s
+			if s < 255 {
+				break
+			}
+		}
+
+		page := make([]byte, headerSize, headerSize+len(lacing)+n)
+		copy(page, "OggS")
+		page[5] = flags
+		if !first {
+			page[5] = flagContinued
+		}
+		binary.LittleEndian.PutUint32(page[14:18], serial)
+		binary.LittleEndian.PutUint32(page[18:22], seq)
+		page[26] = byte(len(lacing))
+		page = append(page, lacing...)
+		page = append(page, pkt[:n]...)
+		setChecksum(page)
+
+		pages = append(pages, page...)
+		pkt = pkt[n:]
+		seq++
+		if lacing[len(lacing)-1] < 255 {
+			return pages, seq
+		}
+	}
+}
+
+// setChecksum computes the CRC of a page and stores it in its header.
+func setChecksum(page []byte) {
+	clear(page[22:26])
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ crcTable[byte(crc>>24)^b]
+	}
+	binary.LittleEndian.PutUint32(page[22:26], crc)
+}
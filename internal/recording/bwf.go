@@ -0,0 +1,30 @@
+package recording
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/audio"
+)
+
+// bwfArgs returns the FFmpeg output arguments for a Broadcast WAV file whose
+// first sample is at origin. The file switches to RF64 when it outgrows the
+// 4 GiB of RIFF, and its bext chunk places it on the wall clock: the time
+// reference counts the samples from local midnight to the first sample.
+func bwfArgs(origin time.Time, originator, description string) []string {
+	local := origin.Local()
+	seconds := float64(local.Hour()*3600+local.Minute()*60+local.Second()) + float64(local.Nanosecond())/1e9
+	timeReference := int64(math.Round(seconds * audio.SampleRate))
+
+	return []string{
+		"-rf64", "auto",
+		"-write_bext", "1",
+		"-metadata", "originator=" + originator, // FFmpeg truncates to the 32 bytes of the field
+		"-metadata", "description=" + description,
+		"-metadata", "origination_date=" + local.Format("2006-01-02"),
+		"-metadata", "origination_time=" + local.Format("15:04:05"),
+		"-metadata", "time_reference=" + strconv.FormatInt(timeReference, 10),
+		"-metadata", "coding_history=A=PCM,F=" + strconv.Itoa(audio.SampleRate) + ",W=16,M=stereo,T=zwfm-encoder " + EncoderVersion + "\r\n",
+	}
+}
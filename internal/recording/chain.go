@@ -0,0 +1,422 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/util"
+)
+
+// chainHeadName is the file in the output directory of a compliance recorder
+// that holds the last link of its hash chain.
+const chainHeadName = ".chain.json"
+
+// chainTurnTimeout is how long the sidecar of a file waits for the sidecar of
+// the file before it, which may still be hashing a large file.
+const chainTurnTimeout = 2 * time.Minute
+
+const (
+	// MaxChainVerifySpan is the longest time range verified in one request.
+	MaxChainVerifySpan = 93 * 24 * time.Hour
+	// DefaultChainVerifySpan is the range verified when none is given.
+	DefaultChainVerifySpan = 24 * time.Hour
+)
+
+// SidecarChain links a file of a compliance recorder to the file before it.
+// A file that is modified, removed or replaced breaks the link of the file
+// after it.
+type SidecarChain struct {
+	PreviousFile          string `json:"previous_file,omitempty"` // Empty for the first file of the chain
+	PreviousSHA256        string `json:"previous_sha256,omitempty"`
+	PreviousSidecarSHA256 string `json:"previous_sidecar_sha256,omitempty"`
+}
+
+// chainLink is the last file of a hash chain.
+type chainLink struct {
+	File          string `json:"file"`
+	SHA256        string `json:"sha256"`
+	SidecarSHA256 string `json:"sidecar_sha256"`
+}
+
+// next returns the link of the file after l; a nil l starts a new chain.
+func (l *chainLink) next() *SidecarChain {
+	if l == nil {
+		return &SidecarChain{}
+	}
+	return &SidecarChain{
+		PreviousFile:          l.File,
+		PreviousSHA256:        l.SHA256,
+		PreviousSidecarSHA256: l.SidecarSHA256,
+	}
+}
+
+// chainTurn orders the sidecars of consecutive files. Files are finished in
+// the background and can complete out of order, such as a short file after a
+// gap finishing before the long file before it; each sidecar waits for the
+// one before it, so the hash chain links files in recording order.
+type chainTurn struct {
+	prev <-chan struct{} // Closed once the sidecar of the file before is written; nil for the first file
+	done chan struct{}
+}
+
+// newChainTurn returns the turn of a file that follows the file whose turn
+// ends with prev, and the channel that ends the new turn.
+func newChainTurn(prev <-chan struct{}) (chainTurn, <-chan struct{}) {
+	done := make(chan struct{})
+	return chainTurn{prev: prev, done: done}, done
+}
+
+// wait blocks until the sidecar of the file before is written, or until
+// chainTurnTimeout if that file was never finished.
+func (t chainTurn) wait(id string) {
+	if t.prev == nil {
+		return
+	}
+	select {
+	case <-t.prev:
+	case <-time.After(chainTurnTimeout):
+		slog.Warn("sidecar of previous file not written in time, linking to the chain as it is", "id", id)
+	}
+}
+
+// release lets the sidecar of the next file be written.
+func (t chainTurn) release() {
+	close(t.done)
+}
+
+// writeSidecar writes the sidecar of the completed file at audioPath and
+// returns its path. Compliance recorders link it to the file before it.
+func (r *GenericRecorder) writeSidecar(audioPath string, summary *fileSummary) (string, error) {
+	r.mu.RLock()
+	compliance := r.config.Compliance.Enabled
+	dir := r.outputDirLocked()
+	r.mu.RUnlock()
+
+	if !compliance {
+		path, _, err := summary.write(audioPath, nil)
+		return path, err
+	}
+
+	summary.turn.wait(r.id)
+	r.chainMu.Lock()
+	defer r.chainMu.Unlock()
+
+	head := r.chainHead(dir, filepath.Base(audioPath))
+	path, sha, err := summary.write(audioPath, head.next())
+	if err != nil {
+		return "", err
+	}
+	sidecarSums, err := computeChecksums(path)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(chainLink{File: filepath.Base(audioPath), SHA256: sha, SidecarSHA256: sidecarSums.SHA256()})
+	if err != nil {
+		return "", fmt.Errorf("encode chain head: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, chainHeadName), data); err != nil {
+		slog.Warn("failed to save hash chain head", "id", r.id, "error", err)
+	}
+	return path, nil
+}
+
+// chainHead returns the last link of the hash chain in dir. When the chain
+// head file is lost, the newest sidecar before the file current takes its
+// place. It returns nil to start a new chain.
+// Must be called with r.chainMu held.
+func (r *GenericRecorder) chainHead(dir, current string) *chainLink {
+	data, err := os.ReadFile(filepath.Join(dir, chainHeadName)) //nolint:gosec // Path is inside the recorder's output directory
+	if err == nil {
+		var head chainLink
+		if err := json.Unmarshal(data, &head); err == nil {
+			return &head
+		}
+		slog.Warn("invalid hash chain head, recovering from sidecars", "id", r.id)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	recordings, err := r.recordings(ctx)
+	if err != nil {
+		slog.Warn("failed to recover hash chain head, starting a new chain", "id", r.id, "error", err)
+		return nil
+	}
+	for i := range recordings {
+		rec := &recordings[i]
+		if rec.Name == current || rec.Sidecar == "" {
+			continue
+		}
+		sidecar, sidecarSHA, err := r.readChainSidecar(ctx, rec.Sidecar)
+		if err != nil {
+			slog.Warn("failed to recover hash chain head, starting a new chain", "id", r.id, "file", rec.Sidecar, "error", err)
+			return nil
+		}
+		return &chainLink{File: rec.Name, SHA256: sidecar.SHA256, SidecarSHA256: sidecarSHA}
+	}
+	return nil
+}
+
+// readChainSidecar reads the sidecar with the given name and returns it with
+// its checksum.
+func (r *GenericRecorder) readChainSidecar(ctx context.Context, name string) (*Sidecar, string, error) {
+	file, _, err := r.openRecording(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		_ = file.Close() //nolint:errcheck // Read-only
+	}()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("read sidecar: %w", err)
+	}
+	var sidecar Sidecar
+	if err := json.Unmarshal(data, &sidecar); err != nil {
+		return nil, "", fmt.Errorf("decode sidecar: %w", err)
+	}
+	sums, err := readChecksums(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return &sidecar, sums.SHA256(), nil
+}
+
+// ChainStatus is the result of verifying a file of a hash chain.
+type ChainStatus string
+
+// Results of verifying a file of a hash chain.
+const (
+	// ChainOK is a file that matches its sidecar and is linked to the file before it.
+	ChainOK ChainStatus = "ok"
+	// ChainUnchained is a file recorded before compliance mode was enabled.
+	ChainUnchained ChainStatus = "unchained"
+	// ChainModified is a file that differs from the checksum in its sidecar.
+	ChainModified ChainStatus = "modified"
+	// ChainSidecarModified is a file whose sidecar differs from the link of the file after it.
+	ChainSidecarModified ChainStatus = "sidecar_modified"
+	// ChainMissingSidecar is a file without a sidecar in a chain.
+	ChainMissingSidecar ChainStatus = "missing_sidecar"
+	// ChainGap is a file linked to a file that is missing.
+	ChainGap ChainStatus = "gap"
+	// ChainBroken is a file linked to another file than the one before it, or not linked at all.
+	ChainBroken ChainStatus = "broken"
+	// ChainUnreadable is a file or sidecar that could not be read.
+	ChainUnreadable ChainStatus = "unreadable"
+)
+
+// ChainEntry is the verification result of a file.
+type ChainEntry struct {
+	File   string      `json:"file"`
+	Start  time.Time   `json:"start"`
+	SHA256 string      `json:"sha256,omitempty"` // Checksum of the file as read
+	Status ChainStatus `json:"status"`
+	Detail string      `json:"detail,omitempty"`
+}
+
+// ChainReport is the verification of the hash chain of a recorder over a
+// time range. Valid is false if any file is not ok or unchained.
+type ChainReport struct {
+	RecorderID string       `json:"recorder_id"`
+	From       time.Time    `json:"from"`
+	To         time.Time    `json:"to"`
+	Valid      bool         `json:"valid"`
+	Files      int          `json:"files"`
+	Problems   int          `json:"problems"`
+	Entries    []ChainEntry `json:"entries"`
+}
+
+// chainFile is a verified file, as seen by the link of the file after it.
+type chainFile struct {
+	name       string
+	sha256     string // From its sidecar
+	sidecarSHA string
+	chained    bool
+}
+
+// VerifyChain walks the hash chain of the files of a recorder that started
+// in [from, to), reading every file from disk or S3.
+func (m *Manager) VerifyChain(ctx context.Context, id string, from, to time.Time) (*ChainReport, error) {
+	recorder, err := m.recorder(id)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case !to.After(from):
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidRange)
+	case to.Sub(from) > MaxChainVerifySpan:
+		return nil, fmt.Errorf("%w: range must not exceed %d days", ErrInvalidRange, int(MaxChainVerifySpan.Hours()/24))
+	}
+	return recorder.verifyChain(ctx, from, to)
+}
+
+func (r *GenericRecorder) verifyChain(ctx context.Context, from, to time.Time) (*ChainReport, error) {
+	recordings, err := r.recordings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(recordings) // Oldest first
+	recordings = slices.DeleteFunc(recordings, func(rec Recording) bool { return rec.Upload == UploadRecording })
+
+	r.mu.RLock()
+	cfg := r.config
+	r.mu.RUnlock()
+	safeName := sanitizeFilename(cfg.Name)
+	cutoff := util.OldestToKeep(cfg.RetentionDays, time.Now())
+	archive := &chainArchive{
+		names: make(map[string]bool, len(recordings)),
+		expired: func(name string) bool {
+			start, ok := recordingTime(safeName, name)
+			return cfg.RetentionDays > 0 && ok && start.Before(cutoff)
+		},
+	}
+	for _, rec := range recordings {
+		archive.names[rec.Name] = true
+	}
+
+	report := &ChainReport{RecorderID: r.id, From: from, To: to, Entries: []ChainEntry{}}
+	first := slices.IndexFunc(recordings, func(rec Recording) bool { return !rec.Start.Before(from) })
+	if first < 0 {
+		first = len(recordings)
+	}
+
+	// The file before the range is needed to check the first link
+	var prev *chainFile
+	if first > 0 {
+		rec := &recordings[first-1]
+		prev = &chainFile{name: rec.Name}
+		if rec.Sidecar != "" {
+			if sidecar, sidecarSHA, err := r.readChainSidecar(ctx, rec.Sidecar); err == nil {
+				prev = &chainFile{name: rec.Name, sha256: sidecar.SHA256, sidecarSHA: sidecarSHA, chained: sidecar.Chain != nil}
+			}
+		}
+	}
+
+	for i := first; i < len(recordings) && recordings[i].Start.Before(to); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		entry, cur, prevModified := r.verifyChainFile(ctx, &recordings[i], prev, archive)
+		if prevModified {
+			if n := len(report.Entries); n > 0 && report.Entries[n-1].Status == ChainOK {
+				report.Entries[n-1].Status = ChainSidecarModified
+				report.Entries[n-1].Detail = "sidecar differs from the link of " + entry.File
+			} else if n == 0 && entry.Status == ChainOK {
+				entry.Status = ChainBroken
+				entry.Detail = "sidecar of " + prev.name + " differs from this link"
+			}
+		}
+		report.Entries = append(report.Entries, entry)
+		prev = cur
+	}
+
+	report.Files = len(report.Entries)
+	for _, entry := range report.Entries {
+		if entry.Status != ChainOK && entry.Status != ChainUnchained {
+			report.Problems++
+		}
+	}
+	report.Valid = report.Problems == 0
+	return report, nil
+}
+
+// verifyChainFile verifies a file against its sidecar and the file before it
+// in the chain. It returns the result, the file as seen by the next link, and
+// whether the sidecar of the file before it differs from the link.
+func (r *GenericRecorder) verifyChainFile(ctx context.Context, rec *Recording, prev *chainFile, archive *chainArchive) (ChainEntry, *chainFile, bool) {
+	entry := ChainEntry{File: rec.Name, Start: rec.Start, Status: ChainOK}
+	cur := &chainFile{name: rec.Name}
+
+	if rec.Sidecar == "" {
+		entry.Status = ChainUnchained
+		if prev != nil && prev.chained {
+			entry.Status = ChainMissingSidecar
+		}
+		return entry, cur, false
+	}
+	sidecar, sidecarSHA, err := r.readChainSidecar(ctx, rec.Sidecar)
+	if err != nil {
+		entry.Status, entry.Detail = ChainUnreadable, err.Error()
+		return entry, cur, false
+	}
+	cur.sha256, cur.sidecarSHA, cur.chained = sidecar.SHA256, sidecarSHA, sidecar.Chain != nil
+
+	sha, err := r.recordingChecksum(ctx, rec.Name)
+	if err != nil {
+		entry.Status, entry.Detail = ChainUnreadable, err.Error()
+		return entry, cur, false
+	}
+	entry.SHA256 = sha
+
+	status, detail, prevModified := archive.checkLink(sidecar.Chain, prev)
+	entry.Status, entry.Detail = status, detail
+	if sidecar.SHA256 != "" && sidecar.SHA256 != sha {
+		entry.Status, entry.Detail = ChainModified, "sidecar records "+sidecar.SHA256
+	}
+	return entry, cur, prevModified
+}
+
+// chainArchive is the set of files a hash chain is verified against.
+type chainArchive struct {
+	names   map[string]bool
+	expired func(name string) bool // Reports whether retention cleanup may have removed a file
+}
+
+// checkLink checks the link of a file to prev, the file before it. It also
+// returns whether the sidecar of prev differs from the link.
+func (a *chainArchive) checkLink(link *SidecarChain, prev *chainFile) (status ChainStatus, detail string, prevModified bool) {
+	prevChained := prev != nil && prev.chained
+	switch {
+	case link == nil && prevChained:
+		return ChainBroken, "file is not linked to the chain", false
+	case link == nil:
+		return ChainUnchained, "", false
+	case link.PreviousFile == "" && prevChained:
+		return ChainBroken, "chain restarts after " + prev.name, false
+	case link.PreviousFile == "":
+		return ChainOK, "first file of the chain", false
+	case prev == nil && a.expired(link.PreviousFile):
+		return ChainOK, "previous file " + link.PreviousFile + " was removed after retention", false
+	case prev == nil || link.PreviousFile != prev.name && !a.names[link.PreviousFile]:
+		return ChainGap, "previous file " + link.PreviousFile + " is missing", false
+	case link.PreviousFile != prev.name:
+		return ChainBroken, "linked to " + link.PreviousFile + " instead of " + prev.name, false
+	}
+	return ChainOK, "", link.PreviousSHA256 != prev.sha256 || link.PreviousSidecarSHA256 != prev.sidecarSHA
+}
+
+// recordingChecksum reads a file of the recorder from disk or S3 and returns
+// its SHA-256.
+func (r *GenericRecorder) recordingChecksum(ctx context.Context, name string) (string, error) {
+	file, _, err := r.openRecording(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close() //nolint:errcheck // Read-only
+	}()
+
+	sums, err := readChecksums(file)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", name, err)
+	}
+	return sums.SHA256(), nil
+}
+
+// writeFileAtomic replaces the file at path with data.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,206 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec // MD5 is recorded for S3 tooling, not for security
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Multipart upload sizes. Files from multipartThreshold on are uploaded in
+// parts of uploadPartSize and checked by a CRC32 per part; smaller files are
+// sent in one request with their SHA-256, which S3 checks against the
+// received object.
+const (
+	multipartThreshold = 16 * 1024 * 1024
+	uploadPartSize     = 8 * 1024 * 1024
+)
+
+// Object metadata keys holding the digests of an uploaded file.
+const (
+	metaSHA256 = "sha256"
+	metaMD5    = "md5"
+)
+
+// errUploadMismatch indicates that the object in S3 differs from the local file.
+var errUploadMismatch = errors.New("uploaded object does not match local file")
+
+// fileChecksums are the digests of a file. crc32 is the CRC32 of the whole
+// file, partCRC32 the CRC32 of each uploadPartSize part.
+type fileChecksums struct {
+	sha256    []byte
+	md5       []byte
+	crc32     uint32
+	partCRC32 []uint32
+}
+
+// computeChecksums reads the file at path once and returns its digests.
+func computeChecksums(path string) (fileChecksums, error) {
+	file, err := os.Open(path) //nolint:gosec // Path is a recording file of this recorder
+	if err != nil {
+		return fileChecksums{}, err
+	}
+	defer func() {
+		_ = file.Close() //nolint:errcheck // Read-only
+	}()
+
+	sums, err := readChecksums(file)
+	if err != nil {
+		return fileChecksums{}, fmt.Errorf("checksum %s: %w", path, err)
+	}
+	return sums, nil
+}
+
+// readChecksums reads src to the end and returns its digests.
+func readChecksums(src io.Reader) (fileChecksums, error) {
+	sha, sum, crc := sha256.New(), md5.New(), crc32.NewIEEE() //nolint:gosec // See import
+	parts := &partCRC32Writer{}
+	if _, err := io.Copy(io.MultiWriter(sha, sum, crc, parts), src); err != nil {
+		return fileChecksums{}, err
+	}
+	return fileChecksums{
+		sha256:    sha.Sum(nil),
+		md5:       sum.Sum(nil),
+		crc32:     crc.Sum32(),
+		partCRC32: parts.sums(),
+	}, nil
+}
+
+// partCRC32Writer computes the CRC32 of each uploadPartSize part of the data
+// written to it.
+type partCRC32Writer struct {
+	done    []uint32
+	current uint32
+	written int
+}
+
+func (w *partCRC32Writer) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		chunk := p[:min(len(p), uploadPartSize-w.written)]
+		w.current = crc32.Update(w.current, crc32.IEEETable, chunk)
+		w.written += len(chunk)
+		p = p[len(chunk):]
+		if w.written == uploadPartSize {
+			w.done = append(w.done, w.current)
+			w.current, w.written = 0, 0
+		}
+	}
+	return n, nil
+}
+
+// sums returns the CRC32 of each part, including the last, shorter one.
+func (w *partCRC32Writer) sums() []uint32 {
+	if w.written > 0 {
+		return append(w.done, w.current)
+	}
+	return w.done
+}
+
+// SHA256 returns the hex SHA-256 of the file, as recorded in events and sidecars.
+func (c fileChecksums) SHA256() string {
+	return hex.EncodeToString(c.sha256)
+}
+
+// metadata returns the object metadata that stores the digests in S3.
+func (c fileChecksums) metadata() map[string]string {
+	return map[string]string{
+		metaSHA256: c.SHA256(),
+		metaMD5:    hex.EncodeToString(c.md5),
+	}
+}
+
+// s3CRC32 returns the CRC32 that S3 reports for the file when uploaded as
+// one object: of the whole file for a full-object checksum, or else the
+// composite of a multipart upload, the CRC32 of the part CRC32s followed by
+// the number of parts.
+func (c fileChecksums) s3CRC32(checksumType s3types.ChecksumType) string {
+	if checksumType == s3types.ChecksumTypeFullObject {
+		return base64.StdEncoding.EncodeToString(binary.BigEndian.AppendUint32(nil, c.crc32))
+	}
+	parts := make([]byte, 0, 4*len(c.partCRC32))
+	for _, sum := range c.partCRC32 {
+		parts = binary.BigEndian.AppendUint32(parts, sum)
+	}
+	composite := binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(parts))
+	return fmt.Sprintf("%s-%d", base64.StdEncoding.EncodeToString(composite), len(c.partCRC32))
+}
+
+// verifyUpload checks through a HEAD request that the object at key has the
+// size and digests of the local file. A multipart object without a CRC32 is
+// downloaded and hashed instead.
+func (r *GenericRecorder) verifyUpload(ctx context.Context, client *s3.Client, bucket string, req *uploadRequest, sums fileChecksums) error {
+	head, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(bucket),
+		Key:          aws.String(req.s3Key),
+		ChecksumMode: s3types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return fmt.Errorf("verify upload: %w", err)
+	}
+
+	if size := aws.ToInt64(head.ContentLength); size != req.fileSize {
+		return fmt.Errorf("%w: size %d, expected %d", errUploadMismatch, size, req.fileSize)
+	}
+	if got := head.Metadata[metaSHA256]; got != sums.SHA256() {
+		return fmt.Errorf("%w: sha256 %q, expected %q", errUploadMismatch, got, sums.SHA256())
+	}
+	if req.fileSize < multipartThreshold {
+		if got := aws.ToString(head.ChecksumSHA256); got != "" {
+			if want := base64.StdEncoding.EncodeToString(sums.sha256); got != want {
+				return fmt.Errorf("%w: S3 SHA-256 %s, expected %s", errUploadMismatch, got, want)
+			}
+		}
+		return nil
+	}
+
+	// The metadata is written by this encoder, so only the checksum S3
+	// computed from the received parts shows that the data arrived intact
+	got := aws.ToString(head.ChecksumCRC32)
+	if got == "" {
+		// Storage that keeps no checksums is not a mismatch; hash the stored data instead
+		slog.Warn("S3 reports no CRC32 for the object, verifying by download", "id", r.id, "key", req.s3Key)
+		return verifyByDownload(ctx, client, bucket, req.s3Key, sums)
+	}
+	if want := sums.s3CRC32(head.ChecksumType); got != want {
+		return fmt.Errorf("%w: S3 CRC32 %s, expected %s", errUploadMismatch, got, want)
+	}
+	return nil
+}
+
+// verifyByDownload reads the object at key back from S3 and compares its
+// SHA-256 with that of the local file.
+func verifyByDownload(ctx context.Context, client *s3.Client, bucket, key string, sums fileChecksums) error {
+	obj, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("verify upload: %w", err)
+	}
+	defer func() {
+		_ = obj.Body.Close() //nolint:errcheck // Read-only
+	}()
+
+	sha := sha256.New()
+	if _, err := io.Copy(sha, obj.Body); err != nil {
+		return fmt.Errorf("verify upload: read object: %w", err)
+	}
+	if got := sha.Sum(nil); !bytes.Equal(got, sums.sha256) {
+		return fmt.Errorf("%w: stored data has sha256 %x, expected %x", errUploadMismatch, got, sums.sha256)
+	}
+	return nil
+}
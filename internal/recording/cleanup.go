@@ -8,7 +8,6 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
-	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -81,7 +80,8 @@ func (m *Manager) cleanupLocalFiles(recorder *GenericRecorder) {
 		return
 	}
 
-	var deleted int
+	var deleted, retained int
+	now := time.Now()
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
@@ -89,13 +89,8 @@ func (m *Manager) cleanupLocalFiles(recorder *GenericRecorder) {
 
 		name := entry.Name()
 
-		// Only process files matching this recorder's pattern
-		if !strings.HasPrefix(name, safeName+"-") {
-			continue
-		}
-
-		// Extract date from filename
-		fileDate, ok := util.FilenameTime(name)
+		// Only process files written by this recorder
+		fileDate, ok := recordingTime(safeName, name)
 		if !ok {
 			continue
 		}
@@ -109,6 +104,12 @@ func (m *Manager) cleanupLocalFiles(recorder *GenericRecorder) {
 				continue
 			}
 
+			// Compliance recorders keep files for their retention period
+			if now.Before(cfg.Compliance.RetainedUntil(fileDate)) {
+				retained++
+				continue
+			}
+
 			if err := os.Remove(filePath); err != nil {
 				slog.Warn("cleanup: failed to delete local file", "id", cfg.ID, "path", filePath, "error", err)
 			} else {
@@ -118,6 +119,9 @@ func (m *Manager) cleanupLocalFiles(recorder *GenericRecorder) {
 		}
 	}
 
+	if retained > 0 {
+		slog.Info("cleanup: kept local files under compliance retention", "id", cfg.ID, "count", retained)
+	}
 	if deleted > 0 {
 		slog.Info("cleanup: deleted local files", "id", cfg.ID, "count", deleted)
 		m.logCleanupEvent(cfg.Name, deleted, "local")
@@ -153,8 +157,9 @@ func (m *Manager) cleanupS3Files(recorder *GenericRecorder) {
 	)
 	defer cancel()
 
-	var deleted int
+	var deleted, retained int
 	var continuationToken *string
+	now := time.Now()
 
 	for {
 		input := &s3.ListObjectsV2Input{
@@ -175,14 +180,19 @@ func (m *Manager) cleanupS3Files(recorder *GenericRecorder) {
 			key := aws.ToString(obj.Key)
 			filename := filepath.Base(key)
 
-			// Extract date from filename
-			fileDate, ok := util.FilenameTime(filename)
+			// Only process files written by this recorder
+			fileDate, ok := recordingTime(safeName, filename)
 			if !ok {
 				continue
 			}
 
 			// Delete if older than retention
 			if fileDate.Before(cutoff) {
+				// Compliance recorders refuse to delete locked or retained objects
+				if s3Retained(ctx, client, &cfg, key, fileDate, now) {
+					retained++
+					continue
+				}
 				_, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{
 					Bucket: aws.String(cfg.S3Bucket),
 					Key:    obj.Key,
@@ -202,6 +212,9 @@ func (m *Manager) cleanupS3Files(recorder *GenericRecorder) {
 		continuationToken = output.NextContinuationToken
 	}
 
+	if retained > 0 {
+		slog.Info("cleanup: kept S3 objects under compliance retention", "id", cfg.ID, "count", retained)
+	}
 	if deleted > 0 {
 		slog.Info("cleanup: deleted S3 objects", "id", cfg.ID, "count", deleted)
 		m.logCleanupEvent(cfg.Name, deleted, "s3")
@@ -0,0 +1,91 @@
+package recording
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/transfermanager"
+	tmtypes "github.com/aws/aws-sdk-go-v2/feature/s3/transfermanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/oszuidwest/zwfm-encoder/internal/types"
+)
+
+// applyObjectLock adds the Object Lock retention and legal hold of a
+// compliance recorder to an upload, if its bucket supports Object Lock.
+func (r *GenericRecorder) applyObjectLock(ctx context.Context, client *s3.Client, input *transfermanager.UploadObjectInput) {
+	r.mu.RLock()
+	compliance := r.config.Compliance
+	r.mu.RUnlock()
+
+	if !compliance.Enabled || !r.objectLockSupported(ctx, client) {
+		return
+	}
+	input.ObjectLockMode = tmtypes.ObjectLockMode(strings.ToUpper(string(compliance.LockMode)))
+	input.ObjectLockRetainUntilDate = aws.Time(compliance.RetainedUntil(time.Now()))
+	if compliance.LegalHold {
+		input.ObjectLockLegalHoldStatus = tmtypes.ObjectLockLegalHoldStatusOn
+	}
+}
+
+// objectLockSupported reports whether the bucket has Object Lock enabled.
+// The answer is cached until the S3 configuration changes.
+func (r *GenericRecorder) objectLockSupported(ctx context.Context, client *s3.Client) bool {
+	r.mu.RLock()
+	bucket := r.config.S3Bucket
+	key := s3ConfigKeyFrom(&r.config) + "|" + bucket
+	if r.objectLockKey == key {
+		enabled := r.objectLockEnabled
+		r.mu.RUnlock()
+		return enabled
+	}
+	r.mu.RUnlock()
+
+	out, err := client.GetObjectLockConfiguration(ctx, &s3.GetObjectLockConfigurationInput{Bucket: aws.String(bucket)})
+	var apiErr smithy.APIError
+	if err != nil && !errors.As(err, &apiErr) {
+		// Not an answer from S3; ask again at the next upload
+		slog.Warn("failed to check Object Lock support", "id", r.id, "bucket", bucket, "error", err)
+		return false
+	}
+
+	enabled := err == nil && out.ObjectLockConfiguration != nil &&
+		out.ObjectLockConfiguration.ObjectLockEnabled == s3types.ObjectLockEnabledEnabled
+	if !enabled {
+		slog.Warn("bucket does not support Object Lock, uploading without retention", "id", r.id, "bucket", bucket)
+	}
+
+	r.mu.Lock()
+	r.objectLockKey, r.objectLockEnabled = key, enabled
+	r.mu.Unlock()
+	return enabled
+}
+
+// s3Retained reports whether the object at key of a compliance recorder must
+// be kept at now: within the compliance retention of its file, within its
+// Object Lock retention, or under legal hold. Objects whose lock cannot be
+// read are kept.
+func s3Retained(ctx context.Context, client *s3.Client, cfg *types.Recorder, key string, start, now time.Time) bool {
+	if !cfg.Compliance.Enabled {
+		return false
+	}
+	if now.Before(cfg.Compliance.RetainedUntil(start)) {
+		return true
+	}
+
+	head, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(cfg.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		slog.Warn("cleanup: failed to read object retention, keeping it", "id", cfg.ID, "key", key, "error", err)
+		return true
+	}
+	return head.ObjectLockLegalHoldStatus == s3types.ObjectLockLegalHoldStatusOn ||
+		now.Before(aws.ToTime(head.ObjectLockRetainUntilDate))
+}
@@ -0,0 +1,489 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/eventlog"
+	"github.com/oszuidwest/zwfm-encoder/internal/types"
+	"github.com/oszuidwest/zwfm-encoder/internal/util"
+)
+
+const (
+	// DefaultCoverageDays is the number of days a coverage report spans by default.
+	DefaultCoverageDays = 7
+	// MaxCoverageSpan is the longest time range of a coverage report.
+	MaxCoverageSpan = 93 * 24 * time.Hour
+	// coverageTolerance is the longest stretch between files that is not a
+	// gap, which absorbs the start of the encoder and rounding of file times.
+	coverageTolerance = 10 * time.Second
+	// coverageCheckDelay is how long after each hour the archive is checked,
+	// so the files of the last rotation are complete.
+	coverageCheckDelay = 5 * time.Minute
+	// coverageCheckTimeout bounds a check of all recorders, which may list S3.
+	coverageCheckTimeout = 5 * time.Minute
+	// coverageCatchUp is the longest time before a start that is checked for
+	// gaps, so a restart after a long shutdown does not report weeks of gaps.
+	coverageCatchUp = DefaultCoverageDays * 24 * time.Hour
+)
+
+// coverageStateName is the file in the temp directory that keeps the end of
+// the last coverage check, so the time the encoder was down is checked after
+// it starts again.
+const coverageStateName = ".coverage.json"
+
+// coverageState is the progress of the coverage checks saved across restarts.
+type coverageState struct {
+	CheckedUntil time.Time            `json:"checked_until"`
+	LastGapEnd   map[string]time.Time `json:"last_gap_end,omitempty"`
+}
+
+// GapKind describes why a part of the archive is missing.
+type GapKind string
+
+// Kinds of gaps in the archive of a recorder.
+const (
+	// GapMissing is a rotation period without a file.
+	GapMissing GapKind = "missing"
+	// GapShort is the part of a rotation period its file does not cover.
+	GapShort GapKind = "short"
+	// GapBetween is time between the files of a continuous recorder.
+	GapBetween GapKind = "gap"
+)
+
+// CoverageGap is a stretch of time the files of a recorder do not cover.
+type CoverageGap struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Kind  GapKind   `json:"kind"`
+	File  string    `json:"file,omitempty"` // The short file, if any
+}
+
+// CoverageDay is the coverage of one local day.
+type CoverageDay struct {
+	Date            string        `json:"date"` // YYYY-MM-DD
+	ExpectedSeconds float64       `json:"expected_seconds"`
+	CoveredSeconds  float64       `json:"covered_seconds"`
+	Percent         float64       `json:"percent"` // Of the expected time; 0 if nothing is expected
+	Gaps            []CoverageGap `json:"gaps"`
+}
+
+// CoverageReport compares the files of a recorder over a time range with
+// the files its rotation mode should have written. Time before the recorder
+// was created or past its retention is not expected; nothing is expected of
+// on-demand recorders.
+type CoverageReport struct {
+	RecorderID      string        `json:"recorder_id"`
+	From            time.Time     `json:"from"`
+	To              time.Time     `json:"to"`
+	Expected        bool          `json:"expected"`
+	ExpectedSeconds float64       `json:"expected_seconds"`
+	CoveredSeconds  float64       `json:"covered_seconds"`
+	Percent         float64       `json:"percent"`
+	Gaps            int           `json:"gaps"`
+	Days            []CoverageDay `json:"days"`
+}
+
+// GapHandler is called for each new gap the coverage checker finds.
+type GapHandler func(recorderName string, gap CoverageGap)
+
+// coverageSpan is the time covered by a file.
+type coverageSpan struct {
+	start, end time.Time
+	file       string
+}
+
+// Coverage reports the coverage of the files of a recorder in [from, to).
+func (m *Manager) Coverage(ctx context.Context, id string, from, to time.Time) (*CoverageReport, error) {
+	recorder, err := m.recorder(id)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case !to.After(from):
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidRange)
+	case to.Sub(from) > MaxCoverageSpan:
+		return nil, fmt.Errorf("%w: range must not exceed %d days", ErrInvalidRange, int(MaxCoverageSpan.Hours()/24))
+	}
+	return recorder.coverage(ctx, from, to)
+}
+
+func (r *GenericRecorder) coverage(ctx context.Context, from, to time.Time) (*CoverageReport, error) {
+	cfg := r.Config()
+	spans, err := r.coveredSpans(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	covered := mergeSpans(spans)
+
+	report := &CoverageReport{
+		RecorderID: r.id,
+		From:       from,
+		To:         to,
+		Expected:   cfg.RotationMode.AutoStarts(),
+		Days:       []CoverageDay{},
+	}
+	expectFrom := expectedFrom(&cfg, from, time.Now())
+	var gaps []CoverageGap
+	if report.Expected && to.After(expectFrom) {
+		gaps = findGaps(&cfg, spans, covered, expectFrom, to)
+	} else {
+		expectFrom = to
+	}
+
+	for dayStart := startOfDay(from); dayStart.Before(to); {
+		y, m, d := dayStart.Date()
+		dayEnd := time.Date(y, m, d+1, 0, 0, 0, 0, dayStart.Location())
+		start, end := later(dayStart, from), earlier(dayEnd, to)
+
+		day := CoverageDay{
+			Date:            dayStart.Format(time.DateOnly),
+			ExpectedSeconds: overlap(start, end, expectFrom, to).Seconds(),
+			Gaps:            []CoverageGap{},
+		}
+		for _, span := range covered {
+			day.CoveredSeconds += overlap(span.start, span.end, start, end).Seconds()
+		}
+		for _, gap := range gaps {
+			if gap.Start.Before(end) && gap.End.After(start) {
+				gap.Start, gap.End = later(gap.Start, start), earlier(gap.End, end)
+				day.Gaps = append(day.Gaps, gap)
+			}
+		}
+		day.Percent = percent(day.CoveredSeconds, day.ExpectedSeconds)
+
+		report.ExpectedSeconds += day.ExpectedSeconds
+		report.CoveredSeconds += day.CoveredSeconds
+		report.Days = append(report.Days, day)
+		dayStart = dayEnd
+	}
+	report.Percent = percent(report.CoveredSeconds, report.ExpectedSeconds)
+	report.Gaps = len(gaps)
+	return report, nil
+}
+
+// coveredSpans returns the time covered by each file of the recorder that
+// overlaps [from, to), oldest first. Sidecars give the exact start and end of
+// a file; without one, the file is assumed to start at the time in its name
+// and to end at its last write, or at the end of its rotation period.
+func (r *GenericRecorder) coveredSpans(ctx context.Context, from, to time.Time) ([]coverageSpan, error) {
+	recordings, err := r.recordings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(recordings) // Oldest first
+
+	r.mu.RLock()
+	running := r.state == types.ProcessRunning
+	currentStart := r.startTime
+	r.mu.RUnlock()
+	now := time.Now()
+
+	spans := make([]coverageSpan, 0, len(recordings))
+	for i := range recordings {
+		rec := &recordings[i]
+		span := coverageSpan{start: rec.Start, end: rec.End, file: rec.Name}
+		if span.end.IsZero() && i+1 < len(recordings) {
+			span.end = recordings[i+1].Start // Continuous file only in S3
+		}
+
+		switch {
+		case rec.Upload == UploadRecording && running:
+			span.start, span.end = currentStart, now
+		case !rec.Start.Before(to) || (!span.end.IsZero() && !span.end.After(from)):
+			continue
+		case rec.Sidecar != "":
+			sidecar, err := r.readSidecar(ctx, rec.Sidecar)
+			if err != nil {
+				slog.Warn("coverage: failed to read sidecar", "id", r.id, "file", rec.Sidecar, "error", err)
+				break
+			}
+			span.start, span.end = sidecar.Start, sidecar.End
+		}
+		if span.end.After(span.start) {
+			spans = append(spans, span)
+		}
+	}
+
+	slices.SortFunc(spans, func(a, b coverageSpan) int { return a.start.Compare(b.start) })
+	return spans, nil
+}
+
+// findGaps returns the stretches of [from, to) the files of the recorder do
+// not cover, split by rotation period.
+func findGaps(cfg *types.Recorder, spans, covered []coverageSpan, from, to time.Time) []CoverageGap {
+	if !cfg.RotationMode.Rotates() {
+		var gaps []CoverageGap
+		for _, hole := range uncovered(covered, from, to) {
+			hole.Kind = GapBetween
+			gaps = append(gaps, hole)
+		}
+		return gaps
+	}
+
+	var gaps []CoverageGap
+	for t := from; t.Before(to); {
+		periodStart, next := rotationPeriod(cfg, t)
+		start, end := later(periodStart, from), earlier(next, to)
+		holes := uncovered(covered, start, end)
+
+		// A period no file overlaps has no file at all
+		file := ""
+		for _, span := range spans {
+			if span.start.Before(end) && span.end.After(start) {
+				file = span.file
+				break
+			}
+		}
+		for _, hole := range holes {
+			hole.Kind, hole.File = GapShort, file
+			if file == "" {
+				hole.Kind = GapMissing
+			}
+			gaps = append(gaps, hole)
+		}
+		t = next
+	}
+	return gaps
+}
+
+// uncovered returns the stretches of [from, to) longer than the tolerance
+// that no merged span covers.
+func uncovered(covered []coverageSpan, from, to time.Time) []CoverageGap {
+	var holes []CoverageGap
+	cursor := from
+	for _, span := range covered {
+		if !span.end.After(cursor) {
+			continue
+		}
+		if !span.start.Before(to) {
+			break
+		}
+		if span.start.Sub(cursor) > coverageTolerance {
+			holes = append(holes, CoverageGap{Start: cursor, End: span.start})
+		}
+		cursor = span.end
+	}
+	if to.Sub(cursor) > coverageTolerance {
+		holes = append(holes, CoverageGap{Start: cursor, End: to})
+	}
+	return holes
+}
+
+// mergeSpans joins sorted spans that overlap or lie within the tolerance of
+// each other.
+func mergeSpans(spans []coverageSpan) []coverageSpan {
+	var merged []coverageSpan
+	for _, span := range spans {
+		if n := len(merged); n > 0 && !span.start.After(merged[n-1].end.Add(coverageTolerance)) {
+			merged[n-1].end = later(merged[n-1].end, span.end)
+			continue
+		}
+		merged = append(merged, coverageSpan{start: span.start, end: span.end})
+	}
+	return merged
+}
+
+// expectedFrom returns the start of the time from which files of the
+// recorder are expected: not before it was created or past its retention.
+func expectedFrom(cfg *types.Recorder, from, now time.Time) time.Time {
+	if cfg.CreatedAt > 0 {
+		from = later(from, time.UnixMilli(cfg.CreatedAt))
+	}
+	if cfg.RetentionDays > 0 {
+		from = later(from, util.OldestToKeep(cfg.RetentionDays, now))
+	}
+	return from
+}
+
+// startOfDay returns local midnight of the day of t.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// overlap returns how long [start, end) and [from, to) overlap.
+func overlap(start, end, from, to time.Time) time.Duration {
+	return max(earlier(end, to).Sub(later(start, from)), 0)
+}
+
+func percent(covered, expected float64) float64 {
+	if expected <= 0 {
+		return 0
+	}
+	return min(100, float64(int(covered/expected*1000))/10)
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// startCoverageScheduler checks the archive of each recorder for gaps
+// shortly after each hour. The first check continues from the last check
+// before the start, so the time the encoder was down is checked too.
+// Must be called with m.mu held.
+func (m *Manager) startCoverageScheduler() {
+	m.coverageFrom = time.Now()
+	if state, err := m.readCoverageState(); err != nil {
+		slog.Warn("failed to read coverage state, checking from now", "error", err)
+	} else if state != nil && time.Since(state.CheckedUntil) < coverageCatchUp && state.CheckedUntil.Before(m.coverageFrom) {
+		m.coverageFrom = state.CheckedUntil
+		for id, end := range state.LastGapEnd {
+			if _, ok := m.recorders[id]; ok {
+				m.lastGapEnd[id] = end
+			}
+		}
+	}
+	stopCh := m.coverageStopCh
+	go func() {
+		for {
+			duration := util.TimeUntilNextHour(time.Now()) + coverageCheckDelay
+			select {
+			case <-stopCh:
+				return
+			case <-time.After(duration):
+				m.checkCoverage()
+			}
+		}
+	}()
+}
+
+// checkCoverage reports the gaps in the archive of the enabled recorders
+// since the last check. Gaps that continue a gap reported at the last check
+// are not reported again.
+func (m *Manager) checkCoverage() {
+	ctx, cancel := context.WithTimeout(context.Background(), coverageCheckTimeout)
+	defer cancel()
+
+	m.mu.Lock()
+	from, to := m.coverageFrom, time.Now().Add(-coverageCheckDelay)
+	m.coverageFrom = to
+	recorders := make([]*GenericRecorder, 0, len(m.recorders))
+	for _, recorder := range m.recorders {
+		recorders = append(recorders, recorder)
+	}
+	handler := m.gapHandler
+	m.mu.Unlock()
+	if !to.After(from) {
+		return
+	}
+
+	for _, recorder := range recorders {
+		cfg := recorder.Config()
+		if !cfg.RotationMode.AutoStarts() || !cfg.IsEnabled() {
+			continue
+		}
+		report, err := recorder.coverage(ctx, from, to)
+		if err != nil {
+			slog.Warn("coverage check failed", "id", cfg.ID, "error", err)
+			continue
+		}
+
+		for _, gap := range m.newGaps(cfg.ID, reportGaps(report)) {
+			slog.Warn("gap in recordings", "id", cfg.ID, "kind", gap.Kind, "start", gap.Start, "end", gap.End, "file", gap.File)
+			recorder.logEvent(eventlog.RecorderGap, &eventlog.RecorderEventParams{
+				RecorderName: cfg.Name,
+				Filename:     gap.File,
+				GapKind:      string(gap.Kind),
+				GapStart:     gap.Start,
+				GapEnd:       gap.End,
+			})
+			if handler != nil {
+				handler(cfg.Name, gap)
+			}
+		}
+	}
+
+	m.mu.Lock()
+	state := coverageState{CheckedUntil: to, LastGapEnd: maps.Clone(m.lastGapEnd)}
+	m.mu.Unlock()
+	if err := m.writeCoverageState(&state); err != nil {
+		slog.Warn("failed to save coverage state", "error", err)
+	}
+}
+
+// readCoverageState returns the saved progress of the coverage checks, or nil
+// if there is none.
+func (m *Manager) readCoverageState() (*coverageState, error) {
+	data, err := os.ReadFile(filepath.Join(m.tempDir, coverageStateName)) //nolint:gosec // Path is inside the temp directory
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state coverageState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decode coverage state: %w", err)
+	}
+	return &state, nil
+}
+
+// writeCoverageState saves the progress of the coverage checks.
+func (m *Manager) writeCoverageState(state *coverageState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encode coverage state: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(m.tempDir, coverageStateName), data)
+}
+
+// reportGaps joins the gaps of a report that follow each other, across days
+// and rotation periods. A gap is missing if any of its parts is, and names a
+// file only if all its parts are of that file.
+func reportGaps(report *CoverageReport) []CoverageGap {
+	var gaps []CoverageGap
+	for _, day := range report.Days {
+		for _, gap := range day.Gaps {
+			n := len(gaps)
+			if n == 0 || gap.Start.Sub(gaps[n-1].End) > coverageTolerance {
+				gaps = append(gaps, gap)
+				continue
+			}
+			last := &gaps[n-1]
+			last.End = gap.End
+			if gap.Kind == GapMissing {
+				last.Kind = GapMissing
+			}
+			if gap.File != last.File {
+				last.File = ""
+			}
+		}
+	}
+	return gaps
+}
+
+// newGaps returns the gaps of a recorder that do not continue the last gap
+// reported, and remembers the end of the last one.
+func (m *Manager) newGaps(id string, gaps []CoverageGap) []CoverageGap {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var fresh []CoverageGap
+	for _, gap := range gaps {
+		if last, ok := m.lastGapEnd[id]; !ok || gap.Start.Sub(last) > coverageTolerance {
+			fresh = append(fresh, gap)
+		}
+		m.lastGapEnd[id] = gap.End
+	}
+	return fresh
+}
@@ -0,0 +1,128 @@
+package recording
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/metadata"
+)
+
+const (
+	// cueContentType is the content type of CUE sheets uploaded to S3.
+	cueContentType = "application/x-cue"
+	// maxCueTracks is the track limit of the CUE sheet format.
+	maxCueTracks = 99
+)
+
+// cueSheet logs what aired when in a recording file, as a CUE sheet next to it.
+// Tracks are added in memory and written out by flush, so callers can keep
+// file I/O outside the recorder lock. The sheet on disk is complete up to
+// the last flushed title.
+type cueSheet struct {
+	path string
+
+	mu      sync.Mutex
+	text    strings.Builder
+	tracks  int
+	written int  // Tracks in the file on disk
+	closed  bool // Set once the file is handed on for upload
+}
+
+// cuePath returns the path of the CUE sheet for a recording file.
+func cuePath(audioPath string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".cue"
+}
+
+// newCueSheet returns the CUE sheet for a recording file that started at start.
+// Nothing is written until the first track is flushed.
+func newCueSheet(audioPath string, start time.Time) *cueSheet {
+	fileType := "WAVE"
+	if filepath.Ext(audioPath) == ".mp3" {
+		fileType = "MP3"
+	}
+
+	c := &cueSheet{path: cuePath(audioPath)}
+	fmt.Fprintf(&c.text, "REM DATE %s\n", start.Format("2006-01-02"))
+	fmt.Fprintf(&c.text, "REM START %s\n", start.Format(time.RFC3339))
+	fmt.Fprintf(&c.text, "FILE %s %s\n", cueQuote(filepath.Base(audioPath)), fileType)
+	return c
+}
+
+// addTrack adds the item that started airing offset into the file.
+func (c *cueSheet) addTrack(np metadata.NowPlaying, offset time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	if c.tracks == maxCueTracks {
+		slog.Debug("cue sheet track limit reached", "path", c.path)
+		return
+	}
+	c.tracks++
+
+	fmt.Fprintf(&c.text, "  TRACK %02d AUDIO\n", c.tracks)
+	fmt.Fprintf(&c.text, "    TITLE %s\n", cueQuote(np.Title))
+	if np.Artist != "" {
+		fmt.Fprintf(&c.text, "    PERFORMER %s\n", cueQuote(np.Artist))
+	}
+	fmt.Fprintf(&c.text, "    REM AIRED %s\n", np.UpdatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&c.text, "    INDEX 01 %s\n", cueTime(offset))
+}
+
+// flush writes the sheet to disk if it has tracks that are not written yet.
+func (c *cueSheet) flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	return c.flushLocked()
+}
+
+// flushLocked writes the sheet to disk. Must be called with c.mu held.
+func (c *cueSheet) flushLocked() error {
+	if c.tracks == c.written {
+		return nil
+	}
+	if err := os.WriteFile(c.path, []byte(c.text.String()), 0o644); err != nil { //nolint:gosec // Sidecar is as readable as the recording
+		return fmt.Errorf("write cue sheet: %w", err)
+	}
+	c.written = c.tracks
+	return nil
+}
+
+// close writes the remaining tracks and returns the path of the sheet, or
+// an empty string if it has no tracks. Tracks added later are dropped.
+func (c *cueSheet) close() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if err := c.flushLocked(); err != nil {
+		return "", err
+	}
+	if c.written == 0 {
+		return "", nil
+	}
+	return c.path, nil
+}
+
+// cueQuote returns s as a quoted CUE string. The format has no escapes, so
+// double quotes become single quotes.
+func cueQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+}
+
+// cueTime formats an offset as mm:ss:ff with 75 frames per second.
+func cueTime(d time.Duration) string {
+	d = max(d, 0)
+	frames := d.Milliseconds() * 75 / 1000
+	return fmt.Sprintf("%02d:%02d:%02d", frames/75/60, frames/75%60, frames%75)
+}
@@ -0,0 +1,566 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/audio"
+	"github.com/oszuidwest/zwfm-encoder/internal/types"
+)
+
+// Export limits.
+const (
+	// MaxExportDuration is the longest time range a single export may cover.
+	MaxExportDuration = 24 * time.Hour
+	// exportRetention is how long finished exports stay available for download.
+	exportRetention = 24 * time.Hour
+	// exportMinGap is the shortest stretch without recordings that is filled
+	// with silence; shorter jumps between files are timing jitter.
+	exportMinGap = 100 * time.Millisecond
+)
+
+// ExportState describes where an export job is in its run.
+type ExportState string
+
+// States of an export job.
+const (
+	// ExportQueued is the state of a job waiting for the one before it.
+	ExportQueued ExportState = "queued"
+	// ExportFetching is the state of a job downloading files from S3.
+	ExportFetching ExportState = "fetching"
+	// ExportEncoding is the state of a job cutting and joining the files.
+	ExportEncoding ExportState = "encoding"
+	// ExportDone is the state of a job whose result can be downloaded.
+	ExportDone ExportState = "done"
+	// ExportFailed is the state of a job that stopped with an error.
+	ExportFailed ExportState = "failed"
+)
+
+// Export is a job that cuts a time range out of the files of a recorder.
+// Progress runs from 0 to 1 over the download of files only in S3 and the
+// encoding of the result.
+type Export struct {
+	ID         string      `json:"id"`
+	RecorderID string      `json:"recorder_id"`
+	Start      time.Time   `json:"start"`
+	End        time.Time   `json:"end"`
+	Codec      types.Codec `json:"codec"`
+	State      ExportState `json:"state"`
+	Progress   float64     `json:"progress"`
+	Error      string      `json:"error,omitempty"`
+	Files      []string    `json:"files,omitempty"` // Recordings the range was cut from
+	Gaps       []ExportGap `json:"gaps,omitempty"`  // Stretches without recordings, exported as silence
+	Filename   string      `json:"filename,omitempty"`
+	Size       int64       `json:"size,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	FinishedAt time.Time   `json:"finished_at,omitzero"`
+}
+
+// ExportGap is a stretch of an export range that no recording covers.
+type ExportGap struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// exportJob is an export with the state needed to run and clean it up.
+type exportJob struct {
+	Export
+	dir    string // Holds fetched files and the result
+	cancel context.CancelFunc
+}
+
+// exportInput is a recording with the part of it that is exported, in
+// seconds from the start of the file.
+type exportInput struct {
+	rec      Recording
+	path     string
+	from, to float64
+}
+
+// StartExport queues a job that exports the audio between start and end
+// from the files of a recorder. An empty codec keeps the recorder's codec;
+// any other codec transcodes the result.
+func (m *Manager) StartExport(id string, start, end time.Time, codec types.Codec) (Export, error) {
+	recorder, err := m.recorder(id)
+	if err != nil {
+		return Export{}, err
+	}
+
+	switch {
+	case !end.After(start):
+		return Export{}, fmt.Errorf("%w: end must be after start", ErrInvalidExport)
+	case end.Sub(start) > MaxExportDuration:
+		return Export{}, fmt.Errorf("%w: range must not exceed %s", ErrInvalidExport, MaxExportDuration)
+	case start.After(time.Now()):
+		return Export{}, fmt.Errorf("%w: start must not be in the future", ErrInvalidExport)
+	case codec != "" && (!types.ValidCodecs[codec] || codec == types.CodecAAC):
+		return Export{}, fmt.Errorf("%w: codec must be wav, mp3, mp2, or ogg", ErrInvalidExport)
+	}
+	if codec == "" {
+		codec = recorder.Config().Codec
+	}
+
+	jobID, err := generateExportID()
+	if err != nil {
+		return Export{}, fmt.Errorf("generate export ID: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &exportJob{
+		Export: Export{
+			ID:         jobID,
+			RecorderID: id,
+			Start:      start,
+			End:        end,
+			Codec:      codec,
+			State:      ExportQueued,
+			CreatedAt:  time.Now(),
+		},
+		dir:    filepath.Join(m.tempDir, "exports", jobID),
+		cancel: cancel,
+	}
+
+	m.exportsMu.Lock()
+	m.pruneExportsLocked()
+	m.exports[jobID] = job
+	m.exportsMu.Unlock()
+
+	slog.Info("export queued", "id", jobID, "recorder_id", id, "start", start, "end", end, "codec", codec)
+	go m.runExport(ctx, job, recorder)
+	return job.Export, nil
+}
+
+// Exports returns the export jobs of a recorder, newest first.
+func (m *Manager) Exports(id string) []Export {
+	m.exportsMu.Lock()
+	defer m.exportsMu.Unlock()
+
+	m.pruneExportsLocked()
+	exports := make([]Export, 0, len(m.exports))
+	for _, job := range m.exports {
+		if job.RecorderID == id {
+			exports = append(exports, job.Export)
+		}
+	}
+	slices.SortFunc(exports, func(a, b Export) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return exports
+}
+
+// Export returns an export job of a recorder.
+func (m *Manager) Export(id, jobID string) (Export, error) {
+	m.exportsMu.Lock()
+	defer m.exportsMu.Unlock()
+
+	job, ok := m.exports[jobID]
+	if !ok || job.RecorderID != id {
+		return Export{}, ErrExportNotFound
+	}
+	return job.Export, nil
+}
+
+// OpenExport opens the result of a finished export job for reading.
+func (m *Manager) OpenExport(id, jobID string) (*os.File, Export, error) {
+	export, err := m.Export(id, jobID)
+	if err != nil {
+		return nil, Export{}, err
+	}
+	if export.State != ExportDone {
+		return nil, export, ErrExportNotReady
+	}
+
+	file, err := os.Open(filepath.Join(m.tempDir, "exports", jobID, export.Filename)) //nolint:gosec // Path is built from the job, not from the request
+	if err != nil {
+		return nil, export, err
+	}
+	return file, export, nil
+}
+
+// DeleteExport cancels an export job that is still running and removes it
+// with its result.
+func (m *Manager) DeleteExport(id, jobID string) error {
+	m.exportsMu.Lock()
+	job, ok := m.exports[jobID]
+	if !ok || job.RecorderID != id {
+		m.exportsMu.Unlock()
+		return ErrExportNotFound
+	}
+	delete(m.exports, jobID)
+	finished := !job.FinishedAt.IsZero()
+	m.exportsMu.Unlock()
+
+	// A running job cleans up after itself once it sees the cancellation
+	job.cancel()
+	if finished {
+		removeExportDir(job)
+	}
+	slog.Info("export removed", "id", jobID, "recorder_id", id)
+	return nil
+}
+
+// pruneExportsLocked removes jobs that finished longer than exportRetention ago.
+// Must be called with m.exportsMu held.
+func (m *Manager) pruneExportsLocked() {
+	for jobID, job := range m.exports {
+		if !job.FinishedAt.IsZero() && time.Since(job.FinishedAt) > exportRetention {
+			delete(m.exports, jobID)
+			removeExportDir(job)
+		}
+	}
+}
+
+// runExport runs an export job once the jobs before it are done. Jobs run one
+// at a time, as each one can download and encode hours of audio.
+func (m *Manager) runExport(ctx context.Context, job *exportJob, recorder *GenericRecorder) {
+	defer job.cancel()
+
+	select {
+	case m.exportSem <- struct{}{}:
+		defer func() { <-m.exportSem }()
+		m.finishExport(job, m.export(ctx, job, recorder))
+	case <-ctx.Done():
+		m.finishExport(job, context.Cause(ctx))
+	}
+}
+
+// finishExport records the outcome of an export job.
+func (m *Manager) finishExport(job *exportJob, err error) {
+	m.exportsMu.Lock()
+	job.FinishedAt = time.Now()
+	if err != nil {
+		job.State = ExportFailed
+		job.Error = err.Error()
+	} else {
+		job.State = ExportDone
+		job.Progress = 1
+	}
+	_, kept := m.exports[job.ID]
+	m.exportsMu.Unlock()
+
+	if err != nil || !kept {
+		removeExportDir(job)
+	}
+	if err != nil {
+		slog.Warn("export failed", "id", job.ID, "recorder_id", job.RecorderID, "error", err)
+		return
+	}
+	slog.Info("export finished", "id", job.ID, "recorder_id", job.RecorderID, "file", job.Filename, "size", job.Size)
+}
+
+// export locates the files covering the range of the job, fetches those only
+// in S3 and cuts and joins them into one file.
+func (m *Manager) export(ctx context.Context, job *exportJob, recorder *GenericRecorder) error {
+	recordings, err := recorder.recordings(ctx)
+	if err != nil {
+		return err
+	}
+	// File names give the start of their period; sidecars the exact span
+	inputs := exportInputs(recordings, job.Start, job.End)
+	inputs = exportInputs(exactSpans(ctx, recorder, inputs), job.Start, job.End)
+	if len(inputs) == 0 {
+		return errors.New("no recordings cover the requested range")
+	}
+
+	if err := os.MkdirAll(job.dir, 0o755); err != nil { //nolint:gosec // Export directory needs to be readable
+		return fmt.Errorf("create export directory: %w", err)
+	}
+
+	gaps := exportGaps(inputs, job.Start, earlier(job.End, job.CreatedAt))
+	m.updateExport(job, func(e *Export) {
+		e.State = ExportFetching
+		e.Files = make([]string, len(inputs))
+		for i, in := range inputs {
+			e.Files[i] = in.rec.Name
+		}
+		e.Gaps = gaps
+	})
+	if len(gaps) > 0 {
+		slog.Warn("export range has gaps, filling with silence", "id", job.ID, "recorder_id", job.RecorderID, "gaps", len(gaps))
+	}
+	fetchShare, err := m.fetchExportInputs(ctx, job, recorder, inputs)
+	if err != nil {
+		return err
+	}
+
+	safeName := sanitizeFilename(recorder.Config().Name)
+	filename := fmt.Sprintf("%s-export-%s-%s.%s", safeName,
+		job.Start.Local().Format(fileSecondsLayout), job.End.Local().Format(fileSecondsLayout), codecExtension(job.Codec))
+	m.updateExport(job, func(e *Export) {
+		e.State = ExportEncoding
+		e.Filename = filename
+	})
+
+	output := filepath.Join(job.dir, filename)
+	duration := job.End.Sub(job.Start)
+	var formatArgs []string
+	if job.Codec == types.CodecWAV {
+		formatArgs = bwfArgs(job.Start, m.stationName, recorder.Config().Name)
+	}
+	err = m.encodeExport(ctx, inputs, gaps, output, job.Codec, formatArgs, func(done time.Duration) {
+		m.updateExport(job, func(e *Export) {
+			e.Progress = fetchShare + (1-fetchShare)*min(1, done.Seconds()/duration.Seconds())
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(output)
+	if err != nil {
+		return fmt.Errorf("stat export: %w", err)
+	}
+	m.updateExport(job, func(e *Export) { e.Size = info.Size() })
+	return nil
+}
+
+// fetchExportInputs sets the path of each input, downloading the files only
+// in S3 into the export directory. It returns the share of the progress taken
+// by the downloads.
+func (m *Manager) fetchExportInputs(ctx context.Context, job *exportJob, recorder *GenericRecorder, inputs []exportInput) (float64, error) {
+	var total int64
+	for _, in := range inputs {
+		if !in.rec.Local {
+			total += in.rec.Size
+		}
+	}
+	fetchShare := 0.0
+	if total > 0 {
+		fetchShare = 0.5
+	}
+
+	var fetched int64
+	for i := range inputs {
+		file, _, err := recorder.openRecording(ctx, inputs[i].rec.Name)
+		if err != nil {
+			return 0, fmt.Errorf("open %s: %w", inputs[i].rec.Name, err)
+		}
+		if local, ok := file.(*os.File); ok {
+			inputs[i].path = local.Name()
+			_ = local.Close() //nolint:errcheck // Only the path is needed
+			continue
+		}
+
+		inputs[i].path = filepath.Join(job.dir, fmt.Sprintf("input-%03d%s", i, filepath.Ext(inputs[i].rec.Name)))
+		err = copyToFile(inputs[i].path, file, func(n int64) {
+			fetched += n
+			m.updateExport(job, func(e *Export) {
+				e.Progress = fetchShare * float64(fetched) / float64(total)
+			})
+		})
+		_ = file.Close() //nolint:errcheck // Fully read or already failing
+		if err != nil {
+			return 0, fmt.Errorf("fetch %s: %w", inputs[i].rec.Name, err)
+		}
+	}
+	return fetchShare, nil
+}
+
+// encodeExport trims each input to its part of the range and joins the parts
+// into output, with silence in place of the gaps, reporting how much of the
+// result has been written. formatArgs are added after the output format.
+func (m *Manager) encodeExport(ctx context.Context, inputs []exportInput, gaps []ExportGap, output string, codec types.Codec, formatArgs []string, progress func(time.Duration)) error {
+	args := []string{"-hide_banner", "-loglevel", "error", "-nostdin"}
+	format := fmt.Sprintf("aformat=sample_rates=%d:channel_layouts=stereo", audio.SampleRate)
+	var filter, parts strings.Builder
+	// addGaps adds the silence of the gaps starting before t, or of all
+	// remaining gaps when t is zero
+	gap := 0
+	addGaps := func(t time.Time) {
+		for ; gap < len(gaps) && (t.IsZero() || gaps[gap].Start.Before(t)); gap++ {
+			fmt.Fprintf(&filter, "anullsrc=r=%d:cl=stereo,atrim=duration=%.3f,%s[g%d];",
+				audio.SampleRate, gaps[gap].End.Sub(gaps[gap].Start).Seconds(), format, gap)
+			fmt.Fprintf(&parts, "[g%d]", gap)
+		}
+	}
+	for i, in := range inputs {
+		addGaps(in.rec.Start.Add(secondsDuration(in.from)))
+		args = append(args, "-i", in.path)
+		fmt.Fprintf(&filter, "[%d:a]atrim=start=%.3f:end=%.3f,asetpts=PTS-STARTPTS,%s[a%d];", i, in.from, in.to, format, i)
+		fmt.Fprintf(&parts, "[a%d]", i)
+	}
+	addGaps(time.Time{})
+	fmt.Fprintf(&filter, "%sconcat=n=%d:v=0:a=1[out]", parts.String(), len(inputs)+len(gaps))
+
+	args = append(args,
+		"-filter_complex", filter.String(),
+		"-map", "[out]",
+		"-c:a")
+	args = append(args, codec.Args()...)
+	args = append(args, "-f", codec.FileFormat())
+	args = append(args, formatArgs...)
+	args = append(args,
+		"-progress", "pipe:1",
+		"-nostats",
+		"-y",
+		output,
+	)
+
+	cmd := exec.CommandContext(ctx, m.ffmpegPath, args...) //nolint:gosec // ffmpegPath is from internal config
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("ffmpeg stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start ffmpeg: %w", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "out_time_us=")
+		if !ok {
+			continue
+		}
+		if us, err := strconv.ParseInt(value, 10, 64); err == nil {
+			progress(time.Duration(us) * time.Microsecond)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			return cause
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("ffmpeg: %s", msg)
+		}
+		return fmt.Errorf("ffmpeg: %w", err)
+	}
+	return nil
+}
+
+// updateExport changes the public state of a job under the exports lock.
+func (m *Manager) updateExport(job *exportJob, update func(*Export)) {
+	m.exportsMu.Lock()
+	defer m.exportsMu.Unlock()
+	update(&job.Export)
+}
+
+// exportInputs returns the recordings that overlap start to end, oldest
+// first, each with the part of it inside the range. A file ends where the
+// next one starts, or at its recorded end when it is the last.
+func exportInputs(recordings []Recording, start, end time.Time) []exportInput {
+	recordings = slices.Clone(recordings)
+	slices.SortFunc(recordings, func(a, b Recording) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	var inputs []exportInput
+	for i, rec := range recordings {
+		fileEnd := rec.End
+		if i+1 < len(recordings) && (fileEnd.IsZero() || recordings[i+1].Start.Before(fileEnd)) {
+			fileEnd = recordings[i+1].Start
+		}
+		if !rec.Start.Before(end) || (!fileEnd.IsZero() && !fileEnd.After(start)) {
+			continue
+		}
+		to := end
+		if !fileEnd.IsZero() && fileEnd.Before(to) {
+			to = fileEnd
+		}
+		inputs = append(inputs, exportInput{
+			rec:  rec,
+			from: max(0, start.Sub(rec.Start).Seconds()),
+			to:   to.Sub(rec.Start).Seconds(),
+		})
+	}
+	return inputs
+}
+
+// exportGaps returns the stretches of start to end that inputs leave
+// uncovered, oldest first.
+func exportGaps(inputs []exportInput, start, end time.Time) []ExportGap {
+	var gaps []ExportGap
+	covered := start
+	for _, in := range inputs {
+		inStart := in.rec.Start.Add(secondsDuration(in.from))
+		if inStart.Sub(covered) >= exportMinGap {
+			gaps = append(gaps, ExportGap{Start: covered, End: inStart})
+		}
+		covered = later(covered, in.rec.Start.Add(secondsDuration(in.to)))
+	}
+	if end.Sub(covered) >= exportMinGap {
+		gaps = append(gaps, ExportGap{Start: covered, End: end})
+	}
+	return gaps
+}
+
+// secondsDuration converts seconds to a duration.
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// exactSpans returns the recordings of inputs with the start and end from
+// their sidecars, for the files that have one.
+func exactSpans(ctx context.Context, recorder *GenericRecorder, inputs []exportInput) []Recording {
+	recordings := make([]Recording, len(inputs))
+	for i, in := range inputs {
+		recordings[i] = in.rec
+		if in.rec.Sidecar == "" {
+			continue
+		}
+		sidecar, err := recorder.readSidecar(ctx, in.rec.Sidecar)
+		if err != nil {
+			slog.Warn("failed to read sidecar, using file name", "id", recorder.ID(), "sidecar", in.rec.Sidecar, "error", err)
+			continue
+		}
+		recordings[i].Start, recordings[i].End = sidecar.Start, sidecar.End
+	}
+	return recordings
+}
+
+// copyToFile writes src to a new file at path, reporting each chunk written.
+func copyToFile(path string, src io.Reader, written func(int64)) error {
+	dst, err := os.Create(path) //nolint:gosec // Path is inside the export directory
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 1<<20)
+	for {
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			if _, err := dst.Write(buf[:n]); err != nil {
+				_ = dst.Close() //nolint:errcheck // Already failing
+				return err
+			}
+			written(int64(n))
+		}
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			_ = dst.Close() //nolint:errcheck // Already failing
+			return readErr
+		}
+	}
+	return dst.Close()
+}
+
+// removeExportDir removes the fetched files and the result of a job.
+func removeExportDir(job *exportJob) {
+	if err := os.RemoveAll(job.dir); err != nil {
+		slog.Warn("failed to remove export directory", "id", job.ID, "error", err)
+	}
+}
+
+// generateExportID returns a random ID for an export job.
+func generateExportID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", b), nil
+}
@@ -0,0 +1,386 @@
+package recording
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/oszuidwest/zwfm-encoder/internal/types"
+)
+
+// presignExpiry is how long a presigned download URL stays valid.
+const presignExpiry = 15 * time.Minute
+
+// UploadState describes where a recording is in the upload to S3.
+type UploadState string
+
+// Upload states of a recording.
+const (
+	// UploadNone is the state of recordings of local-only recorders.
+	UploadNone UploadState = ""
+	// UploadRecording is the state of the file still being written.
+	UploadRecording UploadState = "recording"
+	// UploadPending is the state of a finished file waiting for its upload.
+	UploadPending UploadState = "pending"
+	// UploadRetrying is the state of a file whose upload failed and is retried.
+	UploadRetrying UploadState = "retrying"
+	// UploadDone is the state of a file stored in S3.
+	UploadDone UploadState = "uploaded"
+)
+
+// Recording is a file written by a recorder. End is the last write of the
+// local copy, or the end of the rotation period for files only in S3.
+type Recording struct {
+	Name     string      `json:"name"`
+	Size     int64       `json:"size"`
+	Start    time.Time   `json:"start"`
+	End      time.Time   `json:"end,omitzero"`
+	Local    bool        `json:"local"`
+	S3       bool        `json:"s3"`
+	Upload   UploadState `json:"upload,omitempty"`
+	CueSheet string      `json:"cue_sheet,omitempty"` // Name of the CUE sheet of the file, if any
+	Sidecar  string      `json:"sidecar,omitempty"`   // Name of the JSON metadata sidecar of the file, if any
+}
+
+// Recordings lists the files of a recorder on disk and in S3, newest first.
+func (m *Manager) Recordings(ctx context.Context, id string) ([]Recording, error) {
+	recorder, err := m.recorder(id)
+	if err != nil {
+		return nil, err
+	}
+	return recorder.recordings(ctx)
+}
+
+// OpenRecording opens a file of a recorder for reading. The local copy is
+// preferred; files only in S3 are read with ranged requests as they are read.
+func (m *Manager) OpenRecording(ctx context.Context, id, name string) (io.ReadSeekCloser, time.Time, error) {
+	recorder, err := m.recorder(id)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return recorder.openRecording(ctx, name)
+}
+
+// PresignRecording returns a time-limited URL to download a file of a
+// recorder directly from S3.
+func (m *Manager) PresignRecording(ctx context.Context, id, name string) (string, error) {
+	recorder, err := m.recorder(id)
+	if err != nil {
+		return "", err
+	}
+	return recorder.presignRecording(ctx, name)
+}
+
+// recorder returns the recorder with the given ID.
+func (m *Manager) recorder(id string) (*GenericRecorder, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	recorder, exists := m.recorders[id]
+	if !exists {
+		return nil, fmt.Errorf("recorder not found: %s", id)
+	}
+	return recorder, nil
+}
+
+// ContentType returns the content type of a recording by its extension.
+func ContentType(name string) string {
+	switch filepath.Ext(name) {
+	case ".ogg":
+		return "audio/ogg"
+	case ".wav":
+		return "audio/wav"
+	case ".mkv":
+		return "audio/x-matroska" // WAV recordings of earlier versions
+	case ".cue":
+		return cueContentType
+	case ".json":
+		return sidecarContentType
+	default:
+		return "audio/mpeg"
+	}
+}
+
+func (r *GenericRecorder) recordings(ctx context.Context) ([]Recording, error) {
+	r.mu.RLock()
+	cfg := r.config
+	dir := r.outputDirLocked()
+	current := filepath.Base(r.currentFile)
+	next := filepath.Base(r.nextFile) // Started ahead of a rotation, still empty
+	retrying := make(map[string]bool, len(r.retryQueue))
+	for _, p := range r.retryQueue {
+		retrying[filepath.Base(p.request.localPath)] = true
+	}
+	r.mu.RUnlock()
+
+	safeName := sanitizeFilename(cfg.Name)
+	files := make(map[string]*Recording)
+
+	// Local copies, including files waiting for their upload in S3-only mode
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("read recordings directory: %w", err)
+	}
+	for _, entry := range entries {
+		start, ok := recordingTime(safeName, entry.Name())
+		if entry.IsDir() || !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files[entry.Name()] = &Recording{Name: entry.Name(), Size: info.Size(), Start: start, End: info.ModTime(), Local: true}
+	}
+
+	if cfg.StorageMode != types.StorageLocal {
+		if err := r.listS3Recordings(ctx, safeName, files); err != nil {
+			return nil, err
+		}
+	}
+
+	recordings := make([]Recording, 0, len(files))
+	for name, rec := range files {
+		if ext := filepath.Ext(name); ext == ".cue" || ext == ".json" || name == next {
+			continue
+		}
+		if cue := cuePath(name); files[cue] != nil {
+			rec.CueSheet = cue
+		}
+		if sidecar := sidecarPath(name); files[sidecar] != nil {
+			rec.Sidecar = sidecar
+		}
+		if rec.End.IsZero() {
+			_, rec.End = rotationPeriod(&cfg, rec.Start)
+		}
+		switch {
+		case name == current:
+			rec.Upload = UploadRecording
+		case cfg.StorageMode == types.StorageLocal:
+			rec.Upload = UploadNone
+		case rec.S3:
+			rec.Upload = UploadDone
+		case retrying[name]:
+			rec.Upload = UploadRetrying
+		default:
+			rec.Upload = UploadPending
+		}
+		recordings = append(recordings, *rec)
+	}
+
+	slices.SortFunc(recordings, func(a, b Recording) int {
+		return cmp.Or(b.Start.Compare(a.Start), cmp.Compare(a.Name, b.Name))
+	})
+	return recordings, nil
+}
+
+// listS3Recordings adds the objects of the recorder in S3 to files.
+func (r *GenericRecorder) listS3Recordings(ctx context.Context, safeName string, files map[string]*Recording) error {
+	client, err := r.getOrCreateS3Client()
+	if err != nil {
+		return fmt.Errorf("create S3 client: %w", err)
+	}
+	if client == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	bucket := r.config.S3Bucket
+	prefix := r.generateS3Key("")
+	r.mu.RUnlock()
+
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("list S3 objects: %w", err)
+		}
+		for _, obj := range page.Contents {
+			name := filepath.Base(aws.ToString(obj.Key))
+			start, ok := recordingTime(safeName, name)
+			if !ok {
+				continue
+			}
+			if rec := files[name]; rec != nil {
+				rec.S3 = true
+				continue
+			}
+			files[name] = &Recording{Name: name, Size: aws.ToInt64(obj.Size), Start: start, S3: true}
+		}
+	}
+	return nil
+}
+
+func (r *GenericRecorder) openRecording(ctx context.Context, name string) (io.ReadSeekCloser, time.Time, error) {
+	if !r.isRecordingName(name) {
+		return nil, time.Time{}, ErrRecordingNotFound
+	}
+
+	r.mu.RLock()
+	path := filepath.Join(r.outputDirLocked(), name)
+	storageMode := r.config.StorageMode
+	r.mu.RUnlock()
+
+	file, err := os.Open(path) //nolint:gosec // Name is checked to be a file of this recorder
+	if err == nil {
+		info, err := file.Stat()
+		if err != nil {
+			_ = file.Close() //nolint:errcheck // Already failing
+			return nil, time.Time{}, err
+		}
+		return file, info.ModTime(), nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, time.Time{}, err
+	}
+	if storageMode == types.StorageLocal {
+		return nil, time.Time{}, ErrRecordingNotFound
+	}
+
+	client, bucket, key, err := r.s3Object(name)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	head, err := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, time.Time{}, errors.Join(ErrRecordingNotFound, err)
+	}
+	return &s3Reader{
+		ctx:    ctx,
+		client: client,
+		bucket: bucket,
+		key:    key,
+		size:   aws.ToInt64(head.ContentLength),
+	}, aws.ToTime(head.LastModified), nil
+}
+
+func (r *GenericRecorder) presignRecording(ctx context.Context, name string) (string, error) {
+	if !r.isRecordingName(name) {
+		return "", ErrRecordingNotFound
+	}
+	client, bucket, key, err := r.s3Object(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}); err != nil {
+		return "", errors.Join(ErrRecordingNotFound, err)
+	}
+
+	req, err := s3.NewPresignClient(client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(presignExpiry))
+	if err != nil {
+		return "", fmt.Errorf("presign S3 object: %w", err)
+	}
+	return req.URL, nil
+}
+
+// isRecordingName reports whether name is a file of this recorder, which
+// also keeps it from leaving the recordings directory.
+func (r *GenericRecorder) isRecordingName(name string) bool {
+	r.mu.RLock()
+	safeName := sanitizeFilename(r.config.Name)
+	r.mu.RUnlock()
+
+	_, ok := recordingTime(safeName, name)
+	return ok && filepath.Base(name) == name
+}
+
+// s3Object returns the client, bucket and key of a file of this recorder in S3.
+func (r *GenericRecorder) s3Object(name string) (*s3.Client, string, string, error) {
+	client, err := r.getOrCreateS3Client()
+	if err != nil {
+		return nil, "", "", fmt.Errorf("create S3 client: %w", err)
+	}
+	if client == nil {
+		return nil, "", "", ErrRecordingNotFound
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return client, r.config.S3Bucket, r.generateS3Key(name), nil
+}
+
+// s3Reader reads an S3 object with ranged requests, opening a new request
+// from the current offset after each seek.
+type s3Reader struct {
+	ctx    context.Context //nolint:containedctx // Bound to one HTTP request
+	client *s3.Client
+	bucket string
+	key    string
+	size   int64
+
+	offset int64
+	body   io.ReadCloser
+}
+
+func (s *s3Reader) Read(p []byte) (int, error) {
+	if s.offset >= s.size {
+		return 0, io.EOF
+	}
+	if s.body == nil {
+		out, err := s.client.GetObject(s.ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(s.key),
+			Range:  aws.String(fmt.Sprintf("bytes=%d-", s.offset)),
+		})
+		if err != nil {
+			return 0, fmt.Errorf("get S3 object: %w", err)
+		}
+		s.body = out.Body
+	}
+
+	n, err := s.body.Read(p)
+	s.offset += int64(n)
+	if errors.Is(err, io.EOF) && s.offset < s.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (s *s3Reader) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = s.offset + offset
+	case io.SeekEnd:
+		target = s.size + offset
+	default:
+		return 0, fmt.Errorf("seek: invalid whence %d", whence)
+	}
+	if target < 0 {
+		return 0, fmt.Errorf("seek: negative offset %d", target)
+	}
+	if target != s.offset {
+		s.closeBody()
+		s.offset = target
+	}
+	return target, nil
+}
+
+func (s *s3Reader) Close() error {
+	s.closeBody()
+	return nil
+}
+
+func (s *s3Reader) closeBody() {
+	if s.body != nil {
+		_ = s.body.Close() //nolint:errcheck // Nothing left to read
+		s.body = nil
+	}
+}
@@ -0,0 +1,131 @@
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// legacyTempDir is where earlier versions kept the files of S3-only recorders
+// waiting for their upload, for all programmes together.
+const legacyTempDir = "/tmp/encoder-recordings"
+
+// adoptLegacyFiles moves the files a recorder left in legacyTempDir into its
+// directory under tempDir and merges its upload journal, so that its uploads
+// resume. Files that already exist in tempDir are left where they are.
+func adoptLegacyFiles(tempDir, id string) {
+	src := filepath.Join(legacyTempDir, "recorders", id)
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("failed to read legacy recording directory", "id", id, "dir", src, "error", err)
+		}
+		return
+	}
+	dst := filepath.Join(tempDir, "recorders", id)
+	if filepath.Clean(src) == filepath.Clean(dst) {
+		return
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil { //nolint:gosec // Temp directory needs to be readable
+		slog.Warn("failed to create recording directory", "id", id, "dir", dst, "error", err)
+		return
+	}
+
+	moved := 0
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || entry.Name() == journalName {
+			continue
+		}
+		from, to := filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())
+		if _, err := os.Lstat(to); err == nil {
+			slog.Warn("recording exists in both directories, keeping the legacy copy", "id", id, "file", from)
+			continue
+		}
+		if err := moveFile(from, to); err != nil {
+			slog.Warn("failed to move legacy recording", "id", id, "file", from, "error", err)
+			continue
+		}
+		moved++
+	}
+
+	if err := mergeJournal(filepath.Join(src, journalName), filepath.Join(dst, journalName)); err != nil {
+		slog.Warn("failed to merge legacy upload journal", "id", id, "dir", src, "error", err)
+	}
+
+	// Removes the directory only once everything has moved
+	if err := os.Remove(src); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("failed to remove legacy recording directory", "id", id, "dir", src, "error", err)
+	}
+	slog.Info("moved recordings from legacy directory", "id", id, "from", src, "to", dst, "files", moved)
+}
+
+// mergeJournal adds the entries of the journal at from to the journal at to
+// and removes the first.
+func mergeJournal(from, to string) error {
+	legacy, err := readJournal(from)
+	if err != nil || len(legacy) == 0 {
+		return err
+	}
+	entries, err := readJournal(to)
+	if err != nil {
+		return err
+	}
+	for _, e := range legacy {
+		if !slices.ContainsFunc(entries, func(c journalEntry) bool { return c.File == e.File }) {
+			entries = append(entries, e)
+		}
+	}
+	if err := writeJournal(to, entries); err != nil {
+		return err
+	}
+	return os.Remove(from)
+}
+
+// moveFile moves a file, copying it when the destination is on another file
+// system, as /tmp often is. The modification time is kept, as the upload scan
+// selects recent recordings by it.
+func moveFile(from, to string) error {
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	info, err := os.Stat(from)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(from) //nolint:gosec // Path is inside the legacy temp directory
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }() //nolint:errcheck // Read-only
+
+	part := to + ".part"
+	out, err := os.OpenFile(part, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644) //nolint:gosec // Recordings are not secret
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(part) //nolint:errcheck // Partial copy
+		return fmt.Errorf("copy: %w", err)
+	}
+	if err := os.Chtimes(part, info.ModTime(), info.ModTime()); err != nil {
+		slog.Warn("failed to keep modification time", "file", to, "error", err)
+	}
+	if err := os.Rename(part, to); err != nil {
+		_ = os.Remove(part) //nolint:errcheck // Copy is not used
+		return err
+	}
+	return os.Remove(from)
+}
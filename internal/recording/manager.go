@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/oszuidwest/zwfm-encoder/internal/audio"
 	"github.com/oszuidwest/zwfm-encoder/internal/eventlog"
+	"github.com/oszuidwest/zwfm-encoder/internal/metadata"
 	"github.com/oszuidwest/zwfm-encoder/internal/types"
 	"github.com/oszuidwest/zwfm-encoder/internal/util"
 )
@@ -20,38 +23,64 @@ type Manager struct {
 	recorders          map[string]*GenericRecorder
 	tempDir            string
 	ffmpegPath         string
-	maxDurationMinutes int  // Global max duration for on-demand recorders
-	running            bool // Whether encoder is running (recorders should be active)
+	stationName        string // Originator of Broadcast WAV files
+	maxDurationMinutes int    // Global max duration for on-demand recorders
+	preRollSeconds     int    // Audio before the start of on-demand recordings
+	running            bool   // Whether encoder is running (recorders should be active)
 	eventLogger        *eventlog.Logger
+	nowPlaying         metadata.NowPlaying // Passed to recorders added later
 
 	cleanupStopCh     chan struct{} // Stop signal for cleanup scheduler
 	hourlyRetryStopCh chan struct{} // Stop signal for hourly retry scheduler
-}
+	showStopCh        chan struct{} // Stop signal for show scheduler
+	coverageStopCh    chan struct{} // Stop signal for coverage checker
 
-// NewManager creates a new recording manager.
-func NewManager(ffmpegPath, tempDir string, maxDurationMinutes int, eventLogger *eventlog.Logger) (*Manager, error) {
-	if tempDir == "" {
-		tempDir = DefaultTempDir
-	}
+	coverageFrom time.Time            // End of the time checked for gaps
+	lastGapEnd   map[string]time.Time // End of the last gap reported per recorder
+	gapHandler   GapHandler           // Notified of new gaps; nil if unset
 
+	exportsMu sync.Mutex
+	exports   map[string]*exportJob // Export jobs by ID
+	exportSem chan struct{}         // Lets one export job run at a time
+}
+
+// NewManager creates a new recording manager that keeps files waiting for
+// their upload, and exports, in tempDir.
+func NewManager(ffmpegPath, tempDir, stationName string, maxDurationMinutes, preRollSeconds int, eventLogger *eventlog.Logger) (*Manager, error) {
 	// Ensure temp directory exists
 	if err := os.MkdirAll(tempDir, 0o755); err != nil { //nolint:gosec // Temp directory needs to be readable
 		return nil, fmt.Errorf("create temp directory: %w", err)
 	}
 
+	// Export jobs do not survive a restart, so neither do their files
+	if err := os.RemoveAll(filepath.Join(tempDir, "exports")); err != nil {
+		slog.Warn("failed to remove old exports", "error", err)
+	}
+
 	return &Manager{
 		recorders:          make(map[string]*GenericRecorder),
 		tempDir:            tempDir,
 		ffmpegPath:         ffmpegPath,
+		stationName:        stationName,
 		maxDurationMinutes: maxDurationMinutes,
+		preRollSeconds:     preRollSeconds,
 		eventLogger:        eventLogger,
 		cleanupStopCh:      make(chan struct{}),
 		hourlyRetryStopCh:  make(chan struct{}),
+		showStopCh:         make(chan struct{}),
+		coverageStopCh:     make(chan struct{}),
+		lastGapEnd:         make(map[string]time.Time),
+		exports:            make(map[string]*exportJob),
+		exportSem:          make(chan struct{}, 1),
 	}, nil
 }
 
 // AddRecorder adds a recorder to the manager.
 func (m *Manager) AddRecorder(cfg *types.Recorder) error {
+	// Files an earlier version left in the system temp directory are uploaded from here.
+	// Moving them may copy, so it happens outside the lock.
+	adoptLegacyFiles(m.tempDir, cfg.ID)
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -59,20 +88,26 @@ func (m *Manager) AddRecorder(cfg *types.Recorder) error {
 		return fmt.Errorf("recorder already exists: %s", cfg.ID)
 	}
 
-	recorder, err := NewGenericRecorder(cfg, m.ffmpegPath, m.tempDir, m.maxDurationMinutes, m.eventLogger)
+	recorder, err := NewGenericRecorder(cfg, m.ffmpegPath, m.tempDir, m.stationName, m.maxDurationMinutes, m.preRollSeconds, m.eventLogger)
 	if err != nil {
 		return fmt.Errorf("create recorder: %w", err)
 	}
 
 	m.recorders[cfg.ID] = recorder
+	if !m.nowPlaying.IsZero() {
+		recorder.SetNowPlaying(m.nowPlaying)
+	}
 
-	// Auto-start hourly recorders if encoder is running (ondemand never auto-starts)
-	if m.running && cfg.RotationMode == types.RotationHourly && cfg.IsEnabled() {
+	// Auto-start recorders if encoder is running (ondemand never auto-starts)
+	if m.running && cfg.RotationMode.AutoStarts() && cfg.IsEnabled() {
 		if err := recorder.Start(); err != nil {
 			slog.Warn("failed to auto-start recorder", "id", cfg.ID, "error", err)
 		}
 	}
 
+	// Uploads interrupted by a restart continue in the background
+	go recorder.resumeUploads()
+
 	slog.Info("recorder added", "id", cfg.ID, "name", cfg.Name)
 	return nil
 }
@@ -93,6 +128,7 @@ func (m *Manager) RemoveRecorder(id string) error {
 	}
 
 	delete(m.recorders, id)
+	delete(m.lastGapEnd, id)
 	slog.Info("recorder removed", "id", id)
 	return nil
 }
@@ -121,8 +157,8 @@ func (m *Manager) StartRecorder(id string) error {
 	}
 
 	// Only on-demand recorders can be started via API
-	if recorder.Config().RotationMode == types.RotationHourly {
-		return ErrHourlyRecorderNotControllable
+	if recorder.Config().RotationMode.AutoStarts() {
+		return ErrRecorderNotControllable
 	}
 
 	// Check if already recording
@@ -144,8 +180,8 @@ func (m *Manager) StopRecorder(id string) error {
 	}
 
 	// Only on-demand recorders can be stopped via API
-	if recorder.Config().RotationMode == types.RotationHourly {
-		return ErrHourlyRecorderNotControllable
+	if recorder.Config().RotationMode.AutoStarts() {
+		return ErrRecorderNotControllable
 	}
 
 	// Check if not recording
@@ -156,17 +192,17 @@ func (m *Manager) StopRecorder(id string) error {
 	return recorder.Stop()
 }
 
-// Start begins recorder management, starting hourly recorders and cleanup schedulers.
+// Start begins recorder management, starting auto-start recorders and cleanup schedulers.
 func (m *Manager) Start() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.running = true
 
-	// Start hourly recorders (ondemand never auto-starts)
+	// Start auto-start recorders (ondemand never auto-starts)
 	for id, recorder := range m.recorders {
 		cfg := recorder.Config()
-		if cfg.RotationMode == types.RotationHourly && cfg.IsEnabled() {
+		if cfg.RotationMode.AutoStarts() && cfg.IsEnabled() {
 			if err := recorder.Start(); err != nil {
 				slog.Warn("failed to auto-start recorder", "id", id, "error", err)
 			}
@@ -179,6 +215,12 @@ func (m *Manager) Start() error {
 	// Start hourly retry scheduler for failed recorders
 	m.startHourlyRetryScheduler()
 
+	// Start scheduler for recorder schedules
+	m.startShowScheduler()
+
+	// Start checking the archive for gaps
+	m.startCoverageScheduler()
+
 	slog.Info("recording manager started", "recorders", len(m.recorders))
 	return nil
 }
@@ -198,6 +240,14 @@ func (m *Manager) Stop() error {
 	close(m.hourlyRetryStopCh)
 	m.hourlyRetryStopCh = make(chan struct{}) // Reset for potential restart
 
+	// Stop show scheduler
+	close(m.showStopCh)
+	m.showStopCh = make(chan struct{}) // Reset for potential restart
+
+	// Stop coverage checker
+	close(m.coverageStopCh)
+	m.coverageStopCh = make(chan struct{}) // Reset for potential restart
+
 	var errs []error
 	for id, recorder := range m.recorders {
 		if recorder.IsRecording() {
@@ -212,14 +262,14 @@ func (m *Manager) Stop() error {
 	return errors.Join(errs...)
 }
 
-// WriteAudio writes PCM audio to any active recorders.
-func (m *Manager) WriteAudio(pcm []byte) error {
+// WriteAudio writes each active recorder its routed and trimmed channels from the captured frame.
+func (m *Manager) WriteAudio(frame *audio.Frame) error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	for _, recorder := range m.recorders {
-		if recorder.IsRecording() {
-			if err := recorder.WriteAudio(pcm); err != nil {
+		if recorder.WantsAudio() {
+			if err := recorder.WriteAudio(frame.Output(recorder.Route(), recorder.TrimDB())); err != nil {
 				slog.Warn("recorder write error", "id", recorder.ID(), "error", err)
 			}
 		}
@@ -228,6 +278,48 @@ func (m *Manager) WriteAudio(pcm []byte) error {
 	return nil
 }
 
+// SetGapHandler sets the function notified of gaps the coverage checker finds.
+func (m *Manager) SetGapHandler(handler GapHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gapHandler = handler
+}
+
+// SetNowPlaying passes a new item on air to all recorders for their cue sheets.
+func (m *Manager) SetNowPlaying(np metadata.NowPlaying) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nowPlaying = np
+	for _, recorder := range m.recorders {
+		recorder.SetNowPlaying(np)
+	}
+}
+
+// SetSilence passes the silence detector state of each recorder's channels
+// to the recorder, for the sidecars of its files.
+func (m *Manager) SetSilence(levels *audio.AudioLevels) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	for _, recorder := range m.recorders {
+		state := levels
+		if route := recorder.Route(); !route.IsDefault() {
+			routeLevels, ok := levels.Routes[route.Key()]
+			if !ok {
+				continue
+			}
+			state = &routeLevels
+		}
+		since := now
+		if state.Silence {
+			since = now.Add(-time.Duration(state.SilenceDurationMs) * time.Millisecond)
+		}
+		recorder.SetSilence(state.Silence, since)
+	}
+}
+
 // Statuses returns the current status of all recorders.
 func (m *Manager) Statuses() map[string]types.ProcessStatus {
 	m.mu.RLock()
@@ -240,7 +332,8 @@ func (m *Manager) Statuses() map[string]types.ProcessStatus {
 	return statuses
 }
 
-// startHourlyRetryScheduler retries failed hourly recorders at each hour boundary.
+// startHourlyRetryScheduler retries failed auto-start recorders and pending
+// uploads at each hour boundary.
 func (m *Manager) startHourlyRetryScheduler() {
 	go func() {
 		for {
@@ -249,25 +342,28 @@ func (m *Manager) startHourlyRetryScheduler() {
 			case <-m.hourlyRetryStopCh:
 				return
 			case <-time.After(duration):
-				m.retryFailedHourlyRecorders()
+				m.retryFailedRecorders()
 			}
 		}
 	}()
 }
 
-func (m *Manager) retryFailedHourlyRecorders() {
+func (m *Manager) retryFailedRecorders() {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	for _, recorder := range m.recorders {
+		// Stopped and on-demand recorders do not rotate, so retry their uploads here
+		go recorder.processRetryQueue()
+
 		cfg := recorder.Config()
-		if cfg.RotationMode == types.RotationHourly && cfg.IsEnabled() {
+		if cfg.RotationMode.AutoStarts() && cfg.IsEnabled() {
 			status := recorder.Status()
 			if status.State == types.ProcessError {
-				slog.Info("retrying failed hourly recorder at hour boundary", "id", cfg.ID, "name", cfg.Name)
+				slog.Info("retrying failed recorder at hour boundary", "id", cfg.ID, "name", cfg.Name)
 				go func(r *GenericRecorder) {
 					if err := r.Start(); err != nil {
-						slog.Warn("failed to retry hourly recorder", "id", r.ID(), "error", err)
+						slog.Warn("failed to retry recorder", "id", r.ID(), "error", err)
 					}
 				}(recorder)
 			}
@@ -0,0 +1,139 @@
+package recording
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/audio"
+)
+
+// preRollGap is how long audio may stop before the buffered audio is
+// discarded, so a recording never starts with audio from before an outage or
+// an encoder restart.
+const preRollGap = time.Second
+
+// preRoll keeps the last seconds of a recorder's audio while it is not
+// recording, so a recording can start with the audio before it was started.
+//
+// While the buffered audio is written to the new file, live audio queues
+// behind it; the recorder only writes live audio directly once the queue is
+// empty, which keeps the file contiguous.
+type preRoll struct {
+	mu sync.Mutex
+
+	capacity int    // Bytes; zero disables the pre-roll
+	enabled  bool   // Whether the recorder buffers (on-demand mode only)
+	buffer   []byte // Ring buffer, allocated on first use
+	writePos int
+	filled   int
+	lastAt   time.Time // When audio was last buffered
+
+	recording bool     // Audio goes to the file instead of the ring
+	flushing  bool     // Buffered audio is still being written to the file
+	backlog   [][]byte // Live audio received while flushing
+}
+
+// newPreRoll creates a pre-roll of the given length in seconds.
+func newPreRoll(seconds int) *preRoll {
+	return &preRoll{capacity: seconds * audio.SampleRate * frameBytes}
+}
+
+// setEnabled turns buffering on or off. Turning it off frees the buffer.
+func (p *preRoll) setEnabled(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.enabled = enabled && p.capacity > 0
+	if !p.enabled {
+		p.buffer = nil
+		p.writePos, p.filled = 0, 0
+	}
+}
+
+// wants reports whether the recorder needs audio while it is not recording.
+func (p *preRoll) wants() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.enabled && !p.recording
+}
+
+// hold buffers pcm when the recorder is not recording, or queues it while
+// the buffered audio is being written. It reports false when pcm should be
+// written to the file directly.
+func (p *preRoll) hold(pcm []byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case p.flushing:
+		p.backlog = append(p.backlog, append([]byte(nil), pcm...))
+		return true
+	case p.recording || !p.enabled:
+		return false
+	}
+
+	if p.buffer == nil {
+		p.buffer = make([]byte, p.capacity)
+	}
+	now := time.Now()
+	if now.Sub(p.lastAt) > preRollGap {
+		p.writePos, p.filled = 0, 0
+	}
+	p.lastAt = now
+	// Only the tail of a chunk larger than the ring survives
+	if len(pcm) > p.capacity {
+		pcm = pcm[len(pcm)-p.capacity:]
+	}
+	n := copy(p.buffer[p.writePos:], pcm)
+	copy(p.buffer, pcm[n:])
+	p.writePos = (p.writePos + len(pcm)) % p.capacity
+	p.filled = min(p.filled+len(pcm), p.capacity)
+	return true
+}
+
+// begin marks the start of a recording and returns the buffered audio,
+// oldest first. When it is not empty, live audio is queued until next
+// reports the queue is drained.
+func (p *preRoll) begin() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.recording = true
+	if p.filled == 0 || time.Since(p.lastAt) > preRollGap {
+		p.writePos, p.filled = 0, 0
+		return nil
+	}
+
+	pcm := make([]byte, p.filled)
+	start := (p.writePos - p.filled + p.capacity) % p.capacity
+	n := copy(pcm, p.buffer[start:min(start+p.filled, p.capacity)])
+	copy(pcm[n:], p.buffer)
+	p.writePos, p.filled = 0, 0
+	p.flushing = true
+	return pcm
+}
+
+// next returns the live audio queued since the last call. When there is
+// none, live audio is written directly from then on.
+func (p *preRoll) next() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var pcm []byte
+	for _, chunk := range p.backlog {
+		pcm = append(pcm, chunk...)
+	}
+	p.backlog = nil
+	p.flushing = len(pcm) > 0
+	return pcm
+}
+
+// end marks the end of a recording, so audio is buffered again.
+func (p *preRoll) end() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.recording = false
+	p.flushing = false
+	p.backlog = nil
+}
@@ -4,18 +4,32 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/oszuidwest/zwfm-encoder/internal/audio"
 	"github.com/oszuidwest/zwfm-encoder/internal/eventlog"
 	"github.com/oszuidwest/zwfm-encoder/internal/ffmpeg"
+	"github.com/oszuidwest/zwfm-encoder/internal/metadata"
 	"github.com/oszuidwest/zwfm-encoder/internal/types"
 	"github.com/oszuidwest/zwfm-encoder/internal/util"
 )
 
+const (
+	// frameBytes is the size of one stereo 16-bit PCM frame as recorders receive it.
+	frameBytes = audio.Channels * 2
+	// rotationLead is how long before a rotation the encoder of the next file starts.
+	rotationLead = 2 * time.Second
+	// clockResync is how far the arrival of audio may stray from the sample
+	// clock before the clock is reset, as after a gap or stall in the audio.
+	clockResync = 500 * time.Millisecond
+)
+
 // GenericRecorder saves audio to files with optional S3 upload.
 type GenericRecorder struct {
 	mu sync.RWMutex // Protects state, config, file paths
@@ -23,8 +37,10 @@ type GenericRecorder struct {
 	id                 string
 	config             types.Recorder
 	ffmpegPath         string
-	maxDurationMinutes int // For on-demand mode (from global config)
+	stationName        string // Originator of Broadcast WAV files
+	maxDurationMinutes int    // For on-demand mode (from global config)
 	eventLogger        *eventlog.Logger
+	loudness           *audio.AGC
 
 	tempDir   string
 	state     types.ProcessState
@@ -34,13 +50,37 @@ type GenericRecorder struct {
 	result *ffmpeg.StartResult
 
 	// Current recording
+	show        string // Rendered name of the scheduled show; empty for other recordings
 	currentFile string
-	startTime   time.Time
+	startTime   time.Time    // Wall-clock time of the first sample of the file
+	cue         *cueSheet    // Cue sheet of the current file
+	summary     *fileSummary // Sidecar of the current file
+	samples     atomic.Int64 // Frames written since the recorder started
+
+	// Sample clock: frame clockSample was captured at clockRef, and every
+	// other frame follows at the sample rate
+	clockRef    time.Time
+	clockSample int64
+
+	// Next file (rotating modes), switched to on the exact sample at nextRotation
+	nextRotation time.Time
+	next         *ffmpeg.StartResult // Started ahead of the rotation; nil until then
+	nextFile     string
+	finishing    sync.WaitGroup // Files being finalized after a rotation
+
+	preRoll *preRoll // Audio before the start of on-demand recordings
+
+	nowPlaying metadata.NowPlaying // Item on air, for the cue sheet of the next file
+	silent     bool                // Silence detected on the recorder's channels
 
 	// S3 client (cached, recreated when config changes)
 	s3Client    *s3.Client
 	s3ConfigKey string // Config key used to create cached client
 
+	// Object Lock support of the bucket (cached, checked again when config changes)
+	objectLockKey     string
+	objectLockEnabled bool
+
 	// Upload queue
 	uploadQueue         chan uploadRequest
 	uploadWg            sync.WaitGroup
@@ -50,8 +90,12 @@ type GenericRecorder struct {
 
 	// Retry queue for failed uploads (protected by mu)
 	retryQueue []pendingUpload
+	uploading  map[string]bool // Files queued, retrying or being uploaded, by path (protected by mu)
+	journalMu  sync.Mutex      // Serializes upload journal writes
+	chainMu    sync.Mutex      // Serializes links of the hash chain (compliance mode)
+	chainTail  <-chan struct{} // Closed once the sidecar of the newest file is written (protected by mu)
 
-	// Rotation timer (hourly mode)
+	// Rotation timer (rotating modes)
 	rotationTimer *time.Timer
 
 	// Max duration timer (on-demand mode)
@@ -59,18 +103,23 @@ type GenericRecorder struct {
 }
 
 // NewGenericRecorder creates a new recorder instance.
-func NewGenericRecorder(cfg *types.Recorder, ffmpegPath, tempDir string, maxDurationMinutes int, eventLogger *eventlog.Logger) (*GenericRecorder, error) {
+func NewGenericRecorder(cfg *types.Recorder, ffmpegPath, tempDir, stationName string, maxDurationMinutes, preRollSeconds int, eventLogger *eventlog.Logger) (*GenericRecorder, error) {
 	r := &GenericRecorder{
 		id:                 cfg.ID,
 		config:             *cfg,
 		ffmpegPath:         ffmpegPath,
+		stationName:        stationName,
 		maxDurationMinutes: maxDurationMinutes,
 		eventLogger:        eventLogger,
+		loudness:           audio.NewAGC(cfg.Loudness),
 		tempDir:            tempDir,
 		state:              types.ProcessStopped,
 		uploadQueue:        make(chan uploadRequest, 100),
 		uploadStopCh:       make(chan struct{}),
+		uploading:          make(map[string]bool),
+		preRoll:            newPreRoll(preRollSeconds),
 	}
+	r.preRoll.setEnabled(cfg.RotationMode == types.RotationOnDemand && cfg.IsEnabled())
 
 	return r, nil
 }
@@ -87,6 +136,20 @@ func (r *GenericRecorder) Config() types.Recorder {
 	return r.config
 }
 
+// Route returns the input channels this recorder captures.
+func (r *GenericRecorder) Route() audio.Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config.Channels
+}
+
+// TrimDB returns the output level trim of this recorder.
+func (r *GenericRecorder) TrimDB() float64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config.TrimDB
+}
+
 func s3ConfigKeyFrom(cfg *types.Recorder) string {
 	return cfg.S3Endpoint + "|" + cfg.S3AccessKeyID + "|" + cfg.S3SecretAccessKey
 }
@@ -125,6 +188,24 @@ func (r *GenericRecorder) IsCurrentFile(path string) bool {
 
 // Start starts the recorder.
 func (r *GenericRecorder) Start() error {
+	return r.start("")
+}
+
+// StartShow starts a recording of a scheduled show. The show name follows
+// the start time in the file name, and the recording runs until it is
+// stopped, without the maximum duration of on-demand recordings.
+func (r *GenericRecorder) StartShow(name string) error {
+	return r.start(name)
+}
+
+// Show returns the name of the scheduled show being recorded, if any.
+func (r *GenericRecorder) Show() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.show
+}
+
+func (r *GenericRecorder) start(show string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -133,6 +214,7 @@ func (r *GenericRecorder) Start() error {
 	}
 
 	// Clear any previous error and set starting state
+	r.show = show
 	r.lastError = ""
 	r.state = types.ProcessStarting
 
@@ -180,8 +262,11 @@ func (r *GenericRecorder) startAsync() {
 		return
 	}
 
-	// Start FFmpeg encoder
-	if err := r.startEncoderLocked(); err != nil {
+	// Start FFmpeg encoder with the buffered audio, if any, ahead of the live audio
+	pre := r.preRoll.begin()
+	lead := time.Duration(len(pre)/frameBytes) * time.Second / audio.SampleRate
+	if err := r.startEncoderLocked(lead); err != nil {
+		r.preRoll.end()
 		r.state = types.ProcessError
 		r.lastError = err.Error()
 		r.mu.Unlock()
@@ -196,12 +281,7 @@ func (r *GenericRecorder) startAsync() {
 		go r.uploadWorker()
 	}
 
-	// Schedule based on rotation mode
-	if r.config.RotationMode == types.RotationHourly {
-		r.scheduleRotationLocked()
-	} else if r.maxDurationMinutes > 0 {
-		r.scheduleDurationLimitLocked()
-	}
+	r.scheduleTimersLocked()
 
 	r.state = types.ProcessRunning
 
@@ -209,9 +289,15 @@ func (r *GenericRecorder) startAsync() {
 	logParams := r.captureLogParamsLocked()
 	name := r.config.Name
 	mode := r.config.RotationMode
+	result := r.result
+	summary := r.summary
 	r.mu.Unlock()
 
-	slog.Info("recorder started", "id", r.id, "name", name, "mode", mode)
+	if len(pre) > 0 {
+		go r.flushPreRoll(result, summary, pre)
+	}
+
+	slog.Info("recorder started", "id", r.id, "name", name, "mode", mode, "pre_roll", lead)
 	r.logEvent(eventlog.RecorderStarted, logParams)
 }
 
@@ -259,23 +345,21 @@ func (r *GenericRecorder) Stop() error {
 	r.state = types.ProcessStopping
 
 	// Always stop timers (may be running even after write error)
-	if r.rotationTimer != nil {
-		r.rotationTimer.Stop()
-		r.rotationTimer = nil
-	}
-	if r.durationTimer != nil {
-		r.durationTimer.Stop()
-		r.durationTimer = nil
-	}
+	r.stopTimersLocked()
 
 	// Capture result to determine if we need encoder cleanup
 	result := r.result
+	next, nextFile := r.takeNextLocked()
 	r.mu.Unlock()
 
 	// Stop encoder and finalize file (only if encoder is active)
 	if result != nil {
 		r.stopEncoderAndUpload()
 	}
+	discardFile(next, nextFile)
+
+	// Files from earlier rotations must be queued before the worker stops
+	r.finishing.Wait()
 
 	// Always stop upload worker - may be running even after write error
 	r.stopOnce.Do(func() {
@@ -286,16 +370,17 @@ func (r *GenericRecorder) Stop() error {
 	r.mu.Lock()
 	r.state = types.ProcessStopped
 	r.lastError = ""
+	r.show = ""
+	r.preRoll.end()
 	r.uploadStopCh = make(chan struct{})          // Reset for next start
 	r.uploadQueue = make(chan uploadRequest, 100) // Reset for next start
 	r.stopOnce = sync.Once{}                      // Reset Once for next start
 	r.uploadWorkerRunning = false                 // Reset for next start
 
-	// Clear retry queue on shutdown (files remain in temp dir)
+	// Keep the retry queue: pending uploads are journaled and retried hourly
 	if len(r.retryQueue) > 0 {
-		slog.Info("clearing retry queue on stop", "id", r.id, "pending", len(r.retryQueue))
+		slog.Info("uploads pending on stop", "id", r.id, "pending", len(r.retryQueue))
 	}
-	r.retryQueue = nil
 
 	// Capture log params while holding lock
 	logParams := r.captureLogParamsLocked()
@@ -307,26 +392,86 @@ func (r *GenericRecorder) Stop() error {
 	return nil
 }
 
-// WriteAudio writes PCM audio to the recorder.
+// WantsAudio reports whether the recorder records audio or keeps it for the
+// start of a recording.
+func (r *GenericRecorder) WantsAudio() bool {
+	return r.IsRecording() || r.preRoll.wants()
+}
+
+// WriteAudio writes PCM audio to the recorder. Rotations are found on the
+// sample clock, so files are cut on the boundary frame regardless of when
+// the audio arrives.
 func (r *GenericRecorder) WriteAudio(pcm []byte) error {
+	if r.preRoll.hold(pcm) {
+		return nil
+	}
+	now := time.Now()
+
 	r.mu.RLock()
 	state := r.state
 	result := r.result
+	summary := r.summary
+	first := r.samples.Load()
+	frames := int64(len(pcm) / frameBytes)
+	// By the wall clock, the first frame was captured one chunk ago
+	arrived := now.Add(-time.Duration(frames) * time.Second / audio.SampleRate)
+	drift := arrived.Sub(r.frameTimeLocked(first)).Abs()
+	due := r.rotationDueLocked(first + frames)
 	r.mu.RUnlock()
 
-	// Accept both Running and Rotating - rotation closes stdin when ready
-	if state != types.ProcessRunning && state != types.ProcessRotating {
+	if state != types.ProcessRunning || result == nil {
 		return nil
 	}
+	if drift > clockResync {
+		due = r.resyncClock(first, frames, arrived, drift)
+	}
 
-	if result == nil {
-		return nil
+	pcm = r.loudness.Process(pcm)
+	defer r.samples.Add(int64(len(pcm) / frameBytes))
+	if due {
+		return r.rotate(pcm, first)
 	}
+	summary.addAudio(pcm)
+	return r.write(result, pcm)
+}
+
+// frameTimeLocked returns the wall-clock time of frame n of the recording.
+// Must be called with r.mu held.
+func (r *GenericRecorder) frameTimeLocked(n int64) time.Time {
+	return r.clockRef.Add(time.Duration(float64(n-r.clockSample) / audio.SampleRate * float64(time.Second)))
+}
+
+// frameAtLocked returns the frame of the recording captured at t.
+// Must be called with r.mu held.
+func (r *GenericRecorder) frameAtLocked(t time.Time) int64 {
+	return r.clockSample + int64(math.Round(t.Sub(r.clockRef).Seconds()*audio.SampleRate))
+}
+
+// rotationDueLocked reports whether the next rotation falls before frame end.
+// Must be called with r.mu held.
+func (r *GenericRecorder) rotationDueLocked(end int64) bool {
+	return !r.nextRotation.IsZero() && r.frameAtLocked(r.nextRotation) < end
+}
 
+// resyncClock resets the sample clock so frame first was captured at
+// arrived, and reports whether a rotation is due within the chunk of frames.
+func (r *GenericRecorder) resyncClock(first, frames int64, arrived time.Time, drift time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	slog.Info("recorder clock reset after gap in audio", "id", r.id, "drift", drift.Round(time.Millisecond))
+	r.clockRef = arrived
+	r.clockSample = first
+	return r.rotationDueLocked(first + frames)
+}
+
+// write sends PCM to the encoder of the current file. A write error stops
+// the recorder and finalizes the file.
+func (r *GenericRecorder) write(result *ffmpeg.StartResult, pcm []byte) error {
 	// WriteStdin is thread-safe (mutex encapsulated in StartResult)
 	_, err := result.WriteStdin(pcm)
 	if err != nil {
-		// ErrStdinClosed is expected during rotation/shutdown - not a real error
+		// ErrStdinClosed is expected during shutdown - not a real error
 		if errors.Is(err, ffmpeg.ErrStdinClosed) {
 			return nil
 		}
@@ -337,12 +482,19 @@ func (r *GenericRecorder) WriteAudio(pcm []byte) error {
 		r.lastError = err.Error()
 		capturedResult := r.result
 		capturedFile := r.currentFile
+		capturedSidecars := r.takeSidecarsLocked()
+		next, nextFile := r.takeNextLocked()
+		r.stopTimersLocked()
 		r.result = nil
 		r.currentFile = ""
 		r.mu.Unlock()
+		r.preRoll.end()
 
 		// Trigger async cleanup to finalize/upload the current recording
-		go r.cleanupAfterWriteError(capturedResult, capturedFile)
+		go func() {
+			discardFile(next, nextFile)
+			r.cleanupAfterWriteError(capturedResult, capturedFile, capturedSidecars)
+		}()
 
 		return err
 	}
@@ -350,9 +502,121 @@ func (r *GenericRecorder) WriteAudio(pcm []byte) error {
 	return nil
 }
 
+// rotate switches to the next file at the rotation boundary. The frames of
+// pcm from the boundary frame on start the next file and the rest complete
+// the current one: consecutive files are contiguous, without lost or
+// repeated samples. When a gap in the audio has carried the recording past
+// the boundary, the next file starts with the first frame after the gap and
+// belongs to the period of that frame. first is the frame number of pcm[0].
+func (r *GenericRecorder) rotate(pcm []byte, first int64) error {
+	frames := len(pcm) / frameBytes
+
+	r.mu.Lock()
+	if r.state != types.ProcessRunning || r.result == nil {
+		r.mu.Unlock()
+		return nil
+	}
+
+	offset := r.frameAtLocked(r.nextRotation) - first
+	if offset >= int64(frames) {
+		// The clock moved since the rotation was found due
+		result, summary := r.result, r.summary
+		r.mu.Unlock()
+		summary.addAudio(pcm)
+		return r.write(result, pcm)
+	}
+	start := r.nextRotation
+	if offset < 0 {
+		start, offset = r.frameTimeLocked(first), 0
+	}
+	split := int(offset) * frameBytes
+	fileStart, nextRotation := rotationPeriod(&r.config, start)
+
+	old, oldFile, oldSidecars := r.result, r.currentFile, r.takeSidecarsLocked()
+	oldSidecars.summary.addAudio(pcm[:split])
+	next, nextFile := r.takeNextLocked()
+	if next != nil && !start.Equal(r.nextRotation) {
+		// Prepared for the boundary, which the audio skipped
+		go discardFile(next, nextFile)
+		next = nil
+	}
+	if next == nil {
+		// The file was not prepared ahead; start it now
+		var err error
+		if next, nextFile, err = r.startFileLocked(fileStart, start); err != nil {
+			slog.Error("failed to start new recording file after rotation", "id", r.id, "error", err)
+			r.state = types.ProcessError
+			r.lastError = err.Error()
+			r.result = nil
+			r.currentFile = ""
+			r.nextRotation = time.Time{}
+			r.finishing.Add(1)
+			r.mu.Unlock()
+
+			// Without a file to write to, the recorder stays in the error state
+			r.writeFinal(old, pcm[:split])
+			go r.finishRotatedFile(old, oldFile, oldSidecars)
+			return err
+		}
+	}
+
+	r.result = next
+	r.currentFile = nextFile
+	r.startTime = start
+	r.nextRotation = nextRotation
+	r.openFileLocked(first + offset)
+	r.summary.addAudio(pcm[split:])
+	if r.rotationTimer != nil {
+		r.rotationTimer.Stop()
+	}
+	r.scheduleRotationLocked()
+	r.finishing.Add(1)
+	r.mu.Unlock()
+
+	slog.Info("recorder rotated file", "id", r.id, "file", filepath.Base(nextFile), "split_frame", offset)
+
+	r.writeFinal(old, pcm[:split])
+	go r.finishRotatedFile(old, oldFile, oldSidecars)
+	return r.write(next, pcm[split:])
+}
+
+// writeFinal writes the last frames of a rotated file.
+func (r *GenericRecorder) writeFinal(result *ffmpeg.StartResult, pcm []byte) {
+	if len(pcm) == 0 {
+		return
+	}
+	if _, err := result.WriteStdin(pcm); err != nil && !errors.Is(err, ffmpeg.ErrStdinClosed) {
+		slog.Warn("failed to write end of rotated file", "id", r.id, "error", err)
+	}
+}
+
+// finishRotatedFile finalizes and uploads a file after a rotation.
+func (r *GenericRecorder) finishRotatedFile(result *ffmpeg.StartResult, file string, sidecars fileSidecars) {
+	defer r.finishing.Done()
+	r.finishFile(result, file, sidecars)
+
+	// Process retry queue at each rotation
+	r.processRetryQueue()
+}
+
+// flushPreRoll writes the audio buffered before the start to a new file,
+// followed by the live audio that arrived meanwhile.
+func (r *GenericRecorder) flushPreRoll(result *ffmpeg.StartResult, summary *fileSummary, pcm []byte) {
+	for len(pcm) > 0 {
+		pcm = r.loudness.Process(pcm)
+		summary.addAudio(pcm)
+		if err := r.write(result, pcm); err != nil {
+			slog.Warn("recorder write error", "id", r.id, "error", err)
+			return
+		}
+		r.samples.Add(int64(len(pcm) / frameBytes))
+		pcm = r.preRoll.next()
+	}
+}
+
 // cleanupAfterWriteError handles cleanup when a write error occurs.
 // It closes FFmpeg gracefully and uploads the file directly.
-func (r *GenericRecorder) cleanupAfterWriteError(result *ffmpeg.StartResult, currentFile string) {
+func (r *GenericRecorder) cleanupAfterWriteError(result *ffmpeg.StartResult, currentFile string, sidecars fileSidecars) {
 	if result == nil {
 		return
 	}
@@ -392,9 +656,14 @@ func (r *GenericRecorder) cleanupAfterWriteError(result *ffmpeg.StartResult, cur
 	}
 
 	// Upload directly - bypasses queue to avoid race with concurrent Stop()
+	// Sidecars read the file, which is removed after its upload in S3-only mode
+	sidecarPaths := r.finishSidecars(currentFile, sidecars)
 	if currentFile != "" {
 		r.uploadDirectly(currentFile)
 	}
+	for _, path := range sidecarPaths {
+		r.uploadDirectly(path)
+	}
 }
 
 // Status returns the recorder process status.
@@ -402,10 +671,15 @@ func (r *GenericRecorder) Status() types.ProcessStatus {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	return types.ProcessStatus{
+	status := types.ProcessStatus{
 		State: r.state,
 		Error: r.lastError,
 	}
+	if r.config.Loudness.Enabled {
+		loudness := r.loudness.Status()
+		status.Loudness = &loudness
+	}
+	return status
 }
 
 // IsRecording reports whether recording is currently in progress.
@@ -426,7 +700,19 @@ func (r *GenericRecorder) UpdateConfig(cfg *types.Recorder) error {
 		r.lastError = ""
 	}
 
+	rotationChanged := r.config.RotationMode != cfg.RotationMode || r.config.RotationMinutes != cfg.RotationMinutes
 	r.config = *cfg
+	r.loudness.SetConfig(cfg.Loudness)
+	r.preRoll.setEnabled(cfg.RotationMode == types.RotationOnDemand && cfg.IsEnabled())
+
+	// The current file keeps its name; the next one follows the new mode
+	if rotationChanged && r.state == types.ProcessRunning {
+		r.stopTimersLocked()
+		_, r.nextRotation = rotationPeriod(&r.config, time.Now())
+		next, nextFile := r.takeNextLocked()
+		go discardFile(next, nextFile)
+		r.scheduleTimersLocked()
+	}
 	// Note: S3 client will be recreated on next use if config changed
 	// (same pattern as Graph client in notifications)
 
@@ -434,64 +720,104 @@ func (r *GenericRecorder) UpdateConfig(cfg *types.Recorder) error {
 }
 
 // Must be called with r.mu held.
-func (r *GenericRecorder) startEncoderLocked() error {
-	r.startTime = time.Now()
-
-	// Generate filename based on rotation mode
-	var filename string
-	if r.config.RotationMode == types.RotationHourly {
-		hourStart := r.startTime.Truncate(time.Hour)
-		filename = r.generateFilename(hourStart)
-	} else {
-		filename = r.generateFilename(r.startTime)
-	}
-
-	// Determine output directory based on storage mode
-	var outputDir string
-	if r.config.StorageMode == types.StorageS3 {
-		// S3-only: use temp directory
-		outputDir = filepath.Join(r.tempDir, "recorders", r.id)
-	} else {
-		// Local or Both: use configured LocalPath
-		outputDir = r.config.LocalPath
+func (r *GenericRecorder) startEncoderLocked(lead time.Duration) error {
+	r.startTime = time.Now().Add(-lead)
+	r.samples.Store(0)
+	r.clockRef, r.clockSample = r.startTime, 0
+
+	// Rotating files are named after their aligned start
+	fileStart, next := rotationPeriod(&r.config, r.startTime)
+	result, path, err := r.startFileLocked(fileStart, r.startTime)
+	if err != nil {
+		return err
 	}
-	r.currentFile = filepath.Join(outputDir, filename)
 
-	// Get codec configuration
-	codecArgs := r.config.CodecArgs()
-	format := r.config.Format()
-	ext := r.getFileExtension()
+	r.result = result
+	r.currentFile = path
+	r.nextRotation = next
+	r.openFileLocked(0)
+	return nil
+}
 
-	// Update filename with correct extension
-	r.currentFile = r.currentFile[:len(r.currentFile)-len(filepath.Ext(r.currentFile))] + "." + ext
+// startFileLocked starts an encoder for the file of the period that starts at
+// fileStart, whose first sample is at origin, and returns it with the file path.
+// Must be called with r.mu held.
+func (r *GenericRecorder) startFileLocked(fileStart, origin time.Time) (*ffmpeg.StartResult, string, error) {
+	path := filepath.Join(r.outputDirLocked(), r.generateFilename(fileStart))
 
 	// Build FFmpeg command args
 	args := ffmpeg.BaseInputArgs()
 	args = append(args, "-c:a")
-	args = append(args, codecArgs...)
+	args = append(args, r.config.CodecArgs()...)
+	args = append(args, "-f", r.config.Format())
+	if r.config.Codec == types.CodecWAV {
+		args = append(args, bwfArgs(origin, r.stationName, r.config.Name)...)
+	}
 	args = append(args,
-		"-f", format,
 		"-hide_banner",
 		"-loglevel", "warning",
 		"-y",
-		r.currentFile,
+		path,
 	)
 
 	// Start FFmpeg process
 	result, err := ffmpeg.StartProcess(r.ffmpegPath, args)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
-	r.result = result
+	slog.Info("recorder encoding started", "id", r.id, "file", filepath.Base(path), "codec", r.config.Codec)
+	return result, path, nil
+}
 
-	slog.Info("recorder encoding started", "id", r.id, "file", filepath.Base(r.currentFile), "codec", r.config.Codec)
+// outputDirLocked returns the directory the recorder writes its files to.
+// Must be called with r.mu held.
+func (r *GenericRecorder) outputDirLocked() string {
+	if r.config.StorageMode == types.StorageS3 {
+		// S3-only: use temp directory
+		return filepath.Join(r.tempDir, "recorders", r.id)
+	}
+	// Local or Both: use configured LocalPath
+	return r.config.LocalPath
+}
+
+// openFileLocked opens the cue sheet of the current file and logs the new
+// file with the position of its first frame in the recording.
+// Must be called with r.mu held.
+func (r *GenericRecorder) openFileLocked(startSample int64) {
+	// The item already on air opens the cue sheet of the new file
+	r.cue = newCueSheet(r.currentFile, r.startTime)
+	r.summary = r.newFileSummaryLocked(startSample)
+	if !r.nowPlaying.IsZero() {
+		r.cue.addTrack(r.nowPlaying, 0)
+		r.summary.addNowPlaying(r.nowPlaying, 0)
+		go r.flushCue(r.cue)
+	}
 
 	// Log new file event (already holding lock)
 	p := r.captureLogParamsLocked()
 	p.Filename = filepath.Base(r.currentFile)
+	p.StartSample = startSample
+	p.StartTime = r.startTime
 	r.logEvent(eventlog.RecorderFile, p)
-	return nil
+}
+
+// takeNextLocked detaches the encoder prepared for the next file, if any.
+// Must be called with r.mu held.
+func (r *GenericRecorder) takeNextLocked() (*ffmpeg.StartResult, string) {
+	next, nextFile := r.next, r.nextFile
+	r.next, r.nextFile = nil, ""
+	return next, nextFile
+}
+
+// discardFile stops an encoder that never received audio and removes its file.
+func discardFile(result *ffmpeg.StartResult, path string) {
+	if result == nil {
+		return
+	}
+	_ = result.Kill()   //nolint:errcheck // Process may have exited already
+	_ = result.Wait()   //nolint:errcheck // Killed on purpose
+	_ = os.Remove(path) //nolint:errcheck // FFmpeg may not have created it
 }
 
 // stopEncoderAndUpload gracefully stops FFmpeg with staged timeouts and queues the file for upload.
@@ -500,6 +826,7 @@ func (r *GenericRecorder) stopEncoderAndUpload() {
 	r.mu.Lock()
 	currentFile := r.currentFile
 	result := r.result
+	sidecars := r.takeSidecarsLocked()
 	r.mu.Unlock()
 
 	if result == nil {
@@ -507,6 +834,12 @@ func (r *GenericRecorder) stopEncoderAndUpload() {
 		return
 	}
 
+	r.finishFile(result, currentFile, sidecars)
+}
+
+// finishFile gracefully stops the encoder of a file with staged timeouts and
+// queues the file and its sidecars for upload.
+func (r *GenericRecorder) finishFile(result *ffmpeg.StartResult, currentFile string, sidecars fileSidecars) {
 	// Close stdin - signals FFmpeg that input is done
 	result.CloseStdin()
 
@@ -544,58 +877,112 @@ func (r *GenericRecorder) stopEncoderAndUpload() {
 	}
 
 	// Queue for upload if file exists and S3 is configured
+	// Sidecars read the file, which is removed after its upload in S3-only mode
+	sidecarPaths := r.finishSidecars(currentFile, sidecars)
 	if currentFile != "" {
 		r.queueForUpload(currentFile)
 	}
+	for _, path := range sidecarPaths {
+		r.queueForUpload(path)
+	}
 }
 
-// Must be called with r.mu held.
-func (r *GenericRecorder) scheduleRotationLocked() {
-	duration := util.TimeUntilNextHour(time.Now())
-	r.rotationTimer = time.AfterFunc(duration, r.rotateFile)
-}
-
-// rotateFile handles hourly file rotation, stopping the current encoder and starting a new one.
-func (r *GenericRecorder) rotateFile() {
+// SetNowPlaying records a new item on air. While recording, it is added to
+// the cue sheet of the current file at the current position.
+func (r *GenericRecorder) SetNowPlaying(np metadata.NowPlaying) {
 	r.mu.Lock()
-
-	if r.state != types.ProcessRunning {
+	r.nowPlaying = np
+	if r.state != types.ProcessRunning || r.result == nil || r.cue == nil {
 		r.mu.Unlock()
 		return
 	}
-
-	// Set rotating state before releasing lock to prevent Stop() interference
-	r.state = types.ProcessRotating
-	slog.Info("recorder rotating file at hour boundary", "id", r.id)
+	cue := r.cue
+	cue.addTrack(np, np.UpdatedAt.Sub(r.startTime))
+	r.summary.addNowPlaying(np, np.UpdatedAt.Sub(r.startTime))
 	r.mu.Unlock()
 
-	// Stop current encoder and upload
-	r.stopEncoderAndUpload()
+	r.flushCue(cue)
+}
 
-	// Process retry queue at hour boundary
-	r.processRetryQueue()
+// flushCue writes a cue sheet to disk, logging failures.
+func (r *GenericRecorder) flushCue(cue *cueSheet) {
+	if err := cue.flush(); err != nil {
+		slog.Warn("failed to write cue sheet", "id", r.id, "error", err)
+	}
+}
 
+// SetSilence records whether the silence detector reports silence on the
+// recorder's channels, and since when. Silence periods go into the sidecar
+// of the current file.
+func (r *GenericRecorder) SetSilence(silent bool, since time.Time) {
 	r.mu.Lock()
-	// Re-check state after reacquiring lock - Stop() may have been called
-	if r.state != types.ProcessRotating {
-		// State changed (likely Stop() was called) - don't continue rotation
-		r.mu.Unlock()
+	defer r.mu.Unlock()
+
+	if silent == r.silent {
 		return
 	}
+	r.silent = silent
+	if r.summary != nil {
+		r.summary.setSilence(silent, since)
+	}
+}
 
-	// Start new encoder
-	if err := r.startEncoderLocked(); err != nil {
-		slog.Error("failed to start new recording file after rotation", "id", r.id, "error", err)
-		r.state = types.ProcessError
-		r.lastError = err.Error()
-		r.mu.Unlock()
-		return // Don't schedule next rotation - hourly retry will pick this up
+// takeSidecarsLocked detaches the cue sheet and the sidecar of the current file.
+// Must be called with r.mu held.
+func (r *GenericRecorder) takeSidecarsLocked() fileSidecars {
+	sidecars := fileSidecars{cue: r.cue, summary: r.summary}
+	r.cue = nil
+	r.summary = nil
+	return sidecars
+}
+
+// scheduleTimersLocked starts the rotation timer of a rotating recorder, or
+// the max duration timer of an on-demand recorder.
+// Must be called with r.mu held.
+func (r *GenericRecorder) scheduleTimersLocked() {
+	switch {
+	case r.config.RotationMode.Rotates():
+		r.scheduleRotationLocked()
+	case r.config.RotationMode == types.RotationOnDemand && r.maxDurationMinutes > 0 && r.show == "":
+		r.scheduleDurationLimitLocked()
 	}
+}
 
-	// Schedule next rotation
-	r.scheduleRotationLocked()
-	r.state = types.ProcessRunning
-	r.mu.Unlock()
+// stopTimersLocked stops the rotation and max duration timers.
+// Must be called with r.mu held.
+func (r *GenericRecorder) stopTimersLocked() {
+	if r.rotationTimer != nil {
+		r.rotationTimer.Stop()
+		r.rotationTimer = nil
+	}
+	if r.durationTimer != nil {
+		r.durationTimer.Stop()
+		r.durationTimer = nil
+	}
+}
+
+// scheduleRotationLocked prepares the next file shortly before the rotation.
+// Must be called with r.mu held.
+func (r *GenericRecorder) scheduleRotationLocked() {
+	r.rotationTimer = time.AfterFunc(time.Until(r.nextRotation.Add(-rotationLead)), r.prepareRotation)
+}
+
+// prepareRotation starts the encoder of the next file ahead of the rotation,
+// so the switch in WriteAudio does not wait for FFmpeg to start.
+func (r *GenericRecorder) prepareRotation() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.state != types.ProcessRunning || r.next != nil || r.nextRotation.IsZero() {
+		return
+	}
+	next, nextFile, err := r.startFileLocked(r.nextRotation, r.nextRotation)
+	if err != nil {
+		// Retried when the rotation is due
+		slog.Warn("failed to prepare next recording file", "id", r.id, "error", err)
+		return
+	}
+	r.next, r.nextFile = next, nextFile
 }
 
 // Must be called with r.mu held.
@@ -612,7 +999,14 @@ func (r *GenericRecorder) scheduleDurationLimitLocked() {
 func (r *GenericRecorder) generateFilename(t time.Time) string {
 	ext := r.getFileExtension()
 	safeName := sanitizeFilename(r.config.Name)
-	return fmt.Sprintf("%s-%s.%s", safeName, t.Format("2006-01-02-15-04"), ext)
+	if r.show != "" {
+		return fmt.Sprintf("%s-%s-%s.%s", safeName, t.Format(fileSecondsLayout), sanitizeFilename(r.show), ext)
+	}
+	layout := fileTimeLayout
+	if !r.config.RotationMode.Rotates() {
+		layout = fileSecondsLayout
+	}
+	return fmt.Sprintf("%s-%s.%s", safeName, t.Format(layout), ext)
 }
 
 func (r *GenericRecorder) generateS3Key(filename string) string {
@@ -622,7 +1016,12 @@ func (r *GenericRecorder) generateS3Key(filename string) string {
 }
 
 func (r *GenericRecorder) getFileExtension() string {
-	switch r.config.Codec {
+	return codecExtension(r.config.Codec)
+}
+
+// codecExtension returns the file extension of files written with codec.
+func codecExtension(codec types.Codec) string {
+	switch codec {
 	case types.CodecMP2:
 		return "mp2"
 	case types.CodecMP3:
@@ -630,7 +1029,7 @@ func (r *GenericRecorder) getFileExtension() string {
 	case types.CodecOGG:
 		return "ogg"
 	case types.CodecWAV:
-		return "mkv" // WAV uses matroska container
+		return "wav"
 	default:
 		return "mp3"
 	}
@@ -641,7 +1040,7 @@ func (r *GenericRecorder) getContentType() string {
 	case types.CodecOGG:
 		return "audio/ogg"
 	case types.CodecWAV:
-		return "audio/x-matroska"
+		return "audio/wav"
 	default:
 		return "audio/mpeg"
 	}
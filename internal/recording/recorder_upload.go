@@ -2,6 +2,7 @@ package recording
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"log/slog"
 	"os"
@@ -10,15 +11,17 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/transfermanager"
+	tmtypes "github.com/aws/aws-sdk-go-v2/feature/s3/transfermanager/types"
 	"github.com/oszuidwest/zwfm-encoder/internal/eventlog"
 	"github.com/oszuidwest/zwfm-encoder/internal/types"
 )
 
 // uploadRequest represents a file to be uploaded to S3.
 type uploadRequest struct {
-	localPath string
-	s3Key     string
-	fileSize  int64
+	localPath   string
+	s3Key       string
+	fileSize    int64
+	contentType string
 }
 
 // pendingUpload tracks a failed upload for retry.
@@ -34,16 +37,19 @@ const MaxUploadRetryAge = 24 * time.Hour
 
 func (r *GenericRecorder) queueForUpload(filePath string) {
 	req, ok := r.prepareUploadRequest(filePath)
-	if !ok {
+	if !ok || !r.claimUpload(filePath) {
 		return
 	}
 
+	r.journalAdd(req)
+
 	select {
 	case r.uploadQueue <- req:
 		slog.Info("queued file for upload", "id", r.id, "file", filepath.Base(filePath))
 		r.logUploadEvent(eventlog.UploadQueued, filepath.Base(filePath), req.s3Key, "", 0)
 	default:
 		slog.Warn("upload queue full", "id", r.id)
+		r.addToRetryQueue(req, "upload queue full")
 	}
 }
 
@@ -51,11 +57,12 @@ func (r *GenericRecorder) queueForUpload(filePath string) {
 // Used by cleanupAfterWriteError to avoid race conditions with Stop().
 func (r *GenericRecorder) uploadDirectly(filePath string) {
 	req, ok := r.prepareUploadRequest(filePath)
-	if !ok {
+	if !ok || !r.claimUpload(filePath) {
 		return
 	}
 
 	slog.Info("uploading file directly (error recovery)", "id", r.id, "file", filepath.Base(filePath))
+	r.journalAdd(req)
 	r.uploadFile(req)
 }
 
@@ -80,13 +87,47 @@ func (r *GenericRecorder) prepareUploadRequest(filePath string) (uploadRequest,
 		return uploadRequest{}, false
 	}
 
+	contentType := r.getContentType()
+	switch filepath.Ext(filePath) {
+	case ".cue":
+		contentType = cueContentType
+	case ".json":
+		contentType = sidecarContentType
+	}
+
 	return uploadRequest{
-		localPath: filePath,
-		s3Key:     r.generateS3Key(filepath.Base(filePath)),
-		fileSize:  info.Size(),
+		localPath:   filePath,
+		s3Key:       r.generateS3Key(filepath.Base(filePath)),
+		fileSize:    info.Size(),
+		contentType: contentType,
 	}, true
 }
 
+// claimUpload marks a file as on its way to S3. It returns false if the file
+// already is, so a file found by the startup scan and by a rotation at the
+// same time is uploaded once.
+func (r *GenericRecorder) claimUpload(localPath string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.uploading[localPath] {
+		slog.Debug("file already queued for upload", "id", r.id, "file", filepath.Base(localPath))
+		return false
+	}
+	r.uploading[localPath] = true
+	return true
+}
+
+// releaseUpload removes a file that reached S3 or was given up on from the
+// files on their way there and from the upload journal.
+func (r *GenericRecorder) releaseUpload(localPath string) {
+	r.mu.Lock()
+	delete(r.uploading, localPath)
+	r.mu.Unlock()
+
+	r.journalRemove(localPath)
+}
+
 // logUploadEvent logs upload-related events with optional retry count.
 func (r *GenericRecorder) logUploadEvent(eventType eventlog.EventType, filename, s3Key, errMsg string, retryCount int) {
 	if r.eventLogger == nil {
@@ -103,6 +144,22 @@ func (r *GenericRecorder) logUploadEvent(eventType eventlog.EventType, filename,
 	r.logEvent(eventType, p)
 }
 
+// logUploadCompleted logs a verified upload with the checksum of the file.
+func (r *GenericRecorder) logUploadCompleted(req *uploadRequest, sums fileChecksums, retryCount int) {
+	if r.eventLogger == nil {
+		return
+	}
+	r.mu.RLock()
+	p := r.captureLogParamsLocked()
+	r.mu.RUnlock()
+
+	p.Filename = filepath.Base(req.localPath)
+	p.S3Key = req.s3Key
+	p.RetryCount = retryCount
+	p.SHA256 = sums.SHA256()
+	r.logEvent(eventlog.UploadCompleted, p)
+}
+
 // uploadWorker processes the upload queue, draining remaining items on shutdown.
 func (r *GenericRecorder) uploadWorker() {
 	defer r.uploadWg.Done()
@@ -127,7 +184,7 @@ func (r *GenericRecorder) uploadWorker() {
 
 // uploadFile uploads to S3 and deletes temp files in S3-only mode.
 func (r *GenericRecorder) uploadFile(req uploadRequest) {
-	err := r.doUpload(req)
+	sums, err := r.doUpload(req)
 	if err != nil {
 		slog.Error("upload failed", "id", r.id, "s3_key", req.s3Key, "error", err)
 		r.logUploadEvent(eventlog.UploadFailed, filepath.Base(req.localPath), req.s3Key, err.Error(), 0)
@@ -135,17 +192,24 @@ func (r *GenericRecorder) uploadFile(req uploadRequest) {
 		return
 	}
 
-	slog.Info("upload completed", "id", r.id, "s3_key", req.s3Key)
-	r.logUploadEvent(eventlog.UploadCompleted, filepath.Base(req.localPath), req.s3Key, "", 0)
+	slog.Info("upload completed", "id", r.id, "s3_key", req.s3Key, "sha256", sums.SHA256())
+	r.logUploadCompleted(&req, sums, 0)
+	r.releaseUpload(req.localPath)
 	r.deleteIfS3Only(req.localPath)
 }
 
 // doUpload performs the actual S3 upload using the transfer manager for
-// automatic multipart uploads. Returns nil on success.
-func (r *GenericRecorder) doUpload(req uploadRequest) error {
+// automatic multipart uploads, and verifies the uploaded object. Returns the
+// checksums of the file on success.
+func (r *GenericRecorder) doUpload(req uploadRequest) (fileChecksums, error) {
+	sums, err := computeChecksums(req.localPath)
+	if err != nil {
+		return fileChecksums{}, err
+	}
+
 	file, err := os.Open(req.localPath)
 	if err != nil {
-		return err
+		return fileChecksums{}, err
 	}
 	defer func() {
 		if closeErr := file.Close(); closeErr != nil {
@@ -155,10 +219,10 @@ func (r *GenericRecorder) doUpload(req uploadRequest) error {
 
 	client, err := r.getOrCreateS3Client()
 	if err != nil {
-		return err
+		return fileChecksums{}, err
 	}
 	if client == nil {
-		return errNoS3Client
+		return fileChecksums{}, errNoS3Client
 	}
 
 	r.mu.RLock()
@@ -168,7 +232,9 @@ func (r *GenericRecorder) doUpload(req uploadRequest) error {
 	// Create transfer manager with progress logging
 	tm := transfermanager.New(client, func(o *transfermanager.Options) {
 		o.Concurrency = 5
-		o.PartSizeBytes = 8 * 1024 * 1024 // 8 MiB parts
+		o.PartSizeBytes = uploadPartSize
+		o.MultipartUploadThreshold = multipartThreshold
+		o.ChecksumAlgorithm = tmtypes.ChecksumAlgorithmCrc32
 		o.ObjectProgressListeners.Register(&uploadProgressListener{
 			id:       r.id,
 			filename: filepath.Base(req.localPath),
@@ -191,14 +257,27 @@ func (r *GenericRecorder) doUpload(req uploadRequest) error {
 		"size_mb", req.fileSize/(1024*1024),
 		"timeout_min", timeoutMinutes)
 
-	_, err = tm.UploadObject(ctx, &transfermanager.UploadObjectInput{
+	input := &transfermanager.UploadObjectInput{
 		Bucket:        aws.String(bucket),
 		Key:           aws.String(req.s3Key),
 		Body:          file,
 		ContentLength: aws.Int64(req.fileSize),
-		ContentType:   aws.String(r.getContentType()),
-	})
-	return err
+		ContentType:   aws.String(req.contentType),
+		Metadata:      sums.metadata(),
+	}
+	if req.fileSize < multipartThreshold {
+		input.ChecksumAlgorithm = tmtypes.ChecksumAlgorithmSha256
+		input.ChecksumSHA256 = aws.String(base64.StdEncoding.EncodeToString(sums.sha256))
+	}
+	r.applyObjectLock(ctx, client, input)
+	if _, err := tm.UploadObject(ctx, input); err != nil {
+		return fileChecksums{}, err
+	}
+
+	if err := r.verifyUpload(ctx, client, bucket, &req, sums); err != nil {
+		return fileChecksums{}, err
+	}
+	return sums, nil
 }
 
 var errNoS3Client = &noS3ClientError{}
@@ -270,7 +349,7 @@ func (r *GenericRecorder) addToRetryQueue(req uploadRequest, errMsg string) {
 }
 
 // processRetryQueue attempts to upload all pending files.
-// Called from rotateFile() at hour boundaries.
+// Called after each file rotation, hourly, and when pending uploads are resumed at startup.
 func (r *GenericRecorder) processRetryQueue() {
 	r.mu.Lock()
 	if len(r.retryQueue) == 0 {
@@ -297,6 +376,7 @@ func (r *GenericRecorder) processRetryQueue() {
 				"attempts", p.retryCount+1,
 				"last_error", p.lastError)
 			r.logUploadEvent(eventlog.UploadAbandoned, filepath.Base(p.request.localPath), p.request.s3Key, p.lastError, p.retryCount)
+			r.releaseUpload(p.request.localPath)
 			continue
 		}
 
@@ -310,6 +390,7 @@ func (r *GenericRecorder) processRetryQueue() {
 
 		if !r.retryUpload(p) {
 			// Failed - re-add to queue
+			r.journalRetry(p)
 			r.mu.Lock()
 			r.retryQueue = append(r.retryQueue, *p)
 			r.mu.Unlock()
@@ -322,10 +403,11 @@ func (r *GenericRecorder) retryUpload(p *pendingUpload) bool {
 	// Check if file still exists before attempting upload
 	if _, err := os.Stat(p.request.localPath); os.IsNotExist(err) {
 		slog.Warn("retry file no longer exists", "id", r.id, "path", p.request.localPath)
+		r.releaseUpload(p.request.localPath)
 		return true // Nothing to upload
 	}
 
-	err := r.doUpload(p.request)
+	sums, err := r.doUpload(p.request)
 	if err != nil {
 		p.lastError = err.Error()
 		slog.Error("retry upload failed", "id", r.id, "s3_key", p.request.s3Key, "error", err)
@@ -333,8 +415,9 @@ func (r *GenericRecorder) retryUpload(p *pendingUpload) bool {
 		return false
 	}
 
-	slog.Info("retry upload completed", "id", r.id, "s3_key", p.request.s3Key)
-	r.logUploadEvent(eventlog.UploadCompleted, filepath.Base(p.request.localPath), p.request.s3Key, "", p.retryCount)
+	slog.Info("retry upload completed", "id", r.id, "s3_key", p.request.s3Key, "sha256", sums.SHA256())
+	r.logUploadCompleted(&p.request, sums, p.retryCount)
+	r.releaseUpload(p.request.localPath)
 	r.deleteIfS3Only(p.request.localPath)
 	return true
 }
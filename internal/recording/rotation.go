@@ -0,0 +1,67 @@
+package recording
+
+import (
+	"strings"
+	"time"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/types"
+)
+
+const (
+	// fileTimeLayout timestamps files of rotating recorders with their aligned start.
+	fileTimeLayout = "2006-01-02-15-04"
+	// fileSecondsLayout timestamps files of continuous and on-demand recorders,
+	// which can start more than once a minute.
+	fileSecondsLayout = "2006-01-02_15-04-05"
+)
+
+// rotationPeriod returns the start of the file that contains t and the time
+// of the next rotation. Periods are aligned to the local wall clock, so
+// 120-minute files start at even hours. For modes that do not rotate, start
+// is t and next is zero.
+func rotationPeriod(cfg *types.Recorder, t time.Time) (start, next time.Time) {
+	y, m, d := t.Date()
+	switch cfg.RotationMode {
+	case types.RotationHourly, types.RotationInterval:
+		minutes := 60
+		if cfg.RotationMode == types.RotationInterval {
+			minutes = cfg.RotationMinutes
+		}
+		elapsed := (t.Hour()*60 + t.Minute()) / minutes * minutes
+		start = time.Date(y, m, d, 0, elapsed, 0, 0, t.Location())
+		next = time.Date(y, m, d, 0, elapsed+minutes, 0, 0, t.Location())
+	case types.RotationDaily:
+		start = time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+		next = time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+	default:
+		start = t
+	}
+	return start, next
+}
+
+// recordingTime returns the start time in the name of a file written by the
+// recorder with the given sanitized name. Files of other recorders, including
+// those whose name merely starts with the same text, do not match.
+func recordingTime(safeName, filename string) (time.Time, bool) {
+	rest, ok := strings.CutPrefix(filename, safeName+"-")
+	if !ok {
+		return time.Time{}, false
+	}
+	stamp, _, ok := strings.Cut(rest, ".")
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{fileTimeLayout, fileSecondsLayout} {
+		if t, err := time.ParseInLocation(layout, stamp, time.Local); err == nil {
+			return t, true
+		}
+	}
+
+	// Scheduled shows follow the start time with their name
+	if len(stamp) > len(fileSecondsLayout) && stamp[len(fileSecondsLayout)] == '-' {
+		if t, err := time.ParseInLocation(fileSecondsLayout, stamp[:len(fileSecondsLayout)], time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
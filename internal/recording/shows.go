@@ -0,0 +1,157 @@
+package recording
+
+import (
+	"cmp"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/schedule"
+)
+
+// showInterval is how often recorder schedules are evaluated.
+const showInterval = time.Second
+
+// ShowStatus is a scheduled show of a recorder with its current or next
+// occurrence. NextStart and NextEnd include the padding.
+type ShowStatus struct {
+	RecorderID   string `json:"recorder_id"`
+	RecorderName string `json:"recorder_name"`
+	Enabled      bool   `json:"enabled"`
+	schedule.Show
+	Active    bool      `json:"active"`
+	NextStart time.Time `json:"next_start,omitzero"`
+	NextEnd   time.Time `json:"next_end,omitzero"`
+}
+
+// Schedules returns the shows of all recorders, ordered by their next
+// occurrence. Shows without one, such as past one-off dates, come last.
+func (m *Manager) Schedules() []ShowStatus {
+	m.mu.RLock()
+	recorders := slices.Collect(maps.Values(m.recorders))
+	m.mu.RUnlock()
+
+	now := time.Now()
+	var shows []ShowStatus
+	for _, recorder := range recorders {
+		cfg := recorder.Config()
+		for _, show := range cfg.Schedules {
+			status := ShowStatus{
+				RecorderID:   cfg.ID,
+				RecorderName: cfg.Name,
+				Enabled:      cfg.IsEnabled(),
+				Show:         show,
+			}
+			o, ok := show.Current(now)
+			status.Active = ok
+			if !ok {
+				o, ok = show.Next(now)
+			}
+			if ok {
+				status.NextStart, status.NextEnd = o.Start, o.End
+			}
+			shows = append(shows, status)
+		}
+	}
+
+	slices.SortStableFunc(shows, func(a, b ShowStatus) int {
+		if a.NextStart.IsZero() != b.NextStart.IsZero() {
+			if a.NextStart.IsZero() {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Or(a.NextStart.Compare(b.NextStart), cmp.Compare(a.RecorderName, b.RecorderName))
+	})
+	return shows
+}
+
+// startShowScheduler starts and stops recorders for their scheduled shows.
+func (m *Manager) startShowScheduler() {
+	go m.runShows(m.showStopCh)
+}
+
+// runShows evaluates recorder schedules until stopCh is closed. A recorder
+// records one show at a time: a show that overlaps the one being recorded
+// starts when that one ends. Shows are only started once, so a recording
+// stopped through the API stays stopped until the next occurrence.
+func (m *Manager) runShows(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(showInterval)
+	defer ticker.Stop()
+
+	runs := make(map[string]schedule.Occurrence) // Occurrence last started per recorder
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		m.mu.RLock()
+		recorders := slices.Collect(maps.Values(m.recorders))
+		m.mu.RUnlock()
+
+		now := time.Now()
+		seen := make(map[string]bool, len(recorders))
+		for _, recorder := range recorders {
+			id := recorder.ID()
+			seen[id] = true
+
+			if last, ok := runs[id]; ok {
+				if now.Before(last.End) {
+					continue
+				}
+				delete(runs, id)
+				// Leave recordings alone that were started through the API in the meantime
+				if recorder.IsRecording() && recorder.Show() != "" {
+					slog.Info("stopping recorder at end of show", "id", id, "show", recorder.Show())
+					if err := recorder.Stop(); err != nil {
+						slog.Error("failed to stop scheduled recording", "id", id, "error", err)
+					}
+				}
+			}
+
+			cfg := recorder.Config()
+			if !cfg.IsEnabled() {
+				continue
+			}
+			show, o, ok := currentShow(cfg.Schedules, now)
+			if !ok {
+				continue
+			}
+			runs[id] = o
+
+			if recorder.IsRecording() {
+				slog.Warn("recorder busy, skipping scheduled show", "id", id, "show", show.Title)
+				continue
+			}
+			slog.Info("starting recorder for show", "id", id, "show", show.Title, "until", o.End)
+			if err := recorder.StartShow(show.Name(o)); err != nil {
+				slog.Error("failed to start scheduled recording", "id", id, "show", show.Title, "error", err)
+			}
+		}
+		for id := range runs {
+			if !seen[id] {
+				delete(runs, id)
+			}
+		}
+	}
+}
+
+// currentShow returns the show on air at t with its occurrence. When
+// occurrences overlap, the one that started first wins.
+func currentShow(shows []schedule.Show, t time.Time) (schedule.Show, schedule.Occurrence, bool) {
+	var (
+		current schedule.Show
+		first   schedule.Occurrence
+		found   bool
+	)
+	for _, show := range shows {
+		o, ok := show.Current(t)
+		if ok && (!found || o.Start.Before(first.Start)) {
+			current, first, found = show, o, true
+		}
+	}
+	return current, first, found
+}
@@ -0,0 +1,310 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/audio"
+	"github.com/oszuidwest/zwfm-encoder/internal/metadata"
+	"github.com/oszuidwest/zwfm-encoder/internal/types"
+)
+
+const (
+	// sidecarContentType is the content type of metadata sidecars uploaded to S3.
+	sidecarContentType = "application/json"
+	// levelBlockFrames is the length of a level measurement, the cadence of the level meters.
+	levelBlockFrames = audio.SampleRate / 4
+	// levelMinuteFrames is the length of a level summary.
+	levelMinuteFrames = audio.SampleRate * 60
+)
+
+// EncoderVersion is the application version recorded in sidecars, set at startup.
+var EncoderVersion = "dev"
+
+// Sidecar describes a recording file, written as JSON next to it once the
+// file is complete. Start is the wall-clock time of the first sample; End
+// follows from the sample count.
+type Sidecar struct {
+	File           string           `json:"file"`
+	RecorderID     string           `json:"recorder_id"`
+	Recorder       string           `json:"recorder"`
+	Show           string           `json:"show,omitempty"`
+	Start          time.Time        `json:"start"`
+	End            time.Time        `json:"end"`
+	StartSample    int64            `json:"start_sample"` // Position of the first sample in the recording
+	Samples        int64            `json:"samples"`
+	SampleRate     int              `json:"sample_rate"`
+	Channels       int              `json:"channels"`
+	Codec          SidecarCodec     `json:"codec"`
+	EncoderVersion string           `json:"encoder_version"`
+	SHA256         string           `json:"sha256"`          // Checksum of the recording file
+	Chain          *SidecarChain    `json:"chain,omitempty"` // Compliance recorders only
+	Silence        []SilencePeriod  `json:"silence"`
+	Levels         []LevelSummary   `json:"levels"`
+	NowPlaying     []NowPlayingItem `json:"now_playing"`
+}
+
+// SidecarCodec is the encoder configuration of a recording file.
+type SidecarCodec struct {
+	Name   types.Codec `json:"name"`
+	Format string      `json:"format"`
+	Args   []string    `json:"args"`
+}
+
+// SilencePeriod is silence reported by the silence detector, limited to the file.
+type SilencePeriod struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// LevelSummary is the audio level during one minute of a file, from the RMS
+// level of its 250 ms blocks in dBFS. Avg is the power average of the blocks.
+type LevelSummary struct {
+	Minute int     `json:"minute"` // Minutes from the start of the file
+	MinDB  float64 `json:"min_db"`
+	AvgDB  float64 `json:"avg_db"`
+	MaxDB  float64 `json:"max_db"`
+}
+
+// NowPlayingItem is an item that aired during a file, Offset seconds into it.
+type NowPlayingItem struct {
+	Offset float64   `json:"offset"`
+	Aired  time.Time `json:"aired"`
+	Artist string    `json:"artist,omitempty"`
+	Title  string    `json:"title"`
+}
+
+// sidecarPath returns the path of the metadata sidecar for a recording file.
+func sidecarPath(audioPath string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".json"
+}
+
+// fileSummary collects the sidecar of a recording file while it is written.
+// Audio and silence arrive from different goroutines.
+type fileSummary struct {
+	mu sync.Mutex
+
+	sidecar Sidecar
+	silent  bool // A silence period is open
+
+	block       audio.LevelData // Current level block
+	minute      LevelSummary    // Current minute, MinDB and MaxDB so far
+	minuteSum   float64         // Power sum of the blocks of the current minute
+	minuteCount int             // Blocks in the current minute
+
+	turn chainTurn // Place in the hash chain, in the order the files were opened
+}
+
+// newFileSummaryLocked starts the sidecar of the current file, which starts at
+// startSample in the recording.
+// Must be called with r.mu held.
+func (r *GenericRecorder) newFileSummaryLocked(startSample int64) *fileSummary {
+	s := &fileSummary{
+		sidecar: Sidecar{
+			File:        filepath.Base(r.currentFile),
+			RecorderID:  r.id,
+			Recorder:    r.config.Name,
+			Show:        r.show,
+			Start:       r.startTime,
+			StartSample: startSample,
+			SampleRate:  audio.SampleRate,
+			Channels:    audio.Channels,
+			Codec: SidecarCodec{
+				Name:   r.config.Codec,
+				Format: r.config.Format(),
+				Args:   r.config.CodecArgs(),
+			},
+			EncoderVersion: EncoderVersion,
+			Silence:        []SilencePeriod{},
+			Levels:         []LevelSummary{},
+			NowPlaying:     []NowPlayingItem{},
+		},
+	}
+	if r.silent {
+		s.setSilence(true, r.startTime)
+	}
+	s.turn, r.chainTail = newChainTurn(r.chainTail)
+	return s
+}
+
+// addAudio measures the levels of PCM written to the file.
+func (s *fileSummary) addAudio(pcm []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(pcm) >= frameBytes {
+		n := min(len(pcm)/frameBytes, levelBlockFrames-s.block.SampleCount) * frameBytes
+		audio.ProcessSamples(pcm, n, &s.block)
+		pcm = pcm[n:]
+		s.sidecar.Samples += int64(n / frameBytes)
+		if s.block.SampleCount == levelBlockFrames {
+			s.endBlock()
+		}
+		if s.sidecar.Samples%levelMinuteFrames == 0 {
+			s.endMinute()
+		}
+	}
+}
+
+// endBlock adds the current level block to the current minute.
+func (s *fileSummary) endBlock() {
+	levels := audio.CalculateLevels(&s.block)
+	s.block.Reset()
+
+	db := max(levels.RMSLeft, levels.RMSRight)
+	if s.minuteCount == 0 {
+		s.minute.MinDB, s.minute.MaxDB = db, db
+	}
+	s.minute.MinDB = min(s.minute.MinDB, db)
+	s.minute.MaxDB = max(s.minute.MaxDB, db)
+	s.minuteSum += math.Pow(10, db/10)
+	s.minuteCount++
+}
+
+// endMinute adds the current minute to the level summaries.
+func (s *fileSummary) endMinute() {
+	if s.minuteCount == 0 {
+		return
+	}
+	s.minute.Minute = len(s.sidecar.Levels)
+	s.minute.AvgDB = max(10*math.Log10(s.minuteSum/float64(s.minuteCount)), audio.MinDB)
+	s.sidecar.Levels = append(s.sidecar.Levels, LevelSummary{
+		Minute: s.minute.Minute,
+		MinDB:  roundDB(s.minute.MinDB),
+		AvgDB:  roundDB(s.minute.AvgDB),
+		MaxDB:  roundDB(s.minute.MaxDB),
+	})
+	s.minute = LevelSummary{}
+	s.minuteSum, s.minuteCount = 0, 0
+}
+
+// roundDB rounds a level to 0.1 dB.
+func roundDB(db float64) float64 {
+	return math.Round(db*10) / 10
+}
+
+// setSilence opens or closes a silence period at t, no earlier than the
+// start of the file.
+func (s *fileSummary) setSilence(silent bool, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if silent == s.silent {
+		return
+	}
+	s.silent = silent
+	if t.Before(s.sidecar.Start) {
+		t = s.sidecar.Start
+	}
+	if silent {
+		s.sidecar.Silence = append(s.sidecar.Silence, SilencePeriod{Start: t})
+		return
+	}
+	s.sidecar.Silence[len(s.sidecar.Silence)-1].End = t
+}
+
+// addNowPlaying adds an item that started airing offset into the file.
+func (s *fileSummary) addNowPlaying(np metadata.NowPlaying, offset time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sidecar.NowPlaying = append(s.sidecar.NowPlaying, NowPlayingItem{
+		Offset: max(offset, 0).Seconds(),
+		Aired:  np.UpdatedAt,
+		Artist: np.Artist,
+		Title:  np.Title,
+	})
+}
+
+// write completes the sidecar with the end and checksum of the file and its
+// link in the hash chain, if any, and writes it next to the file at audioPath.
+// It returns the path of the sidecar and the checksum of the file.
+func (s *fileSummary) write(audioPath string, chain *SidecarChain) (path, sha string, err error) {
+	sums, err := computeChecksums(audioPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sidecar.SHA256 = sums.SHA256()
+	s.sidecar.Chain = chain
+	if s.block.SampleCount > 0 {
+		s.endBlock()
+	}
+	s.endMinute()
+	s.sidecar.End = s.sidecar.Start.Add(time.Duration(s.sidecar.Samples) * time.Second / audio.SampleRate)
+	if s.silent {
+		s.sidecar.Silence[len(s.sidecar.Silence)-1].End = s.sidecar.End
+	}
+
+	data, err := json.MarshalIndent(s.sidecar, "", "  ")
+	if err != nil {
+		return "", "", fmt.Errorf("encode sidecar: %w", err)
+	}
+	path = sidecarPath(audioPath)
+	if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec // Sidecar is as readable as the recording
+		return "", "", fmt.Errorf("write sidecar: %w", err)
+	}
+	return path, s.sidecar.SHA256, nil
+}
+
+// readSidecar reads the metadata sidecar with the given name, from disk or S3.
+func (r *GenericRecorder) readSidecar(ctx context.Context, name string) (*Sidecar, error) {
+	file, _, err := r.openRecording(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close() //nolint:errcheck // Read-only
+	}()
+
+	var sidecar Sidecar
+	if err := json.NewDecoder(file).Decode(&sidecar); err != nil {
+		return nil, fmt.Errorf("decode sidecar: %w", err)
+	}
+	return &sidecar, nil
+}
+
+// fileSidecars are the files that accompany a recording file.
+type fileSidecars struct {
+	cue     *cueSheet    // Nil if the file has none
+	summary *fileSummary // Written as JSON once the file is complete
+}
+
+// finishSidecars writes the metadata sidecar of the completed file at audioPath and
+// returns the paths of all sidecars to upload with it.
+func (r *GenericRecorder) finishSidecars(audioPath string, sidecars fileSidecars) []string {
+	if sidecars.summary != nil {
+		defer sidecars.summary.turn.release()
+	}
+
+	var paths []string
+	if sidecars.cue != nil {
+		path, err := sidecars.cue.close()
+		if err != nil {
+			slog.Warn("failed to write cue sheet", "id", r.id, "error", err)
+		}
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if sidecars.summary != nil && audioPath != "" {
+		path, err := r.writeSidecar(audioPath, sidecars.summary)
+		if err != nil {
+			slog.Warn("failed to write sidecar", "id", r.id, "file", filepath.Base(audioPath), "error", err)
+		} else {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
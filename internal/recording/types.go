@@ -3,20 +3,39 @@ package recording
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 
 	"github.com/oszuidwest/zwfm-encoder/internal/types"
 )
 
 // Sentinel errors for recording operations.
 var (
-	// ErrHourlyRecorderNotControllable is returned when trying to start/stop an hourly recorder via API.
-	ErrHourlyRecorderNotControllable = errors.New("hourly recorders cannot be started/stopped via API")
+	// ErrRecorderNotControllable is returned when trying to start/stop a recorder that is not on-demand via API.
+	ErrRecorderNotControllable = errors.New("only on-demand recorders can be started/stopped via API")
 
 	// ErrAlreadyRecording is returned when trying to start a recorder that is already recording.
 	ErrAlreadyRecording = errors.New("recorder is already recording")
 
 	// ErrNotRecording is returned when trying to stop a recorder that is not recording.
 	ErrNotRecording = errors.New("recorder is not recording")
+
+	// ErrRecordingNotFound is returned when a recorder has no file with the requested name.
+	ErrRecordingNotFound = errors.New("recording not found")
+
+	// ErrInvalidExport is returned when an export job has an invalid range or codec.
+	ErrInvalidExport = errors.New("invalid export")
+
+	// ErrExportNotFound is returned when a recorder has no export job with the requested ID.
+	ErrExportNotFound = errors.New("export not found")
+
+	// ErrExportNotReady is returned when the result of an export job that has not finished is requested.
+	ErrExportNotReady = errors.New("export is not finished")
+
+	// ErrInvalidRange is returned when a time range to verify is empty or too long.
+	ErrInvalidRange = errors.New("invalid range")
 )
 
 // S3Config is the configuration for S3-compatible storage.
@@ -32,8 +51,32 @@ func (c *S3Config) IsConfigured() bool {
 	return c.Bucket != "" && c.AccessKeyID != "" && c.SecretAccessKey != ""
 }
 
-// DefaultTempDir is the default temporary directory for recordings.
-const DefaultTempDir = "/tmp/encoder-recordings"
+// DefaultTempDir returns the platform-specific directory for the files of
+// S3-only recorders waiting for their upload, with their upload journals, and
+// for exports. It must survive a reboot, so it is not under the system temp
+// directory. Additional programmes pass their ID to get a separate directory.
+func DefaultTempDir(port int, programme string) string {
+	portStr := filepath.Join(strconv.Itoa(port), programme)
+	switch runtime.GOOS {
+	case "windows":
+		// %PROGRAMDATA% is typically C:\ProgramData
+		programData := os.Getenv("PROGRAMDATA")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		return filepath.Join(programData, "encoder", "recordings", portStr)
+	default: // linux, darwin
+		//nolint:gocritic // Intentional absolute path for Unix systems
+		return filepath.Join("/var/lib/encoder/recordings", portStr)
+	}
+}
+
+// FallbackTempDir returns the directory used when DefaultTempDir cannot be
+// created. It is under the system temp directory, so files waiting there may
+// not survive a reboot.
+func FallbackTempDir(port int, programme string) string {
+	return filepath.Join(os.TempDir(), "encoder-recordings", strconv.Itoa(port), programme)
+}
 
 // RecorderToS3Config extracts S3 configuration from a Recorder.
 func RecorderToS3Config(r *types.Recorder) *S3Config {
@@ -0,0 +1,239 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/types"
+)
+
+// journalName is the file in a recorder's output directory that lists the
+// files waiting for their upload to S3, so they survive a restart.
+const journalName = ".uploads.json"
+
+// journalEntry is a file in the upload journal.
+type journalEntry struct {
+	File         string    `json:"file"`
+	S3Key        string    `json:"s3_key"`
+	ContentType  string    `json:"content_type"`
+	FirstAttempt time.Time `json:"first_attempt"`
+	RetryCount   int       `json:"retry_count,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// journalAdd records a file as waiting for its upload. A file already in the
+// journal keeps its first attempt.
+func (r *GenericRecorder) journalAdd(req uploadRequest) {
+	r.updateJournal(filepath.Dir(req.localPath), func(entries []journalEntry) []journalEntry {
+		if slices.ContainsFunc(entries, func(e journalEntry) bool { return e.File == filepath.Base(req.localPath) }) {
+			return entries
+		}
+		return append(entries, journalEntry{
+			File:         filepath.Base(req.localPath),
+			S3Key:        req.s3Key,
+			ContentType:  req.contentType,
+			FirstAttempt: time.Now(),
+		})
+	})
+}
+
+// journalRetry records a failed retry of a pending upload.
+func (r *GenericRecorder) journalRetry(p *pendingUpload) {
+	r.updateJournal(filepath.Dir(p.request.localPath), func(entries []journalEntry) []journalEntry {
+		for i := range entries {
+			if entries[i].File == filepath.Base(p.request.localPath) {
+				entries[i].RetryCount = p.retryCount
+				entries[i].LastError = p.lastError
+			}
+		}
+		return entries
+	})
+}
+
+// journalRemove removes a file that reached S3 or was given up on.
+func (r *GenericRecorder) journalRemove(localPath string) {
+	r.updateJournal(filepath.Dir(localPath), func(entries []journalEntry) []journalEntry {
+		return slices.DeleteFunc(entries, func(e journalEntry) bool { return e.File == filepath.Base(localPath) })
+	})
+}
+
+// updateJournal rewrites the journal in dir with the entries returned by
+// update. The journal is replaced atomically and removed once empty.
+func (r *GenericRecorder) updateJournal(dir string, update func([]journalEntry) []journalEntry) {
+	r.journalMu.Lock()
+	defer r.journalMu.Unlock()
+
+	path := filepath.Join(dir, journalName)
+	entries, err := readJournal(path)
+	if err != nil {
+		slog.Warn("failed to read upload journal", "id", r.id, "path", path, "error", err)
+	}
+	if err := writeJournal(path, update(entries)); err != nil {
+		slog.Warn("failed to write upload journal", "id", r.id, "path", path, "error", err)
+	}
+}
+
+// readJournal returns the entries of the journal at path, or none if there is
+// no journal.
+func readJournal(path string) ([]journalEntry, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // Path is inside the recorder's output directory
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []journalEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decode upload journal: %w", err)
+	}
+	return entries, nil
+}
+
+// writeJournal replaces the journal at path with entries.
+func writeJournal(path string, entries []journalEntry) error {
+	if len(entries) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode upload journal: %w", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// resumeUploads queues the uploads that did not reach S3 before the last
+// shutdown: the files in the journal, and recordings of the last
+// MaxUploadRetryAge in the output directory that are not in S3, such as files
+// that were being written when the power was cut. Uploads keep their first
+// attempt, so the 24-hour abandon policy still applies.
+func (r *GenericRecorder) resumeUploads() {
+	r.mu.RLock()
+	cfg := r.config
+	dir := r.outputDirLocked()
+	r.mu.RUnlock()
+
+	if cfg.StorageMode == types.StorageLocal || !r.isS3Configured() {
+		return
+	}
+
+	journal, err := readJournal(filepath.Join(dir, journalName))
+	if err != nil {
+		slog.Warn("failed to read upload journal", "id", r.id, "dir", dir, "error", err)
+	}
+	pending := make(map[string]pendingUpload, len(journal))
+	for _, e := range journal {
+		pending[e.File] = pendingUpload{
+			request: uploadRequest{
+				localPath:   filepath.Join(dir, e.File),
+				s3Key:       e.S3Key,
+				contentType: e.ContentType,
+			},
+			firstAttempt: e.FirstAttempt,
+			retryCount:   e.RetryCount,
+			lastError:    e.LastError,
+		}
+	}
+
+	for _, p := range r.unjournaledUploads(&cfg, dir, pending) {
+		pending[filepath.Base(p.request.localPath)] = p
+		r.journalAdd(p.request)
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	for name, p := range pending {
+		if info, err := os.Stat(p.request.localPath); err == nil {
+			p.request.fileSize = info.Size()
+		}
+		if !r.uploading[p.request.localPath] && !r.isOpenFileLocked(name) {
+			r.uploading[p.request.localPath] = true
+			r.retryQueue = append(r.retryQueue, p)
+		}
+	}
+	r.mu.Unlock()
+
+	slog.Info("resuming pending uploads", "id", r.id, "files", len(pending))
+	r.processRetryQueue()
+}
+
+// unjournaledUploads returns the recordings and sidecars in dir from the last
+// MaxUploadRetryAge that are neither in the journal nor in S3.
+func (r *GenericRecorder) unjournaledUploads(cfg *types.Recorder, dir string, journaled map[string]pendingUpload) []pendingUpload {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("failed to scan for pending uploads", "id", r.id, "dir", dir, "error", err)
+		}
+		return nil
+	}
+
+	safeName := sanitizeFilename(cfg.Name)
+	candidates := make(map[string]time.Time)
+	for _, entry := range entries {
+		name := entry.Name()
+		if _, ok := recordingTime(safeName, name); !ok || entry.IsDir() {
+			continue
+		}
+		if _, ok := journaled[name]; ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) > MaxUploadRetryAge {
+			continue
+		}
+		candidates[name] = info.ModTime()
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	inS3 := make(map[string]*Recording)
+	if err := r.listS3Recordings(ctx, safeName, inS3); err != nil {
+		slog.Warn("failed to list S3 for pending uploads", "id", r.id, "error", err)
+		return nil
+	}
+
+	var uploads []pendingUpload
+	for name, modTime := range candidates {
+		if inS3[name] != nil {
+			continue
+		}
+		req, ok := r.prepareUploadRequest(filepath.Join(dir, name))
+		if !ok {
+			continue
+		}
+		uploads = append(uploads, pendingUpload{request: req, firstAttempt: modTime, lastError: "interrupted"})
+	}
+	return uploads
+}
+
+// isOpenFileLocked reports whether name is the file being recorded, the file
+// prepared for the next rotation, or one of their sidecars.
+// Must be called with r.mu held.
+func (r *GenericRecorder) isOpenFileLocked(name string) bool {
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	for _, path := range []string{r.currentFile, r.nextFile} {
+		if path != "" && strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) == stem {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,447 @@
+// Package rtp sends PCM audio as RTP (RFC 3551 L16/L24) in a form AES67
+// receivers accept, with optional SAP announcements.
+package rtp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/audio"
+)
+
+const (
+	// DefaultPayloadType is the dynamic payload type used when none is configured.
+	DefaultPayloadType = 96
+	// DefaultPacketTimeMS is the packet time used when none is configured.
+	DefaultPacketTimeMS = 1
+	// DefaultTTL is the multicast TTL used when none is configured.
+	DefaultTTL = 16
+
+	// sapAddress is the SAP announcement group used by AES67 devices.
+	sapAddress = "239.255.255.255:9875"
+	// sapInterval is the time between SAP announcements.
+	sapInterval = 30 * time.Second
+
+	// prebuffer is the audio queued before sending starts, which absorbs the
+	// ~100ms chunks the audio arrives in.
+	prebuffer = 200 * time.Millisecond
+	// maxBuffer is the queued audio above which the oldest packets are dropped.
+	maxBuffer = 500 * time.Millisecond
+
+	headerSize = 12
+)
+
+// Encoding is the RTP sample format.
+type Encoding string
+
+const (
+	// EncodingL16 is 16-bit linear PCM.
+	EncodingL16 Encoding = "L16"
+	// EncodingL24 is 24-bit linear PCM, the AES67 default.
+	EncodingL24 Encoding = "L24"
+)
+
+// Config configures an RTP output. The destination is the stream's host and port.
+type Config struct {
+	Encoding     Encoding `json:"encoding,omitempty"`       // Empty = L24
+	PayloadType  int      `json:"payload_type,omitempty"`   // 0 = DefaultPayloadType
+	PacketTimeMS int      `json:"packet_time_ms,omitempty"` // 0 = DefaultPacketTimeMS
+	TTL          int      `json:"ttl,omitempty"`            // 0 = DefaultTTL
+	SAP          bool     `json:"sap,omitempty"`            // Announce the session with SAP/SDP
+	RefClock     string   `json:"ref_clock,omitempty"`      // SDP ts-refclk value; empty = local MAC
+}
+
+// Validate reports an error if a setting is out of range.
+func (c *Config) Validate() error {
+	switch c.Encoding {
+	case "", EncodingL16, EncodingL24:
+	default:
+		return fmt.Errorf("rtp.encoding: must be L16 or L24")
+	}
+	if c.PayloadType != 0 && (c.PayloadType < 96 || c.PayloadType > 127) {
+		return fmt.Errorf("rtp.payload_type: must be between 96 and 127")
+	}
+	switch c.PacketTimeMS {
+	case 0, 1, 4:
+	default:
+		return fmt.Errorf("rtp.packet_time_ms: must be 1 or 4")
+	}
+	if c.TTL < 0 || c.TTL > 255 {
+		return fmt.Errorf("rtp.ttl: must be between 1 and 255")
+	}
+	if c.RefClock != "" && !validRefClock(c.RefClock) {
+		return fmt.Errorf("rtp.ref_clock: must be local, localmac=..., ntp=... or ptp=...")
+	}
+	return nil
+}
+
+// validRefClock reports whether v is an RFC 7273 clock source without line breaks.
+func validRefClock(v string) bool {
+	if strings.ContainsAny(v, "\r\n") {
+		return false
+	}
+	if v == "local" {
+		return true
+	}
+	for _, prefix := range []string{"localmac=", "ntp=", "ptp="} {
+		if len(v) > len(prefix) && strings.HasPrefix(v, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// EncodingOrDefault returns Encoding, or [EncodingL24] if not set.
+func (c *Config) EncodingOrDefault() Encoding {
+	if c.Encoding == "" {
+		return EncodingL24
+	}
+	return c.Encoding
+}
+
+// SampleBytes returns the size of one sample in the configured encoding.
+func (c *Config) SampleBytes() int {
+	if c.EncodingOrDefault() == EncodingL16 {
+		return 2
+	}
+	return 3
+}
+
+// Sender paces PCM into RTP packets to one destination. One sender lives
+// across encoder restarts so the SSRC, sequence and announcements continue.
+type Sender struct {
+	name         string
+	dest         *net.UDPAddr
+	conn         *net.UDPConn
+	encoding     Encoding
+	payloadType  byte
+	packetTime   time.Duration
+	packetFrames int
+	packetBytes  int
+	ttl          int
+	refClock     string
+	ssrc         uint32
+	sessionID    uint64
+
+	mu  sync.Mutex // Held by Run, so restarts continue the sequence in order
+	seq uint16
+
+	sap     *net.UDPConn
+	sapMsg  uint16
+	sapStop chan struct{}
+	sapDone chan struct{}
+}
+
+// NewSender creates a sender to host:port. name is the SDP session name.
+func NewSender(cfg Config, host string, port int, name string) (*Sender, error) {
+	dest, err := net.ResolveUDPAddr("udp4", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, fmt.Errorf("resolve rtp destination: %w", err)
+	}
+
+	ttl := cfg.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	conn, err := dialUDP(dest, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("open rtp socket: %w", err)
+	}
+
+	packetTimeMS := cfg.PacketTimeMS
+	if packetTimeMS == 0 {
+		packetTimeMS = DefaultPacketTimeMS
+	}
+	payloadType := cfg.PayloadType
+	if payloadType == 0 {
+		payloadType = DefaultPayloadType
+	}
+	packetFrames := audio.SampleRate * packetTimeMS / 1000
+
+	s := &Sender{
+		name:         name,
+		dest:         dest,
+		conn:         conn,
+		encoding:     cfg.EncodingOrDefault(),
+		payloadType:  byte(payloadType),
+		packetTime:   time.Duration(packetTimeMS) * time.Millisecond,
+		packetFrames: packetFrames,
+		packetBytes:  packetFrames * audio.Channels * cfg.SampleBytes(),
+		ttl:          ttl,
+		refClock:     cfg.RefClock,
+		ssrc:         rand.Uint32(),             //nolint:gosec // SSRC only needs to be unlikely to collide
+		sessionID:    uint64(time.Now().Unix()), //nolint:gosec // Current time is positive
+		seq:          uint16(rand.Uint32()),     //nolint:gosec // Random initial sequence number per RFC 3550
+	}
+
+	if s.refClock == "" {
+		s.refClock = localClock(conn.LocalAddr().(*net.UDPAddr).IP)
+	}
+
+	if cfg.SAP {
+		if err := s.startSAP(); err != nil {
+			_ = conn.Close() //nolint:errcheck // Best-effort cleanup after failure
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// dialUDP opens a UDP socket to dest with the given TTL.
+func dialUDP(dest *net.UDPAddr, ttl int) (*net.UDPConn, error) {
+	conn, err := net.DialUDP("udp4", nil, dest)
+	if err != nil {
+		return nil, err
+	}
+	if err := setTTL(conn, ttl, dest.IP.IsMulticast()); err != nil {
+		_ = conn.Close() //nolint:errcheck // Best-effort cleanup after failure
+		return nil, fmt.Errorf("set ttl: %w", err)
+	}
+	return conn, nil
+}
+
+// localClock returns the RFC 7273 clock source of the unsynchronised system
+// clock: the MAC address of the interface that owns ip, or "local" if it has none.
+func localClock(ip net.IP) string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "local"
+	}
+	for _, iface := range ifaces {
+		if len(iface.HardwareAddr) == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return "localmac=" + strings.ToUpper(strings.ReplaceAll(iface.HardwareAddr.String(), ":", "-"))
+			}
+		}
+	}
+	return "local"
+}
+
+// Run sends the big-endian PCM read from r until it ends. Sending starts
+// once enough audio is queued; until then, and whenever the queue runs dry,
+// silence keeps the stream continuous for receivers. Packets are sent on
+// deadlines derived from the start time, so pacing does not drift from the
+// system clock that the timestamps follow.
+func (s *Sender) Run(r io.Reader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	maxPackets := int(maxBuffer / s.packetTime)
+	primePackets := int(prebuffer / s.packetTime)
+	packets := make(chan []byte, maxPackets)
+	readErr := make(chan error, 1)
+
+	go func() {
+		defer close(packets)
+		br := bufio.NewReader(r)
+		for {
+			buf := make([]byte, s.packetBytes)
+			if _, err := io.ReadFull(br, buf); err != nil {
+				readErr <- err
+				return
+			}
+			// Drop-oldest when the source runs ahead of the wall clock
+			select {
+			case packets <- buf:
+			default:
+				select {
+				case <-packets:
+				default:
+				}
+				select {
+				case packets <- buf:
+				default:
+				}
+			}
+		}
+	}()
+
+	// The media clock follows the system clock; see [Config.RefClock]
+	start := time.Now()
+	base := mediaClock(start)
+	silence := make([]byte, s.packetBytes)
+	packet := make([]byte, headerSize+s.packetBytes)
+	primed, ended := false, false
+	var endErr, writeErr error
+
+	ticker := time.NewTicker(s.packetTime)
+	defer ticker.Stop()
+
+	var sent int64
+	for now := start; ; now = <-ticker.C {
+		due := int64(now.Sub(start)/s.packetTime) + 1
+		if due-sent > int64(maxPackets) {
+			// Stalled far behind; skip ahead instead of bursting, keeping
+			// the timestamps on the clock
+			sent = due - 1
+		}
+
+		for ; sent < due; sent++ {
+			if !ended {
+				select {
+				case endErr = <-readErr:
+					ended = true
+				default:
+				}
+			}
+			// Once FFmpeg has exited, play out what is queued
+			primed = primed || ended || len(packets) >= primePackets
+
+			payload := silence
+			if primed {
+				select {
+				case p, ok := <-packets:
+					if !ok {
+						return endError(endErr)
+					}
+					payload = p
+				default:
+					// Underrun; build the queue back up
+					primed = false
+				}
+			}
+
+			timestamp := base + uint32(sent)*uint32(s.packetFrames) //nolint:gosec // Wraps by design
+			s.header(packet, timestamp)
+			copy(packet[headerSize:], payload)
+			if _, err := s.conn.Write(packet); err != nil && writeErr == nil && !errors.Is(err, net.ErrClosed) {
+				writeErr = err
+				slog.Warn("rtp send failed", "destination", s.dest.String(), "error", err)
+			}
+			s.seq++
+		}
+	}
+}
+
+// endError returns the error that ended the input, or nil if it simply ended.
+func endError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, os.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// header writes the RTP header for the next packet into p.
+func (s *Sender) header(p []byte, timestamp uint32) {
+	p[0] = 0x80 // Version 2, no padding, extension or CSRCs
+	p[1] = s.payloadType
+	binary.BigEndian.PutUint16(p[2:], s.seq)
+	binary.BigEndian.PutUint32(p[4:], timestamp)
+	binary.BigEndian.PutUint32(p[8:], s.ssrc)
+}
+
+// mediaClock returns the RTP timestamp of t: samples since the Unix epoch, modulo 2^32.
+func mediaClock(t time.Time) uint32 {
+	sec := uint64(t.Unix())                                         //nolint:gosec // Current time is positive
+	nsec := uint64(t.Nanosecond())                                  //nolint:gosec // Nanoseconds are positive
+	return uint32(sec*audio.SampleRate + nsec*audio.SampleRate/1e9) //nolint:gosec // Wraps by design
+}
+
+// SDP returns the session description of the stream.
+func (s *Sender) SDP() string {
+	origin := s.conn.LocalAddr().(*net.UDPAddr).IP.String()
+	conn := s.dest.IP.String()
+	if s.dest.IP.IsMulticast() {
+		conn += "/" + strconv.Itoa(s.ttl)
+	}
+	return fmt.Sprintf("v=0\r\n"+
+		"o=- %d 0 IN IP4 %s\r\n"+
+		"s=%s\r\n"+
+		"c=IN IP4 %s\r\n"+
+		"t=0 0\r\n"+
+		"m=audio %d RTP/AVP %d\r\n"+
+		"a=rtpmap:%d %s/%d/%d\r\n"+
+		"a=ptime:%d\r\n"+
+		"a=ts-refclk:%s\r\n"+
+		"a=mediaclk:direct=0\r\n"+
+		"a=sendonly\r\n",
+		s.sessionID, origin, s.name, conn,
+		s.dest.Port, s.payloadType,
+		s.payloadType, s.encoding, audio.SampleRate, audio.Channels,
+		s.packetTime.Milliseconds(), s.refClock)
+}
+
+// startSAP opens the announcement socket and starts announcing.
+func (s *Sender) startSAP() error {
+	dest, err := net.ResolveUDPAddr("udp4", sapAddress)
+	if err != nil {
+		return fmt.Errorf("resolve sap address: %w", err)
+	}
+	conn, err := dialUDP(dest, s.ttl)
+	if err != nil {
+		return fmt.Errorf("open sap socket: %w", err)
+	}
+
+	s.sap = conn
+	s.sapMsg = uint16(rand.Uint32()) //nolint:gosec // Message ID hash only needs to be unlikely to collide
+	s.sapStop = make(chan struct{})
+	s.sapDone = make(chan struct{})
+	go s.runSAP()
+	return nil
+}
+
+// runSAP announces the session until stopped, then announces its deletion.
+func (s *Sender) runSAP() {
+	defer close(s.sapDone)
+
+	ticker := time.NewTicker(sapInterval)
+	defer ticker.Stop()
+
+	for {
+		s.announce(false)
+		select {
+		case <-ticker.C:
+		case <-s.sapStop:
+			s.announce(true)
+			return
+		}
+	}
+}
+
+// announce sends a SAP announcement (RFC 2974) or deletion of the session.
+func (s *Sender) announce(deletion bool) {
+	msg := []byte{0x20, 0, 0, 0} // Version 1, IPv4, no authentication
+	if deletion {
+		msg[0] |= 0x04
+	}
+	binary.BigEndian.PutUint16(msg[2:], s.sapMsg)
+	msg = append(msg, s.conn.LocalAddr().(*net.UDPAddr).IP.To4()...)
+	msg = append(msg, "application/sdp\x00"...)
+	msg = append(msg, s.SDP()...)
+
+	if _, err := s.sap.Write(msg); err != nil {
+		slog.Warn("sap announcement failed", "error", err)
+	}
+}
+
+// Close stops announcements, announcing the session's end, and closes the sockets.
+func (s *Sender) Close() {
+	if s.sap != nil {
+		close(s.sapStop)
+		<-s.sapDone
+		if err := s.sap.Close(); err != nil {
+			slog.Warn("failed to close sap socket", "error", err)
+		}
+	}
+	if err := s.conn.Close(); err != nil {
+		slog.Warn("failed to close rtp socket", "error", err)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package rtp
+
+import (
+	"net"
+	"syscall"
+)
+
+// setTTL sets the unicast or multicast TTL of conn.
+func setTTL(conn *net.UDPConn, ttl int, multicast bool) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	opt := syscall.IP_TTL
+	if multicast {
+		opt = syscall.IP_MULTICAST_TTL
+	}
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, opt, ttl)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,211 @@
+// Package schedule evaluates weekly on-air windows with dated exceptions.
+package schedule
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	// dateLayout is the format of exception dates.
+	dateLayout = "2006-01-02"
+	// lookahead limits the search for the next transition.
+	lookahead = 8 * 24 * time.Hour
+)
+
+// dayNames maps day abbreviations to weekdays.
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window is a daily time range on the given weekdays. A window whose end is
+// not after its start runs past midnight into the next day.
+type Window struct {
+	Days  []string `json:"days"`  // mon, tue, wed, thu, fri, sat, sun
+	Start string   `json:"start"` // HH:MM
+	End   string   `json:"end"`   // HH:MM; 24:00 is the end of the day
+}
+
+// Exception replaces the windows on one date. Without start and end the
+// whole date is off air, as on a holiday.
+type Exception struct {
+	Date  string `json:"date"`            // YYYY-MM-DD
+	Start string `json:"start,omitempty"` // HH:MM
+	End   string `json:"end,omitempty"`   // HH:MM
+}
+
+// Config is a weekly schedule. An empty schedule is always on air.
+type Config struct {
+	Windows    []Window    `json:"windows,omitempty"`
+	Exceptions []Exception `json:"exceptions,omitempty"`
+}
+
+// IsSet reports whether the schedule has any windows or exceptions.
+func (c *Config) IsSet() bool {
+	return len(c.Windows) > 0 || len(c.Exceptions) > 0
+}
+
+// Validate reports an error if a day, time or date cannot be parsed.
+func (c *Config) Validate() error {
+	for i, w := range c.Windows {
+		if len(w.Days) == 0 {
+			return fmt.Errorf("schedule.windows[%d].days: at least one day is required", i)
+		}
+		for _, day := range w.Days {
+			if _, ok := dayNames[day]; !ok {
+				return fmt.Errorf("schedule.windows[%d].days: unknown day %q", i, day)
+			}
+		}
+		if start, err := parseClock(w.Start); err != nil {
+			return fmt.Errorf("schedule.windows[%d].start: %w", i, err)
+		} else if start == 24*60 {
+			return fmt.Errorf("schedule.windows[%d].start: must be before 24:00", i)
+		}
+		if _, err := parseClock(w.End); err != nil {
+			return fmt.Errorf("schedule.windows[%d].end: %w", i, err)
+		}
+	}
+	for i, e := range c.Exceptions {
+		if _, err := time.Parse(dateLayout, e.Date); err != nil {
+			return fmt.Errorf("schedule.exceptions[%d].date: must be YYYY-MM-DD", i)
+		}
+		if (e.Start == "") != (e.End == "") {
+			return fmt.Errorf("schedule.exceptions[%d]: start and end must be set together", i)
+		}
+		if e.Start == "" {
+			continue
+		}
+		start, err := parseClock(e.Start)
+		if err != nil {
+			return fmt.Errorf("schedule.exceptions[%d].start: %w", i, err)
+		}
+		end, err := parseClock(e.End)
+		if err != nil {
+			return fmt.Errorf("schedule.exceptions[%d].end: %w", i, err)
+		}
+		if end <= start {
+			return fmt.Errorf("schedule.exceptions[%d]: end must be after start", i)
+		}
+	}
+	return nil
+}
+
+// Allows reports whether t falls inside the schedule. An empty schedule
+// allows any time. On a date with an exception only the exception applies,
+// including for windows that started the evening before.
+func (c *Config) Allows(t time.Time) bool {
+	if !c.IsSet() {
+		return true
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	if e, ok := c.exception(t); ok {
+		if e.Start == "" {
+			return false
+		}
+		start, _ := parseClock(e.Start) //nolint:errcheck // Validated on save
+		end, _ := parseClock(e.End)     //nolint:errcheck // Validated on save
+		return minute >= start && minute < end
+	}
+
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+	for i := range c.Windows {
+		w := &c.Windows[i]
+		start, _ := parseClock(w.Start) //nolint:errcheck // Validated on save
+		end, _ := parseClock(w.End)     //nolint:errcheck // Validated on save
+		if end > start {
+			if w.on(today) && minute >= start && minute < end {
+				return true
+			}
+			continue
+		}
+		// Overnight: the evening of a listed day and the morning after
+		if (w.on(today) && minute >= start) || (w.on(yesterday) && minute < end) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the first time after t at which Allows changes, or the zero
+// time if it does not change within a week.
+func (c *Config) Next(t time.Time) time.Time {
+	if !c.IsSet() {
+		return time.Time{}
+	}
+
+	// Changes only happen at midnight or at the start or end of a window or exception
+	var clocks []string
+	for _, w := range c.Windows {
+		clocks = append(clocks, w.Start, w.End)
+	}
+	for _, e := range c.Exceptions {
+		if e.Start != "" {
+			clocks = append(clocks, e.Start, e.End)
+		}
+	}
+	minutes := []int{0}
+	for _, clock := range clocks {
+		m, _ := parseClock(clock) //nolint:errcheck // Validated on save
+		minutes = append(minutes, m)
+	}
+	slices.Sort(minutes)
+	minutes = slices.Compact(minutes)
+
+	now := c.Allows(t)
+	y, mo, d := t.Date()
+	for day := range int(lookahead / (24 * time.Hour)) {
+		for _, m := range minutes {
+			candidate := time.Date(y, mo, d+day, 0, m, 0, 0, t.Location())
+			if candidate.After(t) && c.Allows(candidate) != now {
+				return candidate
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// exception returns the exception for the date of t, if any.
+func (c *Config) exception(t time.Time) (Exception, bool) {
+	date := t.Format(dateLayout)
+	for _, e := range c.Exceptions {
+		if e.Date == date {
+			return e, true
+		}
+	}
+	return Exception{}, false
+}
+
+// on reports whether the window applies to the given weekday.
+func (w *Window) on(day time.Weekday) bool {
+	for _, name := range w.Days {
+		if dayNames[name] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock returns the minute of the day of an HH:MM time. 24:00 is allowed
+// as the end of the day.
+func parseClock(s string) (int, error) {
+	var h, m int
+	if n, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || n != 2 || len(s) != 5 {
+		return 0, fmt.Errorf("must be HH:MM")
+	}
+	if h == 24 && m == 0 {
+		return 24 * 60, nil
+	}
+	if h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("must be between 00:00 and 24:00")
+	}
+	return h*60 + m, nil
+}
@@ -0,0 +1,139 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxPadMinutes limits the padding before and after a show.
+const MaxPadMinutes = 60
+
+// Show is a programme that airs every week on the given days or once on a
+// date. Recordings start PadBefore minutes early and end PadAfter minutes late.
+type Show struct {
+	Title        string   `json:"title"`
+	Days         []string `json:"days,omitempty"` // Weekly: mon, tue, wed, thu, fri, sat, sun
+	Date         string   `json:"date,omitempty"` // Once: YYYY-MM-DD
+	Start        string   `json:"start"`          // HH:MM
+	End          string   `json:"end"`            // HH:MM; past midnight when not after start
+	PadBefore    int      `json:"pad_before_minutes,omitempty"`
+	PadAfter     int      `json:"pad_after_minutes,omitempty"`
+	NameTemplate string   `json:"name_template,omitempty"` // Empty = {title}
+}
+
+// Occurrence is one airing of a show. Start and End include the padding.
+type Occurrence struct {
+	Start time.Time
+	End   time.Time
+	Air   time.Time // Scheduled start without padding
+}
+
+// Validate reports an error if the show has no title, no days or date, or
+// a time that cannot be parsed.
+func (s *Show) Validate() error {
+	if strings.TrimSpace(s.Title) == "" {
+		return fmt.Errorf("title: is required")
+	}
+	if (len(s.Days) == 0) == (s.Date == "") {
+		return fmt.Errorf("days: set either days or a date")
+	}
+	for _, day := range s.Days {
+		if _, ok := dayNames[day]; !ok {
+			return fmt.Errorf("days: unknown day %q", day)
+		}
+	}
+	if s.Date != "" {
+		if _, err := time.Parse(dateLayout, s.Date); err != nil {
+			return fmt.Errorf("date: must be YYYY-MM-DD")
+		}
+	}
+	if start, err := parseClock(s.Start); err != nil {
+		return fmt.Errorf("start: %w", err)
+	} else if start == 24*60 {
+		return fmt.Errorf("start: must be before 24:00")
+	}
+	if _, err := parseClock(s.End); err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+	if s.PadBefore < 0 || s.PadBefore > MaxPadMinutes {
+		return fmt.Errorf("pad_before_minutes: must be between 0 and %d", MaxPadMinutes)
+	}
+	if s.PadAfter < 0 || s.PadAfter > MaxPadMinutes {
+		return fmt.Errorf("pad_after_minutes: must be between 0 and %d", MaxPadMinutes)
+	}
+	return nil
+}
+
+// Current returns the occurrence whose padded span contains t.
+func (s *Show) Current(t time.Time) (Occurrence, bool) {
+	y, m, d := t.Date()
+	// Yesterday's occurrence may run past midnight; tomorrow's padding may start before it
+	for day := -1; day <= 1; day++ {
+		o, ok := s.occurrence(time.Date(y, m, d+day, 0, 0, 0, 0, t.Location()))
+		if ok && !t.Before(o.Start) && t.Before(o.End) {
+			return o, true
+		}
+	}
+	return Occurrence{}, false
+}
+
+// Next returns the first occurrence that starts after t. A one-off show
+// whose date has passed has none.
+func (s *Show) Next(t time.Time) (Occurrence, bool) {
+	if s.Date != "" {
+		day, err := time.ParseInLocation(dateLayout, s.Date, t.Location())
+		if err != nil {
+			return Occurrence{}, false
+		}
+		o, _ := s.occurrence(day)
+		return o, o.Start.After(t)
+	}
+
+	y, m, d := t.Date()
+	for day := range int(lookahead / (24 * time.Hour)) {
+		o, ok := s.occurrence(time.Date(y, m, d+day, 0, 0, 0, 0, t.Location()))
+		if ok && o.Start.After(t) {
+			return o, true
+		}
+	}
+	return Occurrence{}, false
+}
+
+// Name returns the name template with {title}, {date}, {time} and {weekday}
+// replaced for the occurrence.
+func (s *Show) Name(o Occurrence) string {
+	template := s.NameTemplate
+	if template == "" {
+		template = "{title}"
+	}
+	return strings.NewReplacer(
+		"{title}", s.Title,
+		"{date}", o.Air.Format(dateLayout),
+		"{time}", o.Air.Format("15-04"),
+		"{weekday}", strings.ToLower(o.Air.Weekday().String()[:3]),
+	).Replace(template)
+}
+
+// occurrence returns the occurrence that airs on the date of day, if any.
+func (s *Show) occurrence(day time.Time) (Occurrence, bool) {
+	if s.Date != "" {
+		if day.Format(dateLayout) != s.Date {
+			return Occurrence{}, false
+		}
+	} else if w := (Window{Days: s.Days}); !w.on(day.Weekday()) {
+		return Occurrence{}, false
+	}
+
+	start, _ := parseClock(s.Start) //nolint:errcheck // Validated on save
+	end, _ := parseClock(s.End)     //nolint:errcheck // Validated on save
+	if end <= start {
+		end += 24 * 60
+	}
+	y, m, d := day.Date()
+	return Occurrence{
+		Start: time.Date(y, m, d, 0, start-s.PadBefore, 0, 0, day.Location()),
+		End:   time.Date(y, m, d, 0, end+s.PadAfter, 0, 0, day.Location()),
+		Air:   time.Date(y, m, d, 0, start, 0, 0, day.Location()),
+	}, true
+}
@@ -38,8 +38,11 @@ const (
 	outputDirPrefix = "encoder-silence-dumps"
 )
 
-// outputDirForPort returns the output directory for silence dumps, unique per port.
-func outputDirForPort(port int) string {
+// outputDirForPort returns the output directory for silence dumps, unique per port and programme.
+func outputDirForPort(port int, programme string) string {
+	if programme != "" {
+		return filepath.Join(os.TempDir(), fmt.Sprintf("%s-%d-%s", outputDirPrefix, port, programme))
+	}
 	return filepath.Join(os.TempDir(), fmt.Sprintf("%s-%d", outputDirPrefix, port))
 }
 
@@ -30,8 +30,9 @@ type Manager struct {
 }
 
 // NewManager creates a new silence dump manager.
-func NewManager(ffmpegPath string, port int, enabled bool, retentionDays int, onDumpReady DumpCallback) *Manager {
-	outputDir := outputDirForPort(port)
+// Additional programmes pass their ID to get a separate output directory.
+func NewManager(ffmpegPath string, port int, programme string, enabled bool, retentionDays int, onDumpReady DumpCallback) *Manager {
+	outputDir := outputDirForPort(port, programme)
 
 	m := &Manager{
 		ffmpegPath:    ffmpegPath,
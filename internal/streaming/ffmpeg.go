@@ -6,6 +6,7 @@ import (
 	"net/url"
 
 	"github.com/oszuidwest/zwfm-encoder/internal/ffmpeg"
+	"github.com/oszuidwest/zwfm-encoder/internal/rtp"
 	"github.com/oszuidwest/zwfm-encoder/internal/types"
 )
 
@@ -13,14 +14,46 @@ import (
 func BuildFFmpegArgs(stream *types.Stream) []string {
 	codecArgs := stream.CodecArgs()
 	format := stream.Format()
-	srtURL := BuildSRTURL(stream)
 
 	// Start with base input args, add stream-specific flags
 	args := ffmpeg.BaseInputArgs()
 	args = append(args, "-hide_banner", "-loglevel", "warning", "-codec:a")
+
+	if stream.Output() == types.OutputRTP {
+		// Big-endian PCM on stdout for the RTP sender
+		format = "s24be"
+		if stream.RTP.EncodingOrDefault() == rtp.EncodingL16 {
+			format = "s16be"
+		}
+		return append(args, "pcm_"+format, "-f", format, "pipe:1")
+	}
+
 	args = append(args, codecArgs...)
-	args = append(args, "-f", format, srtURL)
-	return args
+
+	if stream.Output() == types.OutputHLS {
+		// Bare frames on stdout for the packager; no Xing frame or ID3 tag
+		if stream.Codec == types.CodecMP3 {
+			args = append(args, "-write_xing", "0", "-id3v2_version", "0")
+		}
+		return append(args, "-f", format, "-flush_packets", "1", "pipe:1")
+	}
+	if stream.Output() == types.OutputExec || stream.Codec == types.CodecOGG {
+		// Encoded audio on stdout, piped into the command or the title chainer
+		return append(args, "-f", format, "-flush_packets", "1", "pipe:1")
+	}
+	return append(args, "-f", format, BuildSRTURL(stream))
+}
+
+// BuildRelayArgs returns FFmpeg arguments that send the bytes on stdin to the
+// SRT destination as they are. Ogg streams pass through the title chainer
+// before they reach SRT, so they are encoded and sent by separate processes.
+func BuildRelayArgs(stream *types.Stream) []string {
+	return []string{
+		"-hide_banner", "-loglevel", "warning",
+		"-f", "data", "-i", "pipe:0",
+		"-map", "0", "-codec", "copy",
+		"-f", "data", BuildSRTURL(stream),
+	}
 }
 
 // BuildSRTURL constructs an SRT streaming URL.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"maps"
 	"slices"
@@ -12,12 +13,19 @@ import (
 	"time"
 
 	"github.com/oszuidwest/zwfm-encoder/internal/ffmpeg"
+	"github.com/oszuidwest/zwfm-encoder/internal/hls"
+	"github.com/oszuidwest/zwfm-encoder/internal/ogg"
+	"github.com/oszuidwest/zwfm-encoder/internal/rtp"
 	"github.com/oszuidwest/zwfm-encoder/internal/types"
 	"github.com/oszuidwest/zwfm-encoder/internal/util"
 )
 
-// errStoppedByUser indicates the stream was intentionally stopped.
-var errStoppedByUser = errors.New("stopped by user")
+var (
+	// errStoppedByUser indicates the stream was intentionally stopped.
+	errStoppedByUser = errors.New("stopped by user")
+	// errStoppedBySchedule indicates the stream was stopped at the end of its schedule window.
+	errStoppedBySchedule = errors.New("stopped by schedule")
+)
 
 // audioBufferSize is the number of audio chunks buffered per stream.
 // At ~100ms per chunk, 5 chunks provides ~500ms of buffer.
@@ -37,13 +45,19 @@ type EventCallback func(streamID, streamName string, event string, message strin
 // Manager orchestrates multiple streams.
 type Manager struct {
 	ffmpegPath    string
+	execAllowlist string // Allowlist file of the commands exec outputs may run
 	streams       map[string]*Stream
-	mu            sync.RWMutex // Protects streams map
+	packagers     map[string]*hls.Packager // HLS packagers; kept across retries
+	senders       map[string]*rtp.Sender   // RTP senders; kept across retries
+	chainers      map[string]*ogg.Chainer  // Title chainers of running Ogg streams
+	artist        string                   // Artist of the title on air
+	title         string                   // Title on air
+	mu            sync.RWMutex             // Protects streams, packagers, senders, chainers and the title
 	onEvent       EventCallback
 	getStreamName func(string) string
 }
 
-// Stream represents a managed SRT stream to a server.
+// Stream represents a managed stream process.
 type Stream struct {
 	result     *ffmpeg.StartResult
 	state      types.ProcessState
@@ -68,11 +82,15 @@ func (s *Stream) closeAudioCh() {
 	})
 }
 
-// NewManager creates a Manager with the given FFmpeg path.
-func NewManager(ffmpegPath string) *Manager {
+// NewManager creates a Manager with the given FFmpeg path and exec allowlist file.
+func NewManager(ffmpegPath, execAllowlist string) *Manager {
 	return &Manager{
-		ffmpegPath: ffmpegPath,
-		streams:    make(map[string]*Stream),
+		ffmpegPath:    ffmpegPath,
+		execAllowlist: execAllowlist,
+		streams:       make(map[string]*Stream),
+		packagers:     make(map[string]*hls.Packager),
+		senders:       make(map[string]*rtp.Sender),
+		chainers:      make(map[string]*ogg.Chainer),
 	}
 }
 
@@ -178,9 +196,9 @@ func (m *Manager) Start(stream *types.Stream) error {
 
 	args := BuildFFmpegArgs(stream)
 
-	slog.Info("starting stream", "stream_id", stream.ID, "host", stream.Host, "port", stream.Port)
+	slog.Info("starting stream", "stream_id", stream.ID, "destination", stream.Destination())
 
-	result, err := ffmpeg.StartProcess(m.ffmpegPath, args)
+	result, err := m.startProcess(stream, args)
 	if err != nil {
 		m.mu.Lock()
 		if m.streams[stream.ID] == placeholder {
@@ -204,6 +222,9 @@ func (m *Manager) Start(stream *types.Stream) error {
 	if m.streams[stream.ID] != placeholder {
 		// Placeholder was removed by Stop/Remove during startup.
 		// Kill the process we just spawned and bail out.
+		if _, restarted := m.streams[stream.ID]; !restarted {
+			m.dropOutputLocked(stream.ID)
+		}
 		m.mu.Unlock()
 		result.Cancel(errStoppedByUser)
 		result.CloseStdin()
@@ -215,7 +236,7 @@ func (m *Manager) Start(stream *types.Stream) error {
 
 	go m.runWriter(stream.ID, s)
 
-	m.emitEvent(stream.ID, "stream_started", fmt.Sprintf("Connecting to %s:%d", stream.Host, stream.Port), "", 0, 0)
+	m.emitEvent(stream.ID, "stream_started", "Connecting to "+stream.Destination(), "", 0, 0)
 
 	// Emit stable event after threshold if still running
 	go func(id string) {
@@ -232,9 +253,128 @@ func (m *Manager) Start(stream *types.Stream) error {
 	return nil
 }
 
+// startProcess launches the FFmpeg process of a stream. HLS and RTP streams
+// write to stdout, which feeds the stream's packager or sender; these are
+// created on first start and kept across retries. Exec streams run their
+// command directly on the PCM, or behind FFmpeg when encoded.
+func (m *Manager) startProcess(stream *types.Stream, args []string) (*ffmpeg.StartResult, error) {
+	var run func(io.Reader) error
+	switch stream.Output() {
+	case types.OutputHLS:
+		m.mu.Lock()
+		packager := m.packagers[stream.ID]
+		if packager == nil {
+			packager = hls.NewPackager(stream.HLS)
+			m.packagers[stream.ID] = packager
+		}
+		m.mu.Unlock()
+		run = packager.Run
+	case types.OutputRTP:
+		m.mu.Lock()
+		sender := m.senders[stream.ID]
+		m.mu.Unlock()
+		if sender == nil {
+			var err error
+			if sender, err = rtp.NewSender(stream.RTP, stream.Host, stream.Port, stream.ID); err != nil {
+				return nil, err
+			}
+			m.mu.Lock()
+			m.senders[stream.ID] = sender
+			m.mu.Unlock()
+		}
+		run = sender.Run
+	case types.OutputExec:
+		if err := stream.Exec.CheckAllowlist(m.execAllowlist); err != nil {
+			return nil, err
+		}
+		if stream.Exec.PCM {
+			return ffmpeg.StartCommand(stream.Exec.Command, stream.Exec.Args)
+		}
+		if stream.Codec == types.CodecOGG {
+			return ffmpeg.StartFilteredPipeline(m.ffmpegPath, args, stream.Exec.Command, stream.Exec.Args, m.newChainer(stream.ID).Copy)
+		}
+		return ffmpeg.StartPipeline(m.ffmpegPath, args, stream.Exec.Command, stream.Exec.Args)
+	default:
+		if stream.Codec == types.CodecOGG {
+			return ffmpeg.StartFilteredPipeline(m.ffmpegPath, args, m.ffmpegPath, BuildRelayArgs(stream), m.newChainer(stream.ID).Copy)
+		}
+		return ffmpeg.StartProcess(m.ffmpegPath, args)
+	}
+
+	result, stdout, err := ffmpeg.StartOutputProcess(m.ffmpegPath, args)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := run(stdout); err != nil {
+			slog.Warn("stream output stopped", "stream_id", stream.ID, "error", err)
+		}
+	}()
+	return result, nil
+}
+
+// newChainer returns the title chainer for a new process of an Ogg stream,
+// starting with the title on air.
+func (m *Manager) newChainer(streamID string) *ogg.Chainer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chainer := ogg.NewChainer(m.artist, m.title)
+	m.chainers[streamID] = chainer
+	return chainer
+}
+
+// SetTitle sets the title on air. Ogg streams carry it in-band from the next
+// page on; other formats have no in-band titles.
+func (m *Manager) SetTitle(artist, title string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.artist, m.title = artist, title
+	for _, chainer := range m.chainers {
+		chainer.SetTitle(artist, title)
+	}
+}
+
+// dropOutputLocked discards the packager, sender or title chainer of a stream.
+// Must be called with m.mu held.
+func (m *Manager) dropOutputLocked(streamID string) {
+	delete(m.packagers, streamID)
+	delete(m.chainers, streamID)
+	if sender, ok := m.senders[streamID]; ok {
+		sender.Close()
+		delete(m.senders, streamID)
+	}
+}
+
+// Packager returns the HLS packager of a stream, or nil if it has none.
+func (m *Manager) Packager(streamID string) *hls.Packager {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.packagers[streamID]
+}
+
+// Sender returns the RTP sender of a stream, or nil if it has none.
+func (m *Manager) Sender(streamID string) *rtp.Sender {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.senders[streamID]
+}
+
 // Stop terminates a stream with proper graceful shutdown.
 func (m *Manager) Stop(streamID string) error {
+	return m.stop(streamID, errStoppedByUser)
+}
+
+// StopScheduled terminates a stream at the end of its schedule window.
+func (m *Manager) StopScheduled(streamID string) error {
+	return m.stop(streamID, errStoppedBySchedule)
+}
+
+// stop terminates a stream, recording cause as the reason.
+func (m *Manager) stop(streamID string, cause error) error {
 	m.mu.Lock()
+	m.dropOutputLocked(streamID)
 	stream, exists := m.streams[streamID]
 	if !exists {
 		m.mu.Unlock()
@@ -265,7 +405,7 @@ func (m *Manager) Stop(streamID string) error {
 	result := stream.result
 	m.mu.Unlock()
 
-	slog.Info("stopping stream", "stream_id", streamID)
+	slog.Info("stopping stream", "stream_id", streamID, "reason", cause)
 
 	// 1. Close audio channel — no more data from distributor.
 	//    Writer's for-range will exit after draining remaining items.
@@ -274,7 +414,7 @@ func (m *Manager) Stop(streamID string) error {
 	// 2. Cancel context — marks stop as intentional. For stream processes
 	//    (no cmd.Cancel set), exec.CommandContext sends SIGKILL, breaking
 	//    the pipe and unblocking any writer stuck in stdin.Write().
-	result.Cancel(errStoppedByUser)
+	result.Cancel(cause)
 
 	// 3. Wait for process exit with timeout escalation.
 	//    Must complete before writerWg.Wait — guarantees the process is
@@ -330,6 +470,10 @@ func (m *Manager) StopAll() error {
 
 	m.mu.Lock()
 	clear(m.streams)
+	for id := range m.senders {
+		m.dropOutputLocked(id)
+	}
+	clear(m.packagers)
 	m.mu.Unlock()
 
 	return errors.Join(errs...)
@@ -461,6 +605,7 @@ func (m *Manager) MarkStopped(streamID string) {
 // Remove deletes a stream from the manager and cleans up its writer goroutine.
 func (m *Manager) Remove(streamID string) {
 	m.mu.Lock()
+	m.dropOutputLocked(streamID)
 	stream, exists := m.streams[streamID]
 	if exists {
 		delete(m.streams, streamID)
@@ -493,6 +638,10 @@ func (m *Manager) handleStreamExit(streamID string, result *ffmpeg.StartResult,
 		m.emitEvent(streamID, "stream_stopped", "Stream stopped by user", "", 0, 0)
 		return
 	}
+	if errors.Is(cause, errStoppedBySchedule) {
+		m.emitEvent(streamID, "stream_stopped", "Stream stopped by schedule", "", 0, 0)
+		return
+	}
 
 	if err != nil {
 		errMsg := util.ExtractLastError(result.Stderr())
@@ -530,6 +679,9 @@ func (m *Manager) shouldContinueRetry(streamID string, ctx StreamContext) (shoul
 	if !stream.IsEnabled() {
 		return false, "stream disabled"
 	}
+	if !stream.Schedule.Allows(time.Now()) {
+		return false, "outside schedule"
+	}
 	retryCount := m.RetryCount(streamID)
 	maxRetries := stream.MaxRetriesOrDefault()
 	if retryCount > maxRetries {
@@ -0,0 +1,254 @@
+// Package tap serves live PCM audio to local readers over a Unix domain socket.
+package tap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/audio"
+)
+
+// staleCheckTimeout bounds the connection attempt that tells a socket in use
+// from one left by an earlier run.
+const staleCheckTimeout = time.Second
+
+// audioBufferSize is the number of PCM chunks (~100ms each) queued for a reader.
+const audioBufferSize = 20
+
+// header is the line sent to a reader on connect, before the first audio.
+var header = fmt.Sprintf("ZWFM-PCM/1 format=s16le rate=%d channels=%d\n", audio.SampleRate, audio.Channels)
+
+// Status describes a connected reader.
+type Status struct {
+	ID          int       `json:"id"`
+	ConnectedAt time.Time `json:"connected_at"`
+	AudioDrops  int64     `json:"audio_drops,omitempty"`
+}
+
+// Server accepts readers on a Unix domain socket and sends each of them the
+// audio passed to WriteAudio. A reader that falls behind loses its oldest
+// queued audio, so a slow reader never holds up the encoder.
+type Server struct {
+	path     string
+	listener net.Listener
+
+	mu      sync.Mutex
+	readers map[*reader]struct{}
+	nextID  int
+	active  atomic.Int32 // Number of connected readers
+	closed  bool
+}
+
+// reader is one connected socket.
+type reader struct {
+	id          int
+	conn        net.Conn
+	connectedAt time.Time
+	audioCh     chan []byte
+	closeOnce   sync.Once
+	done        chan struct{}
+	audioDrops  atomic.Int64
+}
+
+// Listen creates the socket at path and starts accepting readers. A stale
+// socket left by an earlier run is removed; a socket that still accepts
+// connections, or any other file at path, is an error.
+func Listen(path string) (*Server, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("tap socket %s: file exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, staleCheckTimeout); err == nil {
+			_ = conn.Close() //nolint:errcheck // Only probing
+			return nil, fmt.Errorf("tap socket %s: in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale tap socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on tap socket: %w", err)
+	}
+	// Readers run as local users in the same group, such as a second program on the host
+	if err := os.Chmod(path, 0o660); err != nil {
+		slog.Warn("failed to set tap socket permissions", "path", path, "error", err)
+	}
+
+	s := &Server{
+		path:     path,
+		listener: listener,
+		readers:  make(map[*reader]struct{}),
+	}
+	go s.accept()
+	return s, nil
+}
+
+// accept adds readers until the listener is closed.
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Warn("tap socket stopped accepting", "path", s.path, "error", err)
+			}
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			closeConn(conn)
+			return
+		}
+		s.nextID++
+		r := &reader{
+			id:          s.nextID,
+			conn:        conn,
+			connectedAt: time.Now(),
+			audioCh:     make(chan []byte, audioBufferSize),
+			done:        make(chan struct{}),
+		}
+		s.readers[r] = struct{}{}
+		s.active.Add(1)
+		s.mu.Unlock()
+
+		slog.Info("tap reader connected", "path", s.path, "reader", r.id)
+		go s.serve(r)
+	}
+}
+
+// serve writes the header and queued audio to a reader until it disconnects.
+func (s *Server) serve(r *reader) {
+	defer s.remove(r)
+
+	// Readers send nothing; reading only notices when they hang up
+	go func() {
+		_, _ = io.Copy(io.Discard, r.conn) //nolint:errcheck // Any end means the reader is gone
+		r.close()
+	}()
+
+	if _, err := io.WriteString(r.conn, header); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-r.done:
+			return
+		case pcm := <-r.audioCh:
+			if _, err := r.conn.Write(pcm); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// remove drops a reader and closes its connection.
+func (s *Server) remove(r *reader) {
+	r.close()
+
+	s.mu.Lock()
+	if _, ok := s.readers[r]; ok {
+		delete(s.readers, r)
+		s.active.Add(-1)
+	}
+	s.mu.Unlock()
+
+	slog.Info("tap reader disconnected", "path", s.path, "reader", r.id, "audio_drops", r.audioDrops.Load())
+}
+
+// close ends the reader's connection exactly once.
+func (r *reader) close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		closeConn(r.conn)
+	})
+}
+
+// WriteAudio queues PCM for all readers. It never blocks; when a reader
+// falls behind, its oldest queued audio is dropped.
+func (s *Server) WriteAudio(pcm []byte) {
+	if s.active.Load() == 0 {
+		return
+	}
+
+	// Copy data — the caller reuses the buffer
+	buf := make([]byte, len(pcm))
+	copy(buf, pcm)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for r := range s.readers {
+		select {
+		case r.audioCh <- buf:
+		default:
+			select {
+			case <-r.audioCh:
+				r.audioDrops.Add(1)
+			default:
+			}
+			select {
+			case r.audioCh <- buf:
+			default:
+			}
+		}
+	}
+}
+
+// Statuses returns the connected readers, oldest first.
+func (s *Server) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.readers))
+	for r := range s.readers {
+		statuses = append(statuses, Status{
+			ID:          r.id,
+			ConnectedAt: r.connectedAt,
+			AudioDrops:  r.audioDrops.Load(),
+		})
+	}
+	slices.SortFunc(statuses, func(a, b Status) int { return a.ID - b.ID })
+	return statuses
+}
+
+// Close stops accepting readers, disconnects all of them and removes the socket.
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	readers := make([]*reader, 0, len(s.readers))
+	for r := range s.readers {
+		readers = append(readers, r)
+	}
+	s.mu.Unlock()
+
+	// Closing the listener of a Unix socket also removes the socket file
+	if err := s.listener.Close(); err != nil {
+		slog.Warn("failed to close tap socket", "path", s.path, "error", err)
+	}
+	for _, r := range readers {
+		r.close()
+	}
+}
+
+// closeConn closes a connection, logging unexpected failures.
+func closeConn(conn net.Conn) {
+	if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		slog.Debug("failed to close tap connection", "error", err)
+	}
+}
@@ -3,11 +3,22 @@ package types
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/oszuidwest/zwfm-encoder/internal/audio"
+	"github.com/oszuidwest/zwfm-encoder/internal/delay"
+	"github.com/oszuidwest/zwfm-encoder/internal/hls"
+	"github.com/oszuidwest/zwfm-encoder/internal/metadata"
+	"github.com/oszuidwest/zwfm-encoder/internal/rtp"
+	"github.com/oszuidwest/zwfm-encoder/internal/schedule"
+	"github.com/oszuidwest/zwfm-encoder/internal/tap"
 )
 
 // EncoderState describes the encoder lifecycle phase.
@@ -36,12 +47,12 @@ const (
 	ProcessStarting ProcessState = "starting"
 	// ProcessRunning indicates the process is active.
 	ProcessRunning ProcessState = "running"
-	// ProcessRotating indicates file rotation is in progress (recorders only).
-	ProcessRotating ProcessState = "rotating"
 	// ProcessStopping indicates the process is shutting down.
 	ProcessStopping ProcessState = "stopping"
 	// ProcessError indicates the process failed.
 	ProcessError ProcessState = "error"
+	// ProcessScheduled indicates the stream is stopped outside its schedule (streams only).
+	ProcessScheduled ProcessState = "scheduled"
 )
 
 // ProcessStatus holds runtime status for a stream or recorder.
@@ -54,6 +65,10 @@ type ProcessStatus struct {
 	Error      string       `json:"error,omitempty"`
 	Uptime     string       `json:"uptime,omitempty"`
 	AudioDrops int64        `json:"audio_drops,omitempty"`
+	NextChange time.Time    `json:"next_change,omitzero"` // Next schedule start or stop
+
+	Loudness *audio.LoudnessStatus `json:"loudness,omitempty"` // Set when loudness normalisation is enabled
+	Delay    *delay.Status         `json:"delay,omitempty"`    // Set when the stream is delayed
 }
 
 const (
@@ -80,7 +95,8 @@ const (
 type Codec string
 
 const (
-	// CodecWAV is uncompressed PCM in a Matroska container.
+	// CodecWAV is uncompressed PCM, in a Matroska container for streams and as
+	// Broadcast WAV for files.
 	CodecWAV Codec = "wav"
 	// CodecMP3 is MPEG Audio Layer III.
 	CodecMP3 Codec = "mp3"
@@ -88,11 +104,13 @@ const (
 	CodecMP2 Codec = "mp2"
 	// CodecOGG is Ogg Vorbis.
 	CodecOGG Codec = "ogg"
+	// CodecAAC is AAC-LC in ADTS framing. Only HLS streams use it.
+	CodecAAC Codec = "aac"
 )
 
 // ValidCodecs is the set of supported audio codecs.
 var ValidCodecs = map[Codec]bool{
-	CodecWAV: true, CodecMP3: true, CodecMP2: true, CodecOGG: true,
+	CodecWAV: true, CodecMP3: true, CodecMP2: true, CodecOGG: true, CodecAAC: true,
 }
 
 // UnmarshalJSON validates the codec value during JSON parsing.
@@ -107,23 +125,95 @@ func (c *Codec) UnmarshalJSON(data []byte) error {
 	}
 	codec := Codec(s)
 	if !ValidCodecs[codec] {
-		return fmt.Errorf("codec: must be wav, mp3, mp2, or ogg")
+		return fmt.Errorf("codec: must be wav, mp3, mp2, ogg, or aac")
 	}
 	*c = codec
 	return nil
 }
 
-// Stream defines an SRT streaming destination.
+// OutputType selects how a stream delivers its audio.
+type OutputType string
+
+const (
+	// OutputSRT sends the stream to an SRT server.
+	OutputSRT OutputType = "srt"
+	// OutputHLS publishes the stream as HLS from the built-in web server.
+	OutputHLS OutputType = "hls"
+	// OutputRTP sends linear PCM over RTP, compatible with AES67.
+	OutputRTP OutputType = "rtp"
+	// OutputExec pipes the audio into the stdin of a local command.
+	OutputExec OutputType = "exec"
+)
+
+// ExecConfig configures the command of an exec output.
+type ExecConfig struct {
+	Command string   `json:"command"`        // Absolute path of the executable
+	Args    []string `json:"args,omitempty"` // Arguments passed to the command
+	PCM     bool     `json:"pcm,omitempty"`  // Raw PCM instead of the stream's codec
+}
+
+// ErrCommandNotAllowed is returned when an exec output command is not in
+// the operator's allowlist file.
+var ErrCommandNotAllowed = errors.New("command not in exec allowlist")
+
+// Validate reports an error if the command is missing or not an absolute path.
+func (c *ExecConfig) Validate() error {
+	if c.Command == "" {
+		return fmt.Errorf("exec.command: is required")
+	}
+	if !filepath.IsAbs(c.Command) {
+		return fmt.Errorf("exec.command: must be an absolute path")
+	}
+	return nil
+}
+
+// CheckAllowlist reports an error unless the command is listed in the
+// allowlist file at path: one absolute path per line, with empty lines and
+// lines starting with # ignored. Without an allowlist file any command is
+// allowed; an allowlist file that cannot be read allows none.
+func (c *ExecConfig) CheckAllowlist(path string) error {
+	data, err := os.ReadFile(path) //nolint:gosec // Path is set by the operator, not by the request
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("exec.command: read allowlist: %w", err)
+	}
+
+	command := filepath.Clean(c.Command)
+	for line := range strings.Lines(string(data)) {
+		entry := strings.TrimSpace(line)
+		if entry == "" || strings.HasPrefix(entry, "#") || !filepath.IsAbs(entry) {
+			continue
+		}
+		if filepath.Clean(entry) == command {
+			return nil
+		}
+	}
+	return fmt.Errorf("exec.command: %s: %w", command, ErrCommandNotAllowed)
+}
+
+// Stream defines a streaming destination.
 type Stream struct {
-	ID         string `json:"id"`
-	Enabled    bool   `json:"enabled"`
-	Host       string `json:"host"`
-	Port       int    `json:"port"`
-	Password   string `json:"password"`
-	StreamID   string `json:"stream_id"`
-	Codec      Codec  `json:"codec"`
-	MaxRetries int    `json:"max_retries"` // 0 = no retries
-	CreatedAt  int64  `json:"created_at"`  // Unix ms
+	ID         string               `json:"id"`
+	Enabled    bool                 `json:"enabled"`
+	Type       OutputType           `json:"type,omitempty"` // Empty = srt
+	Host       string               `json:"host"`
+	Port       int                  `json:"port"`
+	Password   string               `json:"password"`
+	StreamID   string               `json:"stream_id"`
+	Codec      Codec                `json:"codec"`
+	Channels   audio.Route          `json:"channels,omitempty"` // Input channels; empty = 1-2
+	TrimDB     float64              `json:"trim_db,omitempty"`  // Output level trim
+	Loudness   audio.LoudnessConfig `json:"loudness,omitzero"`
+	Delay      delay.Config         `json:"delay,omitzero"`
+	HLS        hls.Config           `json:"hls,omitzero"`
+	RTP        rtp.Config           `json:"rtp,omitzero"`
+	Exec       ExecConfig           `json:"exec,omitzero"`
+	IcecastURL string               `json:"icecast_url,omitempty"`
+	Schedule   schedule.Config      `json:"schedule,omitzero"`
+	MaxRetries int                  `json:"max_retries"` // 0 = no retries
+	CreatedAt  int64                `json:"created_at"`  // Unix ms
 }
 
 // IsEnabled reports whether the stream is enabled.
@@ -131,6 +221,28 @@ func (s *Stream) IsEnabled() bool {
 	return s.Enabled
 }
 
+// Output returns the output type, defaulting to [OutputSRT].
+func (s *Stream) Output() OutputType {
+	if s.Type == "" {
+		return OutputSRT
+	}
+	return s.Type
+}
+
+// Destination returns a short description of where the stream goes.
+func (s *Stream) Destination() string {
+	switch s.Output() {
+	case OutputHLS:
+		return "HLS /hls/" + s.ID + "/"
+	case OutputRTP:
+		return fmt.Sprintf("rtp://%s:%d", s.Host, s.Port)
+	case OutputExec:
+		return "exec " + filepath.Base(s.Exec.Command)
+	default:
+		return fmt.Sprintf("%s:%d", s.Host, s.Port)
+	}
+}
+
 // DefaultMaxRetries is the default number of retry attempts for streams.
 const DefaultMaxRetries = 99
 
@@ -157,6 +269,7 @@ var CodecPresets = map[Codec]CodecPreset{
 	CodecMP3: {[]string{"libmp3lame", "-b:a", "320k"}, "mp3"},
 	CodecOGG: {[]string{"libvorbis", "-qscale:a", "10"}, "ogg"},
 	CodecWAV: {[]string{"pcm_s16le"}, "matroska"},
+	CodecAAC: {[]string{"aac", "-b:a", "256k"}, "adts"},
 }
 
 // Args returns the encoder arguments for this codec.
@@ -175,6 +288,15 @@ func (c Codec) Format() string {
 	return CodecPresets[CodecWAV].Format
 }
 
+// FileFormat returns the output format for files written with this codec.
+// Uncompressed PCM is written as WAV, which needs a seekable output.
+func (c Codec) FileFormat() string {
+	if c == CodecWAV {
+		return "wav"
+	}
+	return c.Format()
+}
+
 // CodecArgs returns the encoder arguments for this stream's codec.
 func (s *Stream) CodecArgs() []string {
 	return s.Codec.Args()
@@ -187,15 +309,66 @@ func (s *Stream) Format() string {
 
 // Validate reports an error if the stream configuration is invalid.
 func (s *Stream) Validate() error {
+	switch s.Output() {
+	case OutputSRT:
+		if err := s.validateDestination(); err != nil {
+			return err
+		}
+	case OutputRTP:
+		if err := s.validateDestination(); err != nil {
+			return err
+		}
+		if err := s.RTP.Validate(); err != nil {
+			return err
+		}
+	case OutputHLS:
+		if s.Codec != CodecAAC && s.Codec != CodecMP3 {
+			return fmt.Errorf("codec: HLS requires aac or mp3")
+		}
+		if err := s.HLS.Validate(); err != nil {
+			return err
+		}
+	case OutputExec:
+		if err := s.Exec.Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("type: must be srt, hls, rtp or exec")
+	}
+	if s.Codec == CodecAAC && s.Output() != OutputHLS {
+		return fmt.Errorf("codec: aac is only available for HLS streams")
+	}
+	if s.IcecastURL != "" {
+		if err := metadata.ValidateIcecastURL(s.IcecastURL); err != nil {
+			return fmt.Errorf("icecast_url: %w", err)
+		}
+	}
+	if s.MaxRetries < 0 {
+		return fmt.Errorf("max_retries: cannot be negative")
+	}
+	if !s.Channels.Valid(audio.MaxInputChannels) {
+		return fmt.Errorf("channels: must select one or two channels between 1 and %d", audio.MaxInputChannels)
+	}
+	if err := audio.ValidateTrim(s.TrimDB); err != nil {
+		return err
+	}
+	if err := s.Loudness.Validate(); err != nil {
+		return err
+	}
+	if err := s.Schedule.Validate(); err != nil {
+		return err
+	}
+	return s.Delay.Validate()
+}
+
+// validateDestination reports an error if the host or port is missing or invalid.
+func (s *Stream) validateDestination() error {
 	if strings.TrimSpace(s.Host) == "" {
 		return fmt.Errorf("host: is required")
 	}
 	if s.Port <= 0 || s.Port > 65535 {
 		return fmt.Errorf("port: must be between 1 and 65535")
 	}
-	if s.MaxRetries < 0 {
-		return fmt.Errorf("max_retries: cannot be negative")
-	}
 	return nil
 }
 
@@ -205,13 +378,28 @@ type RotationMode string
 const (
 	// RotationHourly rotates recordings at system clock hour boundaries.
 	RotationHourly RotationMode = "hourly"
+	// RotationInterval rotates recordings every RotationMinutes, aligned to the clock.
+	RotationInterval RotationMode = "interval"
+	// RotationDaily rotates recordings at local midnight.
+	RotationDaily RotationMode = "daily"
+	// RotationContinuous records one file from start until stop.
+	RotationContinuous RotationMode = "continuous"
+	// RotationOnDemand records one file between API start and stop calls.
+	RotationOnDemand RotationMode = "ondemand"
 )
 
 // ValidRotationModes is the set of supported rotation modes.
 var ValidRotationModes = map[RotationMode]bool{
-	RotationHourly: true,
+	RotationHourly:     true,
+	RotationInterval:   true,
+	RotationDaily:      true,
+	RotationContinuous: true,
+	RotationOnDemand:   true,
 }
 
+// ValidRotationMinutes is the set of supported intervals for [RotationInterval].
+var ValidRotationMinutes = []int{5, 15, 30, 60, 120}
+
 // UnmarshalJSON validates the rotation mode during JSON parsing.
 func (m *RotationMode) UnmarshalJSON(data []byte) error {
 	var s string
@@ -224,12 +412,24 @@ func (m *RotationMode) UnmarshalJSON(data []byte) error {
 	}
 	mode := RotationMode(s)
 	if !ValidRotationModes[mode] {
-		return fmt.Errorf("rotation_mode: must be hourly")
+		return fmt.Errorf("rotation_mode: must be hourly, interval, daily, continuous, or ondemand")
 	}
 	*m = mode
 	return nil
 }
 
+// AutoStarts reports whether recorders in this mode start with the encoder.
+// On-demand recorders only start through the API.
+func (m RotationMode) AutoStarts() bool {
+	return m != RotationOnDemand
+}
+
+// Rotates reports whether recorders in this mode split their recording into
+// clock-aligned files.
+func (m RotationMode) Rotates() bool {
+	return m != RotationContinuous && m != RotationOnDemand
+}
+
 // StorageMode defines where recordings are stored.
 type StorageMode string
 
@@ -279,21 +479,75 @@ type SilenceDumpConfig struct {
 
 // Recorder defines a recording destination configuration.
 type Recorder struct {
-	ID           string       `json:"id"`
-	Name         string       `json:"name"`
-	Enabled      bool         `json:"enabled"`
-	Codec        Codec        `json:"codec"`
-	RotationMode RotationMode `json:"rotation_mode"`
-	StorageMode  StorageMode  `json:"storage_mode"`
-	LocalPath    string       `json:"local_path"`
+	ID           string               `json:"id"`
+	Name         string               `json:"name"`
+	Enabled      bool                 `json:"enabled"`
+	Codec        Codec                `json:"codec"`
+	Channels     audio.Route          `json:"channels,omitempty"` // Input channels; empty = 1-2
+	TrimDB       float64              `json:"trim_db,omitempty"`  // Output level trim
+	Loudness     audio.LoudnessConfig `json:"loudness,omitzero"`
+	RotationMode RotationMode         `json:"rotation_mode"`
+	StorageMode  StorageMode          `json:"storage_mode"`
+	LocalPath    string               `json:"local_path"`
+
+	RotationMinutes int             `json:"rotation_minutes,omitempty"` // Interval mode only
+	Schedules       []schedule.Show `json:"schedules,omitempty"`        // On-demand mode only
 
 	S3Endpoint        string `json:"s3_endpoint"`
 	S3Bucket          string `json:"s3_bucket"`
 	S3AccessKeyID     string `json:"s3_access_key_id"`
 	S3SecretAccessKey string `json:"s3_secret_access_key"`
 
-	RetentionDays int   `json:"retention_days"` // 0 = forever
-	CreatedAt     int64 `json:"created_at"`     // Unix ms
+	RetentionDays int        `json:"retention_days"` // 0 = forever
+	Compliance    Compliance `json:"compliance,omitzero"`
+	CreatedAt     int64      `json:"created_at"` // Unix ms
+}
+
+// ObjectLockMode is the S3 Object Lock retention mode of compliance uploads.
+type ObjectLockMode string
+
+// S3 Object Lock retention modes.
+const (
+	// ObjectLockGovernance lets users with special permissions shorten or remove the retention.
+	ObjectLockGovernance ObjectLockMode = "governance"
+	// ObjectLockCompliance lets nobody shorten or remove the retention.
+	ObjectLockCompliance ObjectLockMode = "compliance"
+)
+
+// MaxComplianceRetainDays is the longest compliance retention.
+const MaxComplianceRetainDays = 3650
+
+// Compliance makes the archive of a recorder tamper-evident: the sidecars of
+// its files form a hash chain, uploads are locked in S3 where the bucket
+// supports Object Lock, and no file is deleted before RetainDays have passed.
+type Compliance struct {
+	Enabled    bool           `json:"enabled"`
+	RetainDays int            `json:"retain_days"`
+	LockMode   ObjectLockMode `json:"lock_mode"`
+	LegalHold  bool           `json:"legal_hold,omitempty"` // Place a legal hold on every upload
+}
+
+// Validate reports an error if the compliance configuration is invalid.
+func (c *Compliance) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.RetainDays < 1 || c.RetainDays > MaxComplianceRetainDays {
+		return fmt.Errorf("compliance.retain_days: must be between 1 and %d", MaxComplianceRetainDays)
+	}
+	if c.LockMode != ObjectLockGovernance && c.LockMode != ObjectLockCompliance {
+		return fmt.Errorf("compliance.lock_mode: must be governance or compliance")
+	}
+	return nil
+}
+
+// RetainedUntil returns the time before which a compliance recorder keeps
+// a file that started at start. It is zero when compliance is disabled.
+func (c *Compliance) RetainedUntil(start time.Time) time.Time {
+	if !c.Enabled {
+		return time.Time{}
+	}
+	return start.AddDate(0, 0, c.RetainDays)
 }
 
 // IsEnabled reports whether the recorder is enabled.
@@ -306,9 +560,9 @@ func (r *Recorder) CodecArgs() []string {
 	return r.Codec.Args()
 }
 
-// Format returns the output format for this recorder's codec.
+// Format returns the file format for this recorder's codec.
 func (r *Recorder) Format() string {
-	return r.Codec.Format()
+	return r.Codec.FileFormat()
 }
 
 // Validate reports an error if the recorder configuration is invalid.
@@ -316,6 +570,9 @@ func (r *Recorder) Validate() error {
 	if strings.TrimSpace(r.Name) == "" {
 		return fmt.Errorf("name: is required")
 	}
+	if r.Codec == CodecAAC {
+		return fmt.Errorf("codec: must be wav, mp3, mp2, or ogg")
+	}
 
 	// Conditional validation based on storage mode
 	needsLocal := r.StorageMode == StorageLocal || r.StorageMode == StorageBoth
@@ -335,10 +592,33 @@ func (r *Recorder) Validate() error {
 			return fmt.Errorf("s3_secret_access_key: is required for s3/both storage mode")
 		}
 	}
+	if r.RotationMode == RotationInterval && !slices.Contains(ValidRotationMinutes, r.RotationMinutes) {
+		return fmt.Errorf("rotation_minutes: must be 5, 15, 30, 60, or 120")
+	}
+	if len(r.Schedules) > 0 && r.RotationMode != RotationOnDemand {
+		return fmt.Errorf("schedules: only on-demand recorders can be scheduled")
+	}
+	for i := range r.Schedules {
+		if err := r.Schedules[i].Validate(); err != nil {
+			return fmt.Errorf("schedules[%d].%w", i, err)
+		}
+	}
 	if r.RetentionDays < 0 {
 		return fmt.Errorf("retention_days: cannot be negative")
 	}
-	return nil
+	if err := r.Compliance.Validate(); err != nil {
+		return err
+	}
+	if r.Compliance.Enabled && r.RetentionDays != 0 && r.RetentionDays < r.Compliance.RetainDays {
+		return fmt.Errorf("retention_days: must be 0 or at least compliance.retain_days")
+	}
+	if !r.Channels.Valid(audio.MaxInputChannels) {
+		return fmt.Errorf("channels: must select one or two channels between 1 and %d", audio.MaxInputChannels)
+	}
+	if err := audio.ValidateTrim(r.TrimDB); err != nil {
+		return err
+	}
+	return r.Loudness.Validate()
 }
 
 // EncoderStatus summarizes the encoder's current operational state.
@@ -359,15 +639,20 @@ type WSRuntimeStatus struct {
 	Encoder           EncoderStatus            `json:"encoder"`
 	StreamStatus      map[string]ProcessStatus `json:"stream_status"`
 	RecorderStatuses  map[string]ProcessStatus `json:"recorder_statuses"`
+	Taps              []tap.Status             `json:"taps,omitempty"` // Readers of the PCM tap socket
+	NowPlaying        *metadata.NowPlaying     `json:"now_playing,omitempty"`
 	GraphSecretExpiry SecretExpiryInfo         `json:"graph_secret_expiry"`
 	Version           VersionInfo              `json:"version"`
 }
 
 // APIConfigResponse contains the complete encoder configuration for API responses.
 type APIConfigResponse struct {
-	AudioInput string         `json:"audio_input"`
-	Devices    []audio.Device `json:"devices"`
-	Platform   string         `json:"platform"`
+	AudioInput    string         `json:"audio_input"`
+	AudioChannels int            `json:"audio_channels"`
+	Devices       []audio.Device `json:"devices"`
+	Platform      string         `json:"platform"`
+
+	Processing audio.ProcessingConfig `json:"processing"`
 
 	SilenceThreshold  float64           `json:"silence_threshold"` // dB
 	SilenceDurationMs int64             `json:"silence_duration_ms"`
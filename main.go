@@ -18,7 +18,7 @@ import (
 	"time"
 
 	"github.com/oszuidwest/zwfm-encoder/internal/config"
-	"github.com/oszuidwest/zwfm-encoder/internal/encoder"
+	"github.com/oszuidwest/zwfm-encoder/internal/recording"
 	"github.com/oszuidwest/zwfm-encoder/internal/util"
 )
 
@@ -43,6 +43,8 @@ func main() {
 
 	slog.Info("using config file", "path", *configPath)
 
+	recording.EncoderVersion = Version
+
 	cfg := config.New(*configPath)
 	if err := cfg.Load(); err != nil {
 		slog.Error("failed to load config", "error", err)
@@ -51,36 +53,21 @@ func main() {
 
 	// Check FFmpeg availability
 	ffmpegPath := util.ResolveFFmpegPath(cfg.FFmpegPath())
-	ffmpegAvailable := ffmpegPath != ""
-	if !ffmpegAvailable {
+	if ffmpegPath == "" {
 		slog.Warn("FFmpeg not found - running in degraded mode",
 			"configured_path", cfg.FFmpegPath())
 	} else {
 		slog.Info("FFmpeg found", "path", ffmpegPath)
 	}
 
-	enc, err := encoder.New(cfg, ffmpegPath)
-	if err != nil {
+	srv := NewServer(cfg, ffmpegPath)
+
+	// Create and start an encoder per programme
+	if err := srv.StartProgrammes(); err != nil {
 		slog.Error("failed to create encoder", "error", err)
 		os.Exit(1)
 	}
 
-	srv := NewServer(cfg, enc, ffmpegAvailable)
-
-	// Initialize recording manager if configured
-	if err := enc.InitRecording(); err != nil {
-		slog.Error("failed to initialize recording", "error", err)
-	}
-
-	if ffmpegAvailable {
-		slog.Info("starting encoder")
-		if err := enc.Start(); err != nil {
-			slog.Error("failed to start encoder", "error", err)
-		}
-	} else {
-		slog.Warn("encoder not started - FFmpeg not available")
-	}
-
 	// Start web server.
 	httpServer := srv.Start()
 
@@ -101,9 +88,7 @@ func main() {
 		slog.Error("HTTP server shutdown error", "error", err)
 	}
 
-	if err := enc.Stop(); err != nil {
-		slog.Error("error stopping encoder", "error", err)
-	}
+	srv.StopProgrammes()
 
 	slog.Info("shutdown complete")
 }
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/oszuidwest/zwfm-encoder/internal/config"
+	"github.com/oszuidwest/zwfm-encoder/internal/encoder"
+)
+
+// programme pairs a programme's config view with the encoder that runs it.
+type programme struct {
+	config  *config.Config
+	encoder *encoder.Encoder
+}
+
+// programmeContextKey is the request context key for the scoped programme.
+type programmeContextKey struct{}
+
+// StartProgrammes creates an encoder for every configured programme and starts
+// it when FFmpeg is available. Only a failure of the main programme is fatal.
+func (s *Server) StartProgrammes() error {
+	for _, info := range s.config.ProgrammeList() {
+		if err := s.startProgramme(s.config.ForProgramme(info.ID)); err != nil {
+			if info.Main {
+				return err
+			}
+			slog.Error("failed to start programme", "programme", info.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// StopProgrammes stops all encoders and releases their resources.
+func (s *Server) StopProgrammes() {
+	s.programmesMu.Lock()
+	programmes := s.programmes
+	s.programmes = make(map[string]*programme)
+	s.programmesMu.Unlock()
+
+	for id, prog := range programmes {
+		if err := prog.encoder.Close(); err != nil {
+			slog.Error("error stopping encoder", "programme", id, "error", err)
+		}
+	}
+}
+
+// startProgramme creates, registers and starts the encoder for a programme.
+func (s *Server) startProgramme(cfg *config.Config) error {
+	id := cfg.Snapshot().ProgrammeID
+
+	enc, err := encoder.New(cfg, s.ffmpegPath)
+	if err != nil {
+		return fmt.Errorf("create encoder: %w", err)
+	}
+
+	// Initialize recording manager if configured
+	if err := enc.InitRecording(); err != nil {
+		slog.Error("failed to initialize recording", "programme", id, "error", err)
+	}
+
+	s.programmesMu.Lock()
+	s.programmes[id] = &programme{config: cfg, encoder: enc}
+	s.programmesMu.Unlock()
+
+	if !s.ffmpegAvailable {
+		slog.Warn("encoder not started - FFmpeg not available", "programme", id)
+		return nil
+	}
+
+	slog.Info("starting encoder", "programme", id)
+	if err := enc.Start(); err != nil {
+		slog.Error("failed to start encoder", "programme", id, "error", err)
+	}
+	return nil
+}
+
+// lookupProgramme returns the running programme with the given ID, or nil if
+// it does not exist. An empty ID selects the main programme.
+func (s *Server) lookupProgramme(id string) *programme {
+	s.programmesMu.RLock()
+	defer s.programmesMu.RUnlock()
+	return s.programmes[cmp.Or(id, s.mainProgramme)]
+}
+
+// programmeList returns all running programmes in config order.
+func (s *Server) programmeList() []*programme {
+	var list []*programme
+	for _, info := range s.config.ProgrammeList() {
+		if prog := s.lookupProgramme(info.ID); prog != nil {
+			list = append(list, prog)
+		}
+	}
+	return list
+}
+
+// scoped wraps a handler so it operates on the programme selected by the
+// {programme} path segment or the programme query parameter. Requests that
+// select neither apply to the main programme.
+func (s *Server) scoped(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := cmp.Or(r.PathValue("programme"), r.URL.Query().Get("programme"))
+		prog := s.lookupProgramme(id)
+		if prog == nil {
+			s.writeError(w, http.StatusNotFound, "Programme not found")
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), programmeContextKey{}, prog)))
+	}
+}
+
+// programme returns the programme a scoped request operates on.
+func (s *Server) programme(r *http.Request) *programme {
+	return r.Context().Value(programmeContextKey{}).(*programme)
+}
+
+// ProgrammeRequest is the request body for creating or renaming a programme.
+type ProgrammeRequest struct {
+	// Name is the programme display name.
+	Name string `json:"name"`
+}
+
+// handleListProgrammes returns all configured programmes.
+func (s *Server) handleListProgrammes(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.config.ProgrammeList())
+}
+
+// handleCreateProgramme adds a programme and starts its encoder.
+func (s *Server) handleCreateProgramme(w http.ResponseWriter, r *http.Request) {
+	req, ok := parseJSON[ProgrammeRequest](s, w, r)
+	if !ok {
+		return
+	}
+
+	if err := config.ValidateProgrammeName(req.Name); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	cfg, err := s.config.AddProgramme(req.Name)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := s.startProgramme(cfg); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	snap := cfg.Snapshot()
+	s.broadcastConfigChanged()
+	s.writeJSON(w, http.StatusCreated, config.ProgrammeInfo{ID: snap.ProgrammeID, Name: snap.ProgrammeName})
+}
+
+// handleUpdateProgramme renames a programme.
+func (s *Server) handleUpdateProgramme(w http.ResponseWriter, r *http.Request) {
+	req, ok := parseJSON[ProgrammeRequest](s, w, r)
+	if !ok {
+		return
+	}
+
+	if err := config.ValidateProgrammeName(req.Name); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.config.RenameProgramme(r.PathValue("programme"), req.Name); err != nil {
+		s.writeConfigError(w, err)
+		return
+	}
+
+	s.broadcastConfigChanged()
+	s.writeNoContent(w)
+}
+
+// handleDeleteProgramme stops a programme's encoder and removes the programme.
+func (s *Server) handleDeleteProgramme(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("programme")
+
+	if err := s.config.RemoveProgramme(id); err != nil {
+		s.writeConfigError(w, err)
+		return
+	}
+
+	s.programmesMu.Lock()
+	prog := s.programmes[id]
+	delete(s.programmes, id)
+	s.programmesMu.Unlock()
+
+	if prog != nil {
+		if err := prog.encoder.Close(); err != nil {
+			slog.Warn("failed to stop encoder of removed programme", "programme", id, "error", err)
+		}
+	}
+
+	s.broadcastConfigChanged()
+	s.writeNoContent(w)
+}
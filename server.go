@@ -13,7 +13,7 @@ import (
 	"time"
 
 	"github.com/oszuidwest/zwfm-encoder/internal/config"
-	"github.com/oszuidwest/zwfm-encoder/internal/encoder"
+	"github.com/oszuidwest/zwfm-encoder/internal/metadata"
 	"github.com/oszuidwest/zwfm-encoder/internal/server"
 	"github.com/oszuidwest/zwfm-encoder/internal/types"
 	"github.com/oszuidwest/zwfm-encoder/internal/util"
@@ -42,24 +42,32 @@ type indexData struct {
 // Server handles HTTP requests and WebSocket connections.
 type Server struct {
 	config          *config.Config
-	encoder         *encoder.Encoder
 	sessions        *server.SessionManager
 	version         *VersionChecker
+	ffmpegPath      string
 	ffmpegAvailable bool
 
+	// Running programmes keyed by programme ID
+	programmes    map[string]*programme
+	programmesMu  sync.RWMutex
+	mainProgramme string
+
 	// WebSocket broadcast channels
 	wsClients   map[chan any]struct{}
 	wsClientsMu sync.RWMutex
 }
 
-// NewServer creates a Server with the given configuration and encoder.
-func NewServer(cfg *config.Config, enc *encoder.Encoder, ffmpegAvailable bool) *Server {
+// NewServer creates a Server with the given configuration. Encoders are
+// created by [Server.StartProgrammes].
+func NewServer(cfg *config.Config, ffmpegPath string) *Server {
 	return &Server{
 		config:          cfg,
-		encoder:         enc,
 		sessions:        server.NewSessionManager(),
 		version:         NewVersionChecker(),
-		ffmpegAvailable: ffmpegAvailable,
+		ffmpegPath:      ffmpegPath,
+		ffmpegAvailable: ffmpegPath != "",
+		programmes:      make(map[string]*programme),
+		mainProgramme:   cfg.Snapshot().ProgrammeID,
 		wsClients:       make(map[chan any]struct{}),
 	}
 }
@@ -86,7 +94,7 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// Reader goroutine - keeps connection alive
 	go s.runWebSocketReader(conn, done)
 
-	s.runWebSocketEventLoop(send, done)
+	s.runWebSocketEventLoop(s.programme(r), send, done)
 }
 
 func (s *Server) registerWSClient(send chan any) {
@@ -145,7 +153,7 @@ func (s *Server) runWebSocketReader(conn server.WebSocketConn, done chan<- struc
 }
 
 // runWebSocketEventLoop sends periodic level and status updates to the client.
-func (s *Server) runWebSocketEventLoop(send chan any, done <-chan struct{}) {
+func (s *Server) runWebSocketEventLoop(prog *programme, send chan any, done <-chan struct{}) {
 	levelsTicker := time.NewTicker(100 * time.Millisecond)  // 10 fps for VU meters
 	statusTicker := time.NewTicker(3000 * time.Millisecond) // Status updates every 3s
 	defer levelsTicker.Stop()
@@ -162,7 +170,7 @@ func (s *Server) runWebSocketEventLoop(send chan any, done <-chan struct{}) {
 	}
 
 	// Send initial status
-	if !trySend(s.buildWSRuntime()) {
+	if !trySend(s.buildWSRuntime(prog)) {
 		close(send)
 		return
 	}
@@ -173,12 +181,12 @@ func (s *Server) runWebSocketEventLoop(send chan any, done <-chan struct{}) {
 			close(send)
 			return
 		case <-levelsTicker.C:
-			if !trySend(types.WSLevelsResponse{Type: "levels", Levels: s.encoder.AudioLevels()}) {
+			if !trySend(types.WSLevelsResponse{Type: "levels", Levels: prog.encoder.AudioLevels()}) {
 				close(send)
 				return
 			}
 		case <-statusTicker.C:
-			if !trySend(s.buildWSRuntime()) {
+			if !trySend(s.buildWSRuntime(prog)) {
 				close(send)
 				return
 			}
@@ -186,18 +194,25 @@ func (s *Server) runWebSocketEventLoop(send chan any, done <-chan struct{}) {
 	}
 }
 
-func (s *Server) buildWSRuntime() types.WSRuntimeStatus {
-	cfg := s.config.Snapshot()
-	status := s.encoder.Status()
+func (s *Server) buildWSRuntime(prog *programme) types.WSRuntimeStatus {
+	cfg := prog.config.Snapshot()
+	status := prog.encoder.Status()
 	status.StreamCount = len(cfg.Streams)
 
+	var nowPlaying *metadata.NowPlaying
+	if np := prog.encoder.NowPlaying(); !np.IsZero() {
+		nowPlaying = &np
+	}
+
 	return types.WSRuntimeStatus{
 		Type:              "status",
 		FFmpegAvailable:   s.ffmpegAvailable,
 		Encoder:           status,
-		StreamStatus:      s.encoder.StreamStatuses(cfg.Streams),
-		RecorderStatuses:  s.encoder.RecorderStatuses(),
-		GraphSecretExpiry: s.encoder.GraphSecretExpiry(),
+		StreamStatus:      prog.encoder.StreamStatuses(cfg.Streams),
+		RecorderStatuses:  prog.encoder.RecorderStatuses(),
+		Taps:              prog.encoder.Taps(),
+		NowPlaying:        nowPlaying,
+		GraphSecretExpiry: prog.encoder.GraphSecretExpiry(),
 		Version:           s.version.Info(),
 	}
 }
@@ -212,49 +227,89 @@ func (s *Server) SetupRoutes() http.Handler {
 	mux.HandleFunc("/logout", s.handleLogout)
 	mux.HandleFunc("GET /health", s.handleHealth)
 
+	// HLS outputs (public or session auth, per stream)
+	mux.HandleFunc("GET /hls/{id}/{file}", s.handleHLS)
+
 	// Public static assets (needed for login page styling)
 	mux.HandleFunc("/style.css", s.handlePublicStatic)
 	mux.HandleFunc("/icons.js", s.handlePublicStatic)
 	mux.HandleFunc("/favicon.svg", s.handleFavicon)
 
+	// Programme routes (session auth)
+	mux.HandleFunc("GET /api/programmes", auth(s.handleListProgrammes))
+	mux.HandleFunc("POST /api/programmes", auth(s.handleCreateProgramme))
+	mux.HandleFunc("PUT /api/programmes/{programme}", auth(s.handleUpdateProgramme))
+	mux.HandleFunc("DELETE /api/programmes/{programme}", auth(s.handleDeleteProgramme))
+
+	// Programme-scoped routes are served for the main programme under /api
+	// and for any programme under /api/programmes/{programme}.
+	scoped := func(method, path string, handler http.HandlerFunc) {
+		mux.HandleFunc(method+" /api"+path, s.scoped(handler))
+		mux.HandleFunc(method+" /api/programmes/{programme}"+path, s.scoped(handler))
+	}
+
 	// Recording API routes (API key auth)
-	mux.HandleFunc("POST /api/recordings/start", s.apiKeyAuth(s.handleExternalRecordingAction))
-	mux.HandleFunc("POST /api/recordings/stop", s.apiKeyAuth(s.handleExternalRecordingAction))
+	scoped("POST", "/recordings/start", s.apiKeyAuth(s.handleExternalRecordingAction))
+	scoped("POST", "/recordings/stop", s.apiKeyAuth(s.handleExternalRecordingAction))
+
+	// Now-playing metadata from playout systems (API key auth)
+	scoped("POST", "/metadata", s.apiKeyAuth(s.handleSetMetadata))
 
 	// REST API routes (session auth)
-	mux.HandleFunc("GET /api/config", auth(s.handleAPIConfig))
+	scoped("GET", "/config", auth(s.handleAPIConfig))
 	mux.HandleFunc("GET /api/devices", auth(s.handleAPIDevices))
-	mux.HandleFunc("POST /api/settings", auth(s.handleAPISettings))
+	scoped("POST", "/settings", auth(s.handleAPISettings))
+	scoped("GET", "/audio/mixer", auth(s.handleGetMixer))
+	scoped("PUT", "/audio/mixer", auth(s.handleSetMixer))
+	scoped("GET", "/audio/processing", auth(s.handleGetProcessing))
+	scoped("PUT", "/audio/processing", auth(s.handleSetProcessing))
+
+	// Live monitoring
+	scoped("GET", "/listen", auth(s.handleListen))
 
 	// Stream CRUD routes
-	mux.HandleFunc("GET /api/streams", auth(s.handleListStreams))
-	mux.HandleFunc("POST /api/streams", auth(s.handleCreateStream))
-	mux.HandleFunc("GET /api/streams/{id}", auth(s.handleGetStream))
-	mux.HandleFunc("PUT /api/streams/{id}", auth(s.handleUpdateStream))
-	mux.HandleFunc("DELETE /api/streams/{id}", auth(s.handleDeleteStream))
+	scoped("GET", "/metadata", auth(s.handleGetMetadata))
+	scoped("GET", "/streams", auth(s.handleListStreams))
+	scoped("POST", "/streams", auth(s.handleCreateStream))
+	scoped("GET", "/streams/{id}", auth(s.handleGetStream))
+	scoped("PUT", "/streams/{id}", auth(s.handleUpdateStream))
+	scoped("DELETE", "/streams/{id}", auth(s.handleDeleteStream))
+	scoped("POST", "/streams/{id}/delay/{action}", auth(s.handleStreamDelayAction))
+	scoped("GET", "/streams/{id}/listen", auth(s.handleListen))
+	scoped("GET", "/streams/{id}/sdp", auth(s.handleStreamSDP))
 
 	// Recorder CRUD routes
-	mux.HandleFunc("GET /api/recorders", auth(s.handleListRecorders))
-	mux.HandleFunc("POST /api/recorders", auth(s.handleCreateRecorder))
+	scoped("GET", "/recorders", auth(s.handleListRecorders))
+	scoped("POST", "/recorders", auth(s.handleCreateRecorder))
+	scoped("GET", "/recorders/schedules", auth(s.handleListRecorderSchedules))
 	mux.HandleFunc("POST /api/recorders/test-s3", auth(s.handleTestS3))
-	mux.HandleFunc("GET /api/recorders/{id}", auth(s.handleGetRecorder))
-	mux.HandleFunc("PUT /api/recorders/{id}", auth(s.handleUpdateRecorder))
-	mux.HandleFunc("DELETE /api/recorders/{id}", auth(s.handleDeleteRecorder))
-	mux.HandleFunc("POST /api/recorders/{id}/{action}", auth(s.handleRecorderAction))
+	scoped("GET", "/recorders/{id}", auth(s.handleGetRecorder))
+	scoped("PUT", "/recorders/{id}", auth(s.handleUpdateRecorder))
+	scoped("DELETE", "/recorders/{id}", auth(s.handleDeleteRecorder))
+	scoped("POST", "/recorders/{id}/{action}", auth(s.handleRecorderAction))
+	scoped("GET", "/recorders/{id}/recordings", auth(s.handleListRecordings))
+	scoped("GET", "/recorders/{id}/recordings/{name}", auth(s.handleGetRecording))
+	scoped("GET", "/recorders/{id}/chain", auth(s.handleVerifyChain))
+	scoped("GET", "/recorders/{id}/coverage", auth(s.handleRecorderCoverage))
+	scoped("GET", "/recorders/{id}/exports", auth(s.handleListExports))
+	scoped("POST", "/recorders/{id}/exports", auth(s.handleCreateExport))
+	scoped("GET", "/recorders/{id}/exports/{job}", auth(s.handleGetExport))
+	scoped("DELETE", "/recorders/{id}/exports/{job}", auth(s.handleDeleteExport))
+	scoped("GET", "/recorders/{id}/exports/{job}/download", auth(s.handleDownloadExport))
 
 	// Notification test routes
-	mux.HandleFunc("POST /api/notifications/test/webhook", auth(s.handleAPITestWebhook))
-	mux.HandleFunc("POST /api/notifications/test/email", auth(s.handleAPITestEmail))
-	mux.HandleFunc("POST /api/notifications/test/zabbix", auth(s.handleAPITestZabbix))
+	scoped("POST", "/notifications/test/webhook", auth(s.handleAPITestWebhook))
+	scoped("POST", "/notifications/test/email", auth(s.handleAPITestEmail))
+	scoped("POST", "/notifications/test/zabbix", auth(s.handleAPITestZabbix))
 
 	// Recording API key management
-	mux.HandleFunc("POST /api/recording/regenerate-key", auth(s.handleAPIRegenerateKey))
+	scoped("POST", "/recording/regenerate-key", auth(s.handleAPIRegenerateKey))
 
 	// Event log
-	mux.HandleFunc("GET /api/events", auth(s.handleAPIEvents))
+	scoped("GET", "/events", auth(s.handleAPIEvents))
 
 	// Protected routes
-	mux.HandleFunc("/ws", auth(s.handleWebSocket))
+	mux.HandleFunc("/ws", auth(s.scoped(s.handleWebSocket)))
 	mux.HandleFunc("/", auth(s.handleStatic))
 
 	return securityHeaders(mux)
@@ -410,7 +465,7 @@ func (s *Server) handleStatic(w http.ResponseWriter, r *http.Request) {
 // apiKeyAuth wraps a handler with API key authentication.
 func (s *Server) apiKeyAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		apiKey := s.config.RecordingAPIKey()
+		apiKey := s.programme(r).config.RecordingAPIKey()
 		if apiKey == "" {
 			http.Error(w, "API key not configured", http.StatusServiceUnavailable)
 			return
@@ -426,6 +481,7 @@ func (s *Server) apiKeyAuth(next http.HandlerFunc) http.HandlerFunc {
 }
 
 func (s *Server) handleExternalRecordingAction(w http.ResponseWriter, r *http.Request) {
+	prog := s.programme(r)
 	recorderID := r.URL.Query().Get("recorder_id")
 	if recorderID == "" {
 		s.writeError(w, http.StatusBadRequest, "recorder_id is required")
@@ -439,14 +495,14 @@ func (s *Server) handleExternalRecordingAction(w http.ResponseWriter, r *http.Re
 	var status string
 
 	if isStart {
-		if s.encoder.State() != types.StateRunning {
+		if prog.encoder.State() != types.StateRunning {
 			s.writeError(w, http.StatusBadRequest, "Encoder must be running to start recorder")
 			return
 		}
-		err = s.encoder.StartRecorder(recorderID)
+		err = prog.encoder.StartRecorder(recorderID)
 		status = "recording_started"
 	} else {
-		err = s.encoder.StopRecorder(recorderID)
+		err = prog.encoder.StopRecorder(recorderID)
 		status = "recording_stopped"
 	}
 
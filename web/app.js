@@ -12,9 +12,12 @@
  *
  * REST API (configuration):
  *   - GET  /api/config: Full configuration snapshot
+ *   - GET/POST/PUT/DELETE /api/programmes/*: Programme management
+ *   - /api/programmes/{id}/*: Same endpoints scoped to another programme
  *   - POST /api/settings: Update all settings atomically
  *   - POST/GET/PUT/DELETE /api/streams/*: Stream CRUD
  *   - POST/GET/PUT/DELETE /api/recorders/*: Recorder CRUD
+ *   - GET  /api/listen, /api/streams/{id}/listen: Live monitoring audio
  *   - POST /api/notifications/test/*: Test notifications
  *   - GET  /api/notifications/log: View silence log
  *
@@ -44,6 +47,7 @@ const TEST_FEEDBACK_MS = 2000;    // Test result display duration
 const TOAST_DURATION_SUCCESS = 3000;  // Success toast auto-dismiss
 const TOAST_DURATION_ERROR = 5000;    // Error toast auto-dismiss
 const MAX_TOASTS = 3;             // Maximum visible toasts
+const EXPORT_POLL_MS = 1000;       // Export job progress refresh interval
 
 // === PPM Ballistics ===
 // IEC 60268-10 Type I: 20dB fallback in 1.7 seconds
@@ -56,11 +60,16 @@ const API = {
     CONFIG: '/api/config',
     DEVICES: '/api/devices',
     SETTINGS: '/api/settings',
+    MIXER: '/api/audio/mixer',
+    PROCESSING: '/api/audio/processing',
     STREAMS: '/api/streams',
     RECORDERS: '/api/recorders',
     RECORDERS_TEST_S3: '/api/recorders/test-s3',
     NOTIFICATIONS_TEST: '/api/notifications/test',
     RECORDING_REGENERATE_KEY: '/api/recording/regenerate-key',
+    EVENTS: '/api/events',
+    PROGRAMMES: '/api/programmes',
+    LISTEN: '/api/listen',
 };
 
 /** Formats milliseconds to human-readable smart units (ms/s/m). */
@@ -78,30 +87,158 @@ const formatSmartDuration = (ms) => {
 const msToSeconds = (ms) => ms / 1000;
 const secondsToMs = (sec) => Math.round(sec * 1000);
 
+/** Converts a channel route array ([3, 4] or [3]) to its key ("3-4" or "3"); empty means "1-2". */
+const routeToKey = (route) => (route && route.length > 0 ? route.join('-') : '1-2');
+/** Converts a route key back to the channel array sent to the API. */
+const keyToRoute = (key) => (key === '1-2' ? [] : key.split('-').map(Number));
+
 /** Converts dB (-60 to 0) to percentage (0-100) for VU meter display. */
 window.dbToPercent = (db) => Math.max(0, Math.min(100, (db - DB_MINIMUM) / DB_RANGE * 100));
 
+const DEFAULT_LOUDNESS = {
+    enabled: false,
+    target_lufs: -23,
+    max_gain_db: 12,
+    max_cut_db: 12
+};
+
+/** Formats the applied loudness gain of an output status, or '' when normalisation is off. */
+const formatLoudness = (status) => {
+    const loudness = status.loudness;
+    if (!loudness) return '';
+    const gain = `${loudness.gain_db > 0 ? '+' : ''}${loudness.gain_db.toFixed(1)} dB`;
+    return loudness.frozen ? `AGC ${gain} (held)` : `AGC ${gain}`;
+};
+
+/** Formats the current and target delay of a stream, or '' when it is not delayed. */
+const formatDelay = (delay) => {
+    if (!delay) return '';
+    if (delay.error) return `Delay error: ${delay.error}`;
+    const text = `Delay ${delay.current_seconds.toFixed(1)} / ${delay.target_seconds} s`;
+    return delay.ramping ? `${text} (ramping)` : text;
+};
+
+/** Formats a schedule change as HH:MM, prefixed with the weekday when it is not today. */
+const formatScheduleTime = (iso) => {
+    const date = new Date(iso);
+    const time = date.toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' });
+    if (date.toDateString() === new Date().toDateString()) return time;
+    return `${date.toLocaleDateString([], { weekday: 'short' })} ${time}`;
+};
+
+const DEFAULT_HLS = {
+    segment_seconds: 4,
+    window_segments: 6,
+    public: false
+};
+
+const DEFAULT_RTP = {
+    encoding: 'L24',
+    payload_type: 96,
+    packet_time_ms: 1,
+    ttl: 16,
+    sap: false,
+    ref_clock: ''
+};
+
+// Exec arguments are edited as one space-separated line
+const DEFAULT_EXEC = {
+    command: '',
+    args: '',
+    pcm: false
+};
+
+const SCHEDULE_DAYS = ['mon', 'tue', 'wed', 'thu', 'fri', 'sat', 'sun'];
+
+const DEFAULT_SCHEDULE_WINDOW = {
+    days: ['mon', 'tue', 'wed', 'thu', 'fri'],
+    start: '07:00',
+    end: '19:00'
+};
+
+/** Converts schedule exceptions to the form text, e.g. "2026-12-25, 2026-12-24 06:00-18:00". */
+const exceptionsToText = (exceptions) => (exceptions || [])
+    .map(e => (e.start ? `${e.date} ${e.start}-${e.end}` : e.date))
+    .join(', ');
+
+/** Parses the exceptions form text back into the list sent to the API. */
+const textToExceptions = (text) => text.split(',').map(s => s.trim()).filter(Boolean).map(entry => {
+    const [date, hours] = entry.split(/\s+/);
+    if (!hours) return { date };
+    const [start, end] = hours.split('-');
+    return { date, start, end: end || '' };
+});
+
 const DEFAULT_STREAM = {
+    type: 'srt',
     host: '',
     port: 8080,
     stream_id: '',
     password: '',
     codec: 'wav',
+    channels: '1-2',
+    trim_db: 0,
+    loudness: { ...DEFAULT_LOUDNESS },
+    delay: { seconds: 0, storage: 'memory' },
+    hls: { ...DEFAULT_HLS },
+    rtp: { ...DEFAULT_RTP },
+    exec: { ...DEFAULT_EXEC },
+    icecast_url: '',
+    schedule: { windows: [], exceptions: '' },
     max_retries: 99
 };
 
+const DEFAULT_SHOW = {
+    title: '',
+    days: ['mon'],
+    date: '',
+    start: '20:00',
+    end: '22:00',
+    pad_before_minutes: 5,
+    pad_after_minutes: 5,
+    name_template: ''
+};
+
+/** Describes the rotation mode of a recorder for the recorder list. */
+const formatRotation = (recorder) => {
+    switch (recorder.rotation_mode) {
+        case 'interval': return `Every ${recorder.rotation_minutes} min`;
+        case 'daily': return 'Daily';
+        case 'continuous': return 'Continuous';
+        case 'ondemand': return 'On-Demand';
+        default: return 'Hourly';
+    }
+};
+
 const DEFAULT_RECORDER = {
     name: '',
     enabled: true,
     codec: 'mp3',
+    channels: '1-2',
+    trim_db: 0,
+    loudness: { ...DEFAULT_LOUDNESS },
     rotation_mode: 'hourly',
+    rotation_minutes: 15,
     storage_mode: 'local',
     local_path: '',
     s3_endpoint: '',
     s3_bucket: '',
     s3_access_key_id: '',
     s3_secret_access_key: '',
-    retention_days: 90
+    retention_days: 90,
+    compliance: { enabled: false, retain_days: 92, lock_mode: 'governance', legal_hold: false },
+    schedules: []
+};
+
+const DEFAULT_PROCESSING = {
+    enabled: false,
+    gain_db: 0,
+    dc_removal: false,
+    highpass_hz: 0,
+    limiter_enabled: false,
+    limiter_ceiling_db: -1,
+    limiter_lookahead_ms: 5,
+    limiter_release_ms: 150
 };
 
 const DEFAULT_LEVELS = {
@@ -155,11 +292,23 @@ document.addEventListener('alpine:init', () => {
         previousStreamStatuses: {},
         deletingStreams: {},
         connectingAnimations: {},
+        scheduleDays: SCHEDULE_DAYS,
 
         recorders: [],
         recorderStatuses: {},
+        taps: [],
+        nowPlaying: null,
         deletingRecorders: {},
         recorderForm: { ...DEFAULT_RECORDER, id: '' },
+        recordings: [],
+        recordingsLoading: false,
+        exports: [],
+        exportForm: { start: '', end: '', codec: '' },
+        chainReport: null,
+        chainVerifying: false,
+        coverageReport: null,
+        coverageLoading: false,
+        _exportPoll: null,
         recorderFormDirty: false,
 
         // Event history (all event types: stream_* and silence_*)
@@ -170,14 +319,24 @@ document.addEventListener('alpine:init', () => {
         eventsOffset: 0,
 
         devices: [],
+        mixerControls: [],
+        mixerError: '',
         levels: { ...DEFAULT_LEVELS },
         vuMode: localStorage.getItem('vuMode') || 'peak',
         clipActive: false,
         clipTimeout: null,
+        listenSource: null, // null = not listening, '' = programme, otherwise stream ID
+
+        // Programmes (independent audio chains); empty ID selects the main programme
+        programmes: [],
+        programmeId: localStorage.getItem('programme') || '',
+        newProgrammeName: '',
 
         // Configuration from REST API (fetched once, updated on config_changed)
         config: {
             audio_input: '',
+            audio_channels: 2,
+            processing: { ...DEFAULT_PROCESSING },
             devices: [],
             platform: '',
             silence_threshold: -40,
@@ -216,6 +375,10 @@ document.addEventListener('alpine:init', () => {
         settingsDirty: false,
         saving: false,
 
+        // Audio processing form (applied live, separate from Save)
+        processingForm: { ...DEFAULT_PROCESSING },
+        processingDirty: false,
+
         graphSecretExpiry: { expires_soon: false, days_left: 0 },
 
         version: { current: '', latest: '', update_available: false, commit: '', build_time: '' },
@@ -290,18 +453,132 @@ document.addEventListener('alpine:init', () => {
         },
 
         async init() {
+            await this.loadProgrammes();
             await this.loadConfig();
             this.connectWebSocket();
             document.addEventListener('keydown', (e) => this.handleGlobalKeydown(e));
         },
 
+        /**
+         * Returns the API path scoped to the selected programme.
+         * @param {string} path - Path under /api
+         * @returns {string} Scoped path
+         */
+        apiUrl(path) {
+            if (!this.programmeId) return path;
+            return path.replace(/^\/api/, `${API.PROGRAMMES}/${encodeURIComponent(this.programmeId)}`);
+        },
+
+        /**
+         * Fetches the programme list and falls back to the main programme
+         * when the selected one no longer exists.
+         */
+        async loadProgrammes() {
+            try {
+                const response = await fetch(API.PROGRAMMES);
+                if (!response.ok) {
+                    throw new Error(`HTTP ${response.status}`);
+                }
+                this.programmes = await response.json();
+                if (this.programmeId && !this.programmes.some(p => p.id === this.programmeId && !p.main)) {
+                    this.selectProgramme('');
+                }
+            } catch (err) {
+                console.error('Failed to load programmes:', err);
+            }
+        },
+
+        /**
+         * Switches the dashboard and settings to another programme.
+         * @param {string} id - Programme ID (empty or main programme ID for main)
+         */
+        selectProgramme(id) {
+            const main = this.programmes.find(p => p.main);
+            const next = main && id === main.id ? '' : id;
+            if (next === this.programmeId) return;
+
+            this.programmeId = next;
+            localStorage.setItem('programme', next);
+            this.stopListening();
+            this.resetVuMeter();
+            this.loadConfig();
+            // Reconnect so the WebSocket reports the selected programme
+            this.ws?.close();
+        },
+
+        /**
+         * Creates a programme and switches to it.
+         */
+        async addProgramme() {
+            const name = this.newProgrammeName.trim();
+            if (!name) return;
+            try {
+                const response = await fetch(API.PROGRAMMES, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ name })
+                });
+                const data = await response.json();
+                if (!response.ok) {
+                    throw new Error(data.error || `HTTP ${response.status}`);
+                }
+                this.newProgrammeName = '';
+                await this.loadProgrammes();
+                this.showToast(`Programme "${data.name}" added`, 'success');
+            } catch (err) {
+                this.showToast(`Failed to add programme: ${err.message}`, 'error');
+            }
+        },
+
+        /**
+         * Renames a programme.
+         * @param {Object} programme - Programme to rename
+         * @param {string} name - New display name
+         */
+        async renameProgramme(programme, name) {
+            try {
+                const response = await fetch(`${API.PROGRAMMES}/${encodeURIComponent(programme.id)}`, {
+                    method: 'PUT',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ name: name.trim() })
+                });
+                if (!response.ok) {
+                    const data = await response.json();
+                    throw new Error(data.error || `HTTP ${response.status}`);
+                }
+            } catch (err) {
+                this.showToast(`Failed to rename programme: ${err.message}`, 'error');
+            }
+            await this.loadProgrammes();
+        },
+
+        /**
+         * Deletes a programme with confirmation, stopping its streams and recorders.
+         * @param {Object} programme - Programme to delete
+         */
+        async deleteProgramme(programme) {
+            if (!confirm(`Delete programme "${programme.name}"? Its streams and recorders are removed.`)) return;
+            try {
+                const response = await fetch(`${API.PROGRAMMES}/${encodeURIComponent(programme.id)}`, {
+                    method: 'DELETE'
+                });
+                if (!response.ok) {
+                    const data = await response.json();
+                    throw new Error(data.error || `HTTP ${response.status}`);
+                }
+            } catch (err) {
+                this.showToast(`Failed to delete programme: ${err.message}`, 'error');
+            }
+            await this.loadProgrammes();
+        },
+
         /**
          * Fetches configuration from REST API.
          * Called on init and when config_changed event is received.
          */
         async loadConfig() {
             try {
-                const response = await fetch(API.CONFIG);
+                const response = await fetch(this.apiUrl(API.CONFIG));
                 if (!response.ok) {
                     throw new Error(`HTTP ${response.status}`);
                 }
@@ -395,7 +672,8 @@ document.addEventListener('alpine:init', () => {
         // Establishes WebSocket connection with auto-reconnect
         connectWebSocket() {
             const protocol = location.protocol === 'https:' ? 'wss:' : 'ws:';
-            this.ws = new WebSocket(`${protocol}//${location.host}/ws`);
+            const query = this.programmeId ? `?programme=${encodeURIComponent(this.programmeId)}` : '';
+            this.ws = new WebSocket(`${protocol}//${location.host}/ws${query}`);
 
             this.ws.onmessage = (e) => {
                 let msg;
@@ -410,6 +688,7 @@ document.addEventListener('alpine:init', () => {
                 } else if (msg.type === 'status') {
                     this.handleStatus(msg);
                 } else if (msg.type === 'config_changed') {
+                    this.loadProgrammes();
                     // Config was changed (by this or another client), refetch
                     // Skip if we're currently editing (form open)
                     if (this.view !== 'settings' && this.view !== 'stream-form' && this.view !== 'recorder-form') {
@@ -607,6 +886,12 @@ document.addEventListener('alpine:init', () => {
             // Recorder statuses
             this.recorderStatuses = msg.recorder_statuses || {};
 
+            // Readers of the PCM tap socket
+            this.taps = msg.taps || [];
+
+            // Title on air, from the playout system
+            this.nowPlaying = msg.now_playing || null;
+
             // Graph secret expiry (runtime info, not config)
             this.graphSecretExpiry = msg.graph_secret_expiry ?? { expires_soon: false, days_left: 0 };
 
@@ -683,7 +968,72 @@ document.addEventListener('alpine:init', () => {
                 platform: this.config.platform || ''
             };
             this.settingsDirty = false;
+            this.processingForm = { ...DEFAULT_PROCESSING, ...this.config.processing };
+            this.processingDirty = false;
             this.view = 'settings';
+            this.loadMixer();
+        },
+
+        /**
+         * Fetches capture mixer controls for the selected input's sound card.
+         */
+        async loadMixer() {
+            this.mixerError = '';
+            try {
+                const response = await fetch(this.apiUrl(API.MIXER));
+                const data = await response.json();
+                if (!response.ok) {
+                    throw new Error(data.error || `HTTP ${response.status}`);
+                }
+                this.mixerControls = data.controls || [];
+            } catch (err) {
+                this.mixerControls = [];
+                this.mixerError = err.message;
+            }
+        },
+
+        /**
+         * Applies a mixer control value immediately and persists it.
+         * Reloads controls afterwards to reflect the driver's actual value.
+         */
+        async setMixerControl(control, value) {
+            try {
+                const response = await fetch(this.apiUrl(API.MIXER), {
+                    method: 'PUT',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ control: control.name, value: String(value) })
+                });
+                if (!response.ok) {
+                    const data = await response.json();
+                    throw new Error(data.error || `HTTP ${response.status}`);
+                }
+            } catch (err) {
+                this.showToast(`Failed to update ${control.name}: ${err.message}`, 'error');
+            }
+            await this.loadMixer();
+        },
+
+        /**
+         * Applies the audio processing chain immediately and persists it.
+         */
+        async applyProcessing() {
+            try {
+                const response = await fetch(this.apiUrl(API.PROCESSING), {
+                    method: 'PUT',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(this.processingForm)
+                });
+                const data = await response.json();
+                if (!response.ok) {
+                    throw new Error(data.error || `HTTP ${response.status}`);
+                }
+                this.processingForm = { ...DEFAULT_PROCESSING, ...data };
+                this.config.processing = data;
+                this.processingDirty = false;
+                this.showToast('Audio processing applied', 'success');
+            } catch (err) {
+                this.showToast(`Failed to apply processing: ${err.message}`, 'error');
+            }
         },
 
         /**
@@ -708,11 +1058,12 @@ document.addEventListener('alpine:init', () => {
         async updateAudioInput() {
             const prev = this.config.audio_input;
             try {
-                const response = await fetch(API.SETTINGS, {
+                const response = await fetch(this.apiUrl(API.SETTINGS), {
                     method: 'POST',
                     headers: { 'Content-Type': 'application/json' },
                     body: JSON.stringify({
                         audio_input: this.config.audio_input,
+                        audio_channels: this.config.audio_channels,
                         silence_threshold: this.config.silence_threshold,
                         silence_duration_ms: this.config.silence_duration_ms,
                         silence_recovery_ms: this.config.silence_recovery_ms,
@@ -761,6 +1112,7 @@ document.addEventListener('alpine:init', () => {
 
             const payload = {
                 audio_input: form.audioInput,
+                audio_channels: this.config.audio_channels,
                 silence_threshold: form.silenceThreshold,
                 silence_duration_ms: secondsToMs(form.silenceDuration),
                 silence_recovery_ms: secondsToMs(form.silenceRecovery),
@@ -779,7 +1131,7 @@ document.addEventListener('alpine:init', () => {
             };
 
             try {
-                const response = await fetch(API.SETTINGS, {
+                const response = await fetch(this.apiUrl(API.SETTINGS), {
                     method: 'POST',
                     headers: { 'Content-Type': 'application/json' },
                     body: JSON.stringify(payload)
@@ -815,21 +1167,91 @@ document.addEventListener('alpine:init', () => {
                 if (!stream) return;
                 this.streamForm = {
                     id: stream.id,
+                    type: stream.type || 'srt',
                     host: stream.host,
                     port: stream.port,
                     stream_id: stream.stream_id || '',
                     password: '',
                     codec: stream.codec || 'wav',
+                    channels: routeToKey(stream.channels),
+                    trim_db: stream.trim_db || 0,
+                    loudness: stream.loudness?.enabled ? { ...stream.loudness } : { ...DEFAULT_LOUDNESS },
+                    delay: { seconds: stream.delay?.seconds || 0, storage: stream.delay?.storage || 'memory' },
+                    hls: {
+                        segment_seconds: stream.hls?.segment_seconds || DEFAULT_HLS.segment_seconds,
+                        window_segments: stream.hls?.window_segments || DEFAULT_HLS.window_segments,
+                        public: stream.hls?.public || false
+                    },
+                    rtp: { ...DEFAULT_RTP, ...stream.rtp },
+                    exec: {
+                        command: stream.exec?.command || '',
+                        args: (stream.exec?.args || []).join(' '),
+                        pcm: stream.exec?.pcm || false
+                    },
+                    icecast_url: stream.icecast_url || '',
+                    schedule: {
+                        windows: (stream.schedule?.windows || []).map(w => ({ ...w, days: [...w.days] })),
+                        exceptions: exceptionsToText(stream.schedule?.exceptions)
+                    },
                     max_retries: stream.max_retries || 99,
                     enabled: stream.enabled !== false
                 };
             } else {
-                this.streamForm = { ...DEFAULT_STREAM, id: '', enabled: true };
+                this.streamForm = { ...DEFAULT_STREAM, loudness: { ...DEFAULT_LOUDNESS }, delay: { ...DEFAULT_STREAM.delay }, hls: { ...DEFAULT_HLS }, rtp: { ...DEFAULT_RTP }, exec: { ...DEFAULT_EXEC }, schedule: { windows: [], exceptions: '' }, id: '', enabled: true };
             }
             this.streamFormDirty = false;
             this.view = 'stream-form';
         },
 
+        /**
+         * Switches the stream form between output types. HLS only carries AAC or MP3,
+         * and AAC is only available for HLS.
+         * @param {string} type - 'srt', 'hls', 'rtp' or 'exec'
+         */
+        setStreamType(type) {
+            this.streamForm.type = type;
+            if (type === 'hls' && !['aac', 'mp3'].includes(this.streamForm.codec)) {
+                this.streamForm.codec = 'aac';
+            } else if (type !== 'hls' && this.streamForm.codec === 'aac') {
+                this.streamForm.codec = 'mp3';
+            }
+            if (!this.isEditMode) {
+                this.streamForm.port = type === 'rtp' ? 5004 : DEFAULT_STREAM.port;
+            }
+            this.markStreamFormDirty();
+        },
+
+        /**
+         * Returns the playlist URL of an HLS stream.
+         * @param {string} id - Stream ID
+         */
+        hlsUrl(id) {
+            return `${window.location.origin}/hls/${id}/index.m3u8`;
+        },
+
+        /**
+         * Lists selectable channel routes for the captured channel count:
+         * consecutive stereo pairs followed by individual mono channels.
+         */
+        get channelRoutes() {
+            const count = this.config.audio_channels || 2;
+            const routes = [];
+            for (let ch = 1; ch < count; ch += 2) {
+                routes.push({ key: `${ch}-${ch + 1}`, label: `Channels ${ch}-${ch + 1} (stereo)` });
+            }
+            for (let ch = 1; ch <= count; ch++) {
+                routes.push({ key: `${ch}`, label: `Channel ${ch} (mono)` });
+            }
+            return routes;
+        },
+
+        /** Returns per-route levels for routes other than channels 1-2. */
+        get routeLevels() {
+            return Object.entries(this.levels.routes || {})
+                .sort(([a], [b]) => a.localeCompare(b, undefined, { numeric: true }))
+                .map(([key, lv]) => ({ key, ...lv }));
+        },
+
         showTab(tabId) {
             this.settingsTab = tabId;
         },
@@ -840,13 +1262,33 @@ document.addEventListener('alpine:init', () => {
          * Submits stream form via REST API.
          */
         async submitStreamForm() {
-            if (!this.streamForm.host?.trim()) return;
+            const hls = this.streamForm.type === 'hls';
+            const exec = this.streamForm.type === 'exec';
+            if (exec ? !this.streamForm.exec.command.trim() : !hls && !this.streamForm.host?.trim()) return;
 
+            const schedule = {
+                windows: this.streamForm.schedule.windows,
+                exceptions: textToExceptions(this.streamForm.schedule.exceptions)
+            };
             const data = {
+                type: this.streamForm.type,
                 host: this.streamForm.host.trim(),
                 port: this.streamForm.port,
                 stream_id: this.streamForm.stream_id.trim() || 'studio',
                 codec: this.streamForm.codec,
+                channels: keyToRoute(this.streamForm.channels),
+                trim_db: this.streamForm.trim_db || 0,
+                loudness: this.streamForm.loudness,
+                delay: this.streamForm.delay.seconds > 0 ? { ...this.streamForm.delay } : {},
+                hls: hls ? { ...this.streamForm.hls } : {},
+                rtp: this.streamForm.type === 'rtp' ? { ...this.streamForm.rtp } : {},
+                exec: exec ? {
+                    command: this.streamForm.exec.command.trim(),
+                    args: this.streamForm.exec.args.split(/\s+/).filter(Boolean),
+                    pcm: this.streamForm.exec.pcm
+                } : {},
+                icecast_url: this.streamForm.icecast_url.trim(),
+                schedule: schedule.windows.length || schedule.exceptions.length ? schedule : {},
                 max_retries: this.streamForm.max_retries
             };
 
@@ -858,21 +1300,21 @@ document.addEventListener('alpine:init', () => {
                 let response;
                 if (this.isEditMode) {
                     data.enabled = this.streamForm.enabled;
-                    response = await fetch(`${API.STREAMS}/${this.streamForm.id}`, {
+                    response = await fetch(`${this.apiUrl(API.STREAMS)}/${this.streamForm.id}`, {
                         method: 'PUT',
                         headers: { 'Content-Type': 'application/json' },
                         body: JSON.stringify(data)
                     });
                 } else {
-                    response = await fetch(API.STREAMS, {
+                    response = await fetch(this.apiUrl(API.STREAMS), {
                         method: 'POST',
                         headers: { 'Content-Type': 'application/json' },
                         body: JSON.stringify(data)
                     });
                 }
 
+                const result = await response.json();
                 if (!response.ok) {
-                    const result = await response.json();
                     throw new Error(result.error || `HTTP ${response.status}`);
                 }
 
@@ -894,6 +1336,9 @@ document.addEventListener('alpine:init', () => {
                 const toastMsg = this.isEditMode ? 'Stream updated' : 'Stream added';
                 this.showDashboard();
                 this.showToast(toastMsg, 'success');
+                if (result.notice) {
+                    this.showToast(result.notice, 'info');
+                }
             } catch (err) {
                 this.showToast(`Failed to save stream: ${err.message}`, 'error');
             }
@@ -913,7 +1358,7 @@ document.addEventListener('alpine:init', () => {
             this.deletingStreams[id] = stream.created_at;
 
             try {
-                const response = await fetch(`${API.STREAMS}/${id}`, {
+                const response = await fetch(`${this.apiUrl(API.STREAMS)}/${id}`, {
                     method: 'DELETE'
                 });
 
@@ -935,6 +1380,23 @@ document.addEventListener('alpine:init', () => {
             this.streamFormDirty = true;
         },
 
+        addScheduleWindow() {
+            this.streamForm.schedule.windows.push({ ...DEFAULT_SCHEDULE_WINDOW, days: [...DEFAULT_SCHEDULE_WINDOW.days] });
+            this.markStreamFormDirty();
+        },
+
+        removeScheduleWindow(index) {
+            this.streamForm.schedule.windows.splice(index, 1);
+            this.markStreamFormDirty();
+        },
+
+        /** Adds or removes a day from a schedule window, keeping the days in weekday order. */
+        toggleScheduleDay(slot, day) {
+            const days = slot.days.includes(day) ? slot.days.filter(d => d !== day) : [...slot.days, day];
+            slot.days = SCHEDULE_DAYS.filter(d => days.includes(d));
+            this.markStreamFormDirty();
+        },
+
         // Recorder management
 
         /**
@@ -951,19 +1413,33 @@ document.addEventListener('alpine:init', () => {
                     name: recorder.name,
                     enabled: recorder.enabled !== false,
                     codec: recorder.codec || 'mp3',
+                    channels: routeToKey(recorder.channels),
+                    trim_db: recorder.trim_db || 0,
+                    loudness: recorder.loudness?.enabled ? { ...recorder.loudness } : { ...DEFAULT_LOUDNESS },
                     rotation_mode: recorder.rotation_mode || 'hourly',
+                    rotation_minutes: recorder.rotation_minutes || DEFAULT_RECORDER.rotation_minutes,
                     storage_mode: recorder.storage_mode || 'local',
                     local_path: recorder.local_path || '',
                     s3_endpoint: recorder.s3_endpoint || '',
                     s3_bucket: recorder.s3_bucket || '',
                     s3_access_key_id: recorder.s3_access_key_id || '',
                     s3_secret_access_key: '',
-                    retention_days: recorder.retention_days || 90
+                    retention_days: recorder.retention_days || 90,
+                    compliance: { ...DEFAULT_RECORDER.compliance, ...recorder.compliance },
+                    schedules: (recorder.schedules || []).map(show => ({ ...DEFAULT_SHOW, ...show, days: [...(show.days || [])] }))
                 };
             } else {
-                this.recorderForm = { ...DEFAULT_RECORDER, id: '' };
+                this.recorderForm = { ...DEFAULT_RECORDER, loudness: { ...DEFAULT_LOUDNESS }, compliance: { ...DEFAULT_RECORDER.compliance }, schedules: [], id: '' };
             }
             this.recorderFormDirty = false;
+            this.recordings = [];
+            this.exports = [];
+            this.exportForm = { start: '', end: '', codec: '' };
+            this.chainReport = null;
+            this.coverageReport = null;
+            if (this.recorderForm.id) {
+                this.loadExports();
+            }
             this.view = 'recorder-form';
         },
 
@@ -971,6 +1447,23 @@ document.addEventListener('alpine:init', () => {
             this.recorderFormDirty = true;
         },
 
+        addRecorderShow() {
+            this.recorderForm.schedules.push({ ...DEFAULT_SHOW, days: [...DEFAULT_SHOW.days] });
+            this.markRecorderFormDirty();
+        },
+
+        removeRecorderShow(index) {
+            this.recorderForm.schedules.splice(index, 1);
+            this.markRecorderFormDirty();
+        },
+
+        /** Adds or removes a day from a scheduled show, keeping the days in weekday order. */
+        toggleShowDay(show, day) {
+            const days = show.days.includes(day) ? show.days.filter(d => d !== day) : [...show.days, day];
+            show.days = SCHEDULE_DAYS.filter(d => days.includes(d));
+            this.markRecorderFormDirty();
+        },
+
         /**
          * Submits recorder form via REST API.
          */
@@ -1015,13 +1508,27 @@ document.addEventListener('alpine:init', () => {
                 name: name,
                 enabled: this.recorderForm.enabled,
                 codec: this.recorderForm.codec,
+                channels: keyToRoute(this.recorderForm.channels),
+                trim_db: this.recorderForm.trim_db || 0,
+                loudness: this.recorderForm.loudness,
                 rotation_mode: this.recorderForm.rotation_mode,
+                rotation_minutes: this.recorderForm.rotation_mode === 'interval' ? this.recorderForm.rotation_minutes : 0,
                 storage_mode: storageMode,
                 local_path: localPath,
                 s3_endpoint: this.recorderForm.s3_endpoint.trim(),
                 s3_bucket: bucket,
                 s3_access_key_id: accessKey,
-                retention_days: this.recorderForm.retention_days || 90
+                retention_days: this.recorderForm.retention_days || 90,
+                compliance: this.recorderForm.compliance,
+                // A date makes a show one-off; its days are ignored
+                schedules: this.recorderForm.rotation_mode === 'ondemand'
+                    ? this.recorderForm.schedules.map(show => ({
+                        ...show,
+                        days: show.date ? [] : show.days,
+                        pad_before_minutes: show.pad_before_minutes || 0,
+                        pad_after_minutes: show.pad_after_minutes || 0
+                    }))
+                    : []
             };
 
             if (secretKey) {
@@ -1031,13 +1538,13 @@ document.addEventListener('alpine:init', () => {
             try {
                 let response;
                 if (this.isRecorderEditMode) {
-                    response = await fetch(`${API.RECORDERS}/${this.recorderForm.id}`, {
+                    response = await fetch(`${this.apiUrl(API.RECORDERS)}/${this.recorderForm.id}`, {
                         method: 'PUT',
                         headers: { 'Content-Type': 'application/json' },
                         body: JSON.stringify(data)
                     });
                 } else {
-                    response = await fetch(API.RECORDERS, {
+                    response = await fetch(this.apiUrl(API.RECORDERS), {
                         method: 'POST',
                         headers: { 'Content-Type': 'application/json' },
                         body: JSON.stringify(data)
@@ -1086,7 +1593,7 @@ document.addEventListener('alpine:init', () => {
             this.deletingRecorders[id] = recorder.created_at;
 
             try {
-                const response = await fetch(`${API.RECORDERS}/${id}`, {
+                const response = await fetch(`${this.apiUrl(API.RECORDERS)}/${id}`, {
                     method: 'DELETE'
                 });
 
@@ -1104,6 +1611,26 @@ document.addEventListener('alpine:init', () => {
             }
         },
 
+        /**
+         * Dumps or ramps up a stream delay via REST API.
+         * @param {string} id - Stream ID
+         * @param {string} action - 'dump' or 'ramp'
+         */
+        async delayAction(id, action) {
+            try {
+                const response = await fetch(`${this.apiUrl(API.STREAMS)}/${id}/delay/${action}`, {
+                    method: 'POST'
+                });
+
+                if (!response.ok) {
+                    const result = await response.json();
+                    throw new Error(result.error || `HTTP ${response.status}`);
+                }
+            } catch (err) {
+                this.showToast(`Failed to ${action} delay: ${err.message}`, 'error');
+            }
+        },
+
         /**
          * Starts or stops recording via REST API.
          * @param {string} id - Recorder ID
@@ -1111,7 +1638,7 @@ document.addEventListener('alpine:init', () => {
          */
         async recorderAction(id, action) {
             try {
-                const response = await fetch(`${API.RECORDERS}/${id}/${action}`, {
+                const response = await fetch(`${this.apiUrl(API.RECORDERS)}/${id}/${action}`, {
                     method: 'POST'
                 });
 
@@ -1124,6 +1651,209 @@ document.addEventListener('alpine:init', () => {
             }
         },
 
+        /** Loads the files of the recorder being edited, from disk and S3. */
+        async loadRecordings() {
+            this.recordingsLoading = true;
+            try {
+                const response = await fetch(`${this.apiUrl(API.RECORDERS)}/${this.recorderForm.id}/recordings`);
+                const result = await response.json();
+                if (!response.ok) {
+                    throw new Error(result.error || `HTTP ${response.status}`);
+                }
+                this.recordings = result;
+            } catch (err) {
+                this.showToast(`Failed to load recordings: ${err.message}`, 'error');
+            } finally {
+                this.recordingsLoading = false;
+            }
+        },
+
+        /** Returns the URL to play or download a file of the recorder being edited. */
+        recordingUrl(name, download = false) {
+            const url = `${this.apiUrl(API.RECORDERS)}/${this.recorderForm.id}/recordings/${encodeURIComponent(name)}`;
+            return download ? `${url}?download=true&redirect=true` : url;
+        },
+
+        /** Describes a recording as its time span, size and upload state. */
+        formatRecording(rec) {
+            const time = (iso) => new Date(iso).toLocaleTimeString('nl-NL', { hour: '2-digit', minute: '2-digit' });
+            const date = new Date(rec.start).toLocaleDateString('nl-NL');
+            const span = rec.end ? `${time(rec.start)}–${time(rec.end)}` : time(rec.start);
+            const size = rec.size >= 1048576 ? `${(rec.size / 1048576).toFixed(1)} MB` : `${Math.ceil(rec.size / 1024)} KB`;
+            return [`${date} ${span}`, size, rec.upload].filter(Boolean).join(' · ');
+        },
+
+        /** Queues an export of the time range in the export form. */
+        async startExport() {
+            try {
+                const response = await fetch(`${this.apiUrl(API.RECORDERS)}/${this.recorderForm.id}/exports`, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({
+                        start: new Date(this.exportForm.start).toISOString(),
+                        end: new Date(this.exportForm.end).toISOString(),
+                        codec: this.exportForm.codec
+                    })
+                });
+                const result = await response.json();
+                if (!response.ok) {
+                    throw new Error(result.error || `HTTP ${response.status}`);
+                }
+                this.exports.unshift(result);
+                this.pollExports();
+            } catch (err) {
+                this.showToast(`Failed to start export: ${err.message}`, 'error');
+            }
+        },
+
+        /** Verifies the hash chain of the last 24 hours of the recorder being edited. */
+        async verifyChain() {
+            const id = this.recorderForm.id;
+            this.chainVerifying = true;
+            try {
+                const response = await fetch(`${this.apiUrl(API.RECORDERS)}/${id}/chain`);
+                const result = await response.json();
+                if (!response.ok) {
+                    throw new Error(result.error || `HTTP ${response.status}`);
+                }
+                if (id === this.recorderForm.id) {
+                    this.chainReport = result;
+                }
+            } catch (err) {
+                this.showToast(`Failed to verify archive: ${err.message}`, 'error');
+            } finally {
+                this.chainVerifying = false;
+            }
+        },
+
+        /**
+         * Formats the summary of a chain verification.
+         * @param {Object} report - Chain report
+         * @returns {string} Summary text
+         */
+        formatChainReport(report) {
+            if (report.files === 0) {
+                return 'No files in the last 24 hours.';
+            }
+            const files = `${report.files} file${report.files === 1 ? '' : 's'}`;
+            return report.valid ? `${files} verified, chain intact.` : `${files} checked, ${report.problems} with problems:`;
+        },
+
+        /** Loads the coverage of the last week of the recorder being edited. */
+        async loadCoverage() {
+            const id = this.recorderForm.id;
+            this.coverageLoading = true;
+            try {
+                const response = await fetch(`${this.apiUrl(API.RECORDERS)}/${id}/coverage`);
+                const result = await response.json();
+                if (!response.ok) {
+                    throw new Error(result.error || `HTTP ${response.status}`);
+                }
+                if (id === this.recorderForm.id) {
+                    this.coverageReport = result;
+                }
+            } catch (err) {
+                this.showToast(`Failed to load coverage: ${err.message}`, 'error');
+            } finally {
+                this.coverageLoading = false;
+            }
+        },
+
+        /**
+         * Formats the coverage of one day.
+         * @param {Object} day - Coverage day
+         * @returns {string} Summary text
+         */
+        formatCoverageDay(day) {
+            const hours = (seconds) => `${(seconds / 3600).toFixed(1)} h`;
+            if (!this.coverageReport?.expected || day.expected_seconds === 0) {
+                return `${hours(day.covered_seconds)} recorded`;
+            }
+            const gaps = day.gaps.length ? `${day.gaps.length} gap${day.gaps.length === 1 ? '' : 's'}` : 'complete';
+            return `${day.percent}% · ${hours(day.covered_seconds)} of ${hours(day.expected_seconds)} · ${gaps}`;
+        },
+
+        /**
+         * Formats a gap in the recordings.
+         * @param {Object} gap - Coverage gap
+         * @returns {string} Gap text
+         */
+        formatCoverageGap(gap) {
+            const time = (iso) => new Date(iso).toLocaleTimeString('nl-NL', { hour: '2-digit', minute: '2-digit', second: '2-digit' });
+            const duration = formatSmartDuration(new Date(gap.end) - new Date(gap.start));
+            return [`${time(gap.start)}–${time(gap.end)}`, duration, gap.kind, gap.file].filter(Boolean).join(' · ');
+        },
+
+        /** Loads the export jobs of the recorder being edited. */
+        async loadExports() {
+            const id = this.recorderForm.id;
+            try {
+                const response = await fetch(`${this.apiUrl(API.RECORDERS)}/${id}/exports`);
+                const result = await response.json();
+                if (!response.ok) {
+                    throw new Error(result.error || `HTTP ${response.status}`);
+                }
+                if (id === this.recorderForm.id) {
+                    this.exports = result;
+                    this.pollExports();
+                }
+            } catch (err) {
+                this.showToast(`Failed to load exports: ${err.message}`, 'error');
+            }
+        },
+
+        /** Refreshes the export jobs while any of them is still running. */
+        pollExports() {
+            const running = this.exports.some(job => !job.finished_at);
+            if (!running || this._exportPoll || this.view !== 'recorder-form') {
+                return;
+            }
+            this._exportPoll = setTimeout(() => {
+                this._exportPoll = null;
+                if (this.view === 'recorder-form' && this.recorderForm.id) {
+                    this.loadExports();
+                }
+            }, EXPORT_POLL_MS);
+        },
+
+        /** Cancels a running export job or removes a finished one. */
+        async deleteExport(jobId) {
+            try {
+                const response = await fetch(`${this.apiUrl(API.RECORDERS)}/${this.recorderForm.id}/exports/${jobId}`, {
+                    method: 'DELETE'
+                });
+                if (!response.ok) {
+                    const result = await response.json();
+                    throw new Error(result.error || `HTTP ${response.status}`);
+                }
+                this.exports = this.exports.filter(job => job.id !== jobId);
+            } catch (err) {
+                this.showToast(`Failed to remove export: ${err.message}`, 'error');
+            }
+        },
+
+        /** Returns the download URL of a finished export job. */
+        exportUrl(jobId) {
+            return `${this.apiUrl(API.RECORDERS)}/${this.recorderForm.id}/exports/${jobId}/download`;
+        },
+
+        /** Describes an export job as its state, progress or error, and size. */
+        formatExport(job) {
+            switch (job.state) {
+                case 'done': {
+                    const size = job.size >= 1048576 ? `${(job.size / 1048576).toFixed(1)} MB` : `${Math.ceil(job.size / 1024)} KB`;
+                    const gaps = job.gaps?.length ? ` · ${job.gaps.length} gap${job.gaps.length === 1 ? '' : 's'} filled with silence` : '';
+                    return `Done · ${size}${gaps}`;
+                }
+                case 'failed':
+                    return `Failed: ${job.error}`;
+                case 'queued':
+                    return 'Queued';
+                default:
+                    return `${job.state === 'fetching' ? 'Fetching' : 'Encoding'} · ${Math.round(job.progress * 100)}%`;
+            }
+        },
+
         /**
          * Tests S3 connection via REST API.
          */
@@ -1167,7 +1897,7 @@ document.addEventListener('alpine:init', () => {
         /**
          * Computes all display data for a recorder in a single call.
          * @param {Object} recorder - Recorder object
-         * @returns {Object} Display data with stateClass, statusText, duration
+         * @returns {Object} Display data with stateClass, statusText, loudnessText
          */
         getRecorderDisplayData(recorder) {
             const status = this.recorderStatuses[recorder.id] || {};
@@ -1193,10 +1923,6 @@ document.addEventListener('alpine:init', () => {
                         stateClass = 'state-success';
                         statusText = 'Recording';
                         break;
-                    case 'rotating':
-                        stateClass = 'state-warning';
-                        statusText = 'Rotating...';
-                        break;
                     case 'stopping':
                         stateClass = 'state-warning';
                         statusText = 'Finalizing...';
@@ -1214,7 +1940,9 @@ document.addEventListener('alpine:init', () => {
 
             return {
                 stateClass,
-                statusText
+                statusText,
+                rotationText: formatRotation(recorder),
+                loudnessText: formatLoudness(status)
             };
         },
 
@@ -1223,7 +1951,7 @@ document.addEventListener('alpine:init', () => {
          * Use this method to avoid multiple getStreamStatus() calls per render.
          *
          * @param {Object} stream - Stream object with id and created_at
-         * @returns {Object} Object with stateClass, statusText, showError, and lastError
+         * @returns {Object} Object with stateClass, statusText, showError, lastError, loudnessText, delay, and delayText
          */
         getStreamDisplayData(stream) {
             const status = this.streamStatuses[stream.id] || {};
@@ -1241,6 +1969,10 @@ document.addEventListener('alpine:init', () => {
                         stateClass = 'state-stopped';
                         statusText = 'Disabled';
                         break;
+                    case 'scheduled':
+                        stateClass = 'state-stopped';
+                        statusText = status.next_change ? `Off schedule until ${formatScheduleTime(status.next_change)}` : 'Off schedule';
+                        break;
                     case 'starting':
                         stateClass = 'state-warning';
                         statusText = 'Connecting...';
@@ -1284,7 +2016,10 @@ document.addEventListener('alpine:init', () => {
                 stateClass,
                 statusText,
                 showError,
-                lastError: status.error || ''
+                lastError: status.error || '',
+                loudnessText: formatLoudness(status),
+                delay: status.delay || null,
+                delayText: formatDelay(status.delay)
             };
         },
 
@@ -1293,6 +2028,39 @@ document.addEventListener('alpine:init', () => {
             localStorage.setItem('vuMode', this.vuMode);
         },
 
+        /**
+         * Starts or stops live monitoring of the programme or a stream.
+         * @param {string} source - Empty for the programme, otherwise a stream ID
+         */
+        toggleListen(source) {
+            if (this.listenSource === source) {
+                this.stopListening();
+                return;
+            }
+
+            const path = source ? `${this.apiUrl(API.STREAMS)}/${source}/listen` : this.apiUrl(API.LISTEN);
+            const player = this.$refs.listenPlayer;
+            player.src = `${path}?format=mp3`;
+            player.play().catch(err => {
+                this.stopListening();
+                this.showToast(`Failed to listen: ${err.message}`, 'error');
+            });
+            this.listenSource = source;
+        },
+
+        /**
+         * Stops live monitoring.
+         */
+        stopListening() {
+            const player = this.$refs.listenPlayer;
+            if (player) {
+                player.pause();
+                player.removeAttribute('src');
+                player.load();
+            }
+            this.listenSource = null;
+        },
+
         resetVuMeter() {
             this.levels = { ...DEFAULT_LEVELS };
         },
@@ -1312,7 +2080,7 @@ document.addEventListener('alpine:init', () => {
                 if (this.eventFilter) {
                     params.set('type', this.eventFilter);
                 }
-                const response = await fetch(`/api/events?${params}`);
+                const response = await fetch(`${this.apiUrl(API.EVENTS)}?${params}`);
                 if (response.ok) {
                     const data = await response.json();
                     const newEvents = (data.events || []).map(e => ({ ...e, expanded: false }));
@@ -1425,7 +2193,7 @@ document.addEventListener('alpine:init', () => {
             if (type === 'silence_end') return 'success';
             if (type === 'recorder_error' || type === 'upload_failed' || type === 'upload_abandoned') return 'error';
             if (type === 'upload_completed' || type === 'cleanup_completed') return 'success';
-            if (type === 'upload_retry') return 'warning';
+            if (type === 'upload_retry' || type === 'recorder_gap') return 'warning';
             return 'info';
         },
 
@@ -1452,7 +2220,8 @@ document.addEventListener('alpine:init', () => {
                 'upload_failed': 'Upload Failed',
                 'upload_retry': 'Retry',
                 'upload_abandoned': 'Abandoned',
-                'cleanup_completed': 'Cleanup'
+                'cleanup_completed': 'Cleanup',
+                'recorder_gap': 'Gap'
             };
             return labels[type] || type;
         },
@@ -1490,7 +2259,13 @@ document.addEventListener('alpine:init', () => {
             if (event.type === 'recorder_file' || event.type === 'upload_queued' || event.type === 'upload_completed') {
                 const filename = details.filename || '';
                 const codec = details.codec || '';
-                return [filename, codec.toUpperCase()].filter(Boolean).join(' — ');
+                const checksum = details.sha256 ? `SHA-256 ${details.sha256.slice(0, 12)}…` : '';
+                return [filename, codec.toUpperCase(), checksum].filter(Boolean).join(' — ');
+            }
+            if (event.type === 'recorder_gap') {
+                const time = (iso) => new Date(iso).toLocaleString('nl-NL', { dateStyle: 'short', timeStyle: 'short' });
+                const span = details.gap_start ? `${time(details.gap_start)}–${time(details.gap_end)}` : '';
+                return [details.gap_kind, span, details.filename].filter(Boolean).join(' — ');
             }
             if (event.type === 'cleanup_completed') {
                 const count = details.files_deleted || 0;
@@ -1588,7 +2363,7 @@ document.addEventListener('alpine:init', () => {
             }
 
             try {
-                const response = await fetch(`${API.NOTIFICATIONS_TEST}/${type}`, {
+                const response = await fetch(`${this.apiUrl(API.NOTIFICATIONS_TEST)}/${type}`, {
                     method: 'POST',
                     headers: { 'Content-Type': 'application/json' },
                     body: JSON.stringify(payload)
@@ -1621,7 +2396,7 @@ document.addEventListener('alpine:init', () => {
             if (!confirm('Regenerate API key? Existing integrations will stop working.')) return;
 
             try {
-                const response = await fetch(API.RECORDING_REGENERATE_KEY, {
+                const response = await fetch(this.apiUrl(API.RECORDING_REGENERATE_KEY), {
                     method: 'POST'
                 });
 